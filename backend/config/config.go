@@ -3,6 +3,7 @@ package config
 import (
 	"fmt"
 	"os"
+	"strconv"
 	"time"
 
 	"github.com/joho/godotenv"
@@ -10,13 +11,14 @@ import (
 
 // Config хранит основные настройки приложения.
 type Config struct {
-	AppPort       string
-	MongoURI      string
-	MongoDB       string
-	JWTSecret     string
-	TelegramToken string
-	TelegramChat  string
-	Timezone      *time.Location
+	AppPort            string
+	MongoURI           string
+	MongoDB            string
+	JWTSecret          string
+	TelegramToken      string
+	TelegramChat       string
+	Timezone           *time.Location
+	TrashRetentionDays int
 }
 
 func getEnv(key, fallback string) string {
@@ -39,18 +41,24 @@ func Load() (*Config, error) {
 	token := os.Getenv("TELEGRAM_TOKEN")
 	tzName := getEnv("TZ", "America/New_York")
 
+	retention, err := strconv.Atoi(getEnv("TRASH_RETENTION_DAYS", "30"))
+	if err != nil || retention <= 0 {
+		return nil, fmt.Errorf("invalid TRASH_RETENTION_DAYS")
+	}
+
 	tz, err := time.LoadLocation(tzName)
 	if err != nil {
 		return nil, fmt.Errorf("failed to load timezone %s: %w", tzName, err)
 	}
 
 	return &Config{
-		AppPort:       appPort,
-		MongoURI:      mongoURI,
-		MongoDB:       mongoDB,
-		JWTSecret:     jwtSecret,
-		TelegramChat:  chat,
-		TelegramToken: token,
-		Timezone:      tz,
+		AppPort:            appPort,
+		MongoURI:           mongoURI,
+		MongoDB:            mongoDB,
+		JWTSecret:          jwtSecret,
+		TelegramChat:       chat,
+		TelegramToken:      token,
+		Timezone:           tz,
+		TrashRetentionDays: retention,
 	}, nil
 }
@@ -1,10 +1,15 @@
 package handlers
 
 import (
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/gofiber/fiber/v2"
 	"github.com/tss-booking-system/backend/models"
+	"github.com/tss-booking-system/backend/services"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
@@ -15,18 +20,29 @@ const bayCollection = "bays"
 const bookingColForBay = "bookings"
 
 type bayCreateRequest struct {
-	Key  string `json:"key"`
-	Name string `json:"name"`
+	Key             string   `json:"key"`
+	Name            string   `json:"name"`
+	DefaultDuration int      `json:"default_duration"`
+	Capacity        int      `json:"capacity"`
+	Capabilities    []string `json:"capabilities"`
+	MaxLengthFt     int      `json:"max_length_ft"`
 }
 
+// bayRequest is a bay edit. Scheduling fields left out of the body keep
+// their stored values.
 type bayRequest struct {
-	Key  string `json:"key"`
-	Name string `json:"name"`
+	Key             string    `json:"key"`
+	Name            string    `json:"name"`
+	DefaultDuration *int      `json:"default_duration"`
+	Capacity        *int      `json:"capacity"`
+	Capabilities    *[]string `json:"capabilities"`
+	MaxLengthFt     *int      `json:"max_length_ft"`
+	Version         *int      `json:"version"`
 }
 
 func (h *Handler) ListBays(c *fiber.Ctx) error {
 	opts := options.Find().SetSort(bson.D{{Key: "created_at", Value: -1}})
-	cur, err := h.DB.Collection(bayCollection).Find(h.ctx(c), bson.D{}, opts)
+	cur, err := h.DB.Collection(bayCollection).Find(h.ctx(c), notDeleted(bson.M{}), opts)
 	if err != nil {
 		return fiber.ErrInternalServerError
 	}
@@ -49,6 +65,7 @@ func (h *Handler) GetBay(c *fiber.Ctx) error {
 	if err := h.DB.Collection(bayCollection).FindOne(h.ctx(c), bson.M{"_id": id}).Decode(&b); err != nil {
 		return fiber.ErrInternalServerError
 	}
+	setETag(c, b.Version)
 	return c.JSON(b)
 }
 
@@ -61,12 +78,26 @@ func (h *Handler) CreateBay(c *fiber.Ctx) error {
 		return fiber.ErrBadRequest
 	}
 	now := h.now()
+	if req.DefaultDuration < 0 {
+		return fiber.NewError(fiber.StatusBadRequest, "default_duration must not be negative")
+	}
+	if req.Capacity < 0 {
+		return fiber.NewError(fiber.StatusBadRequest, "capacity must not be negative")
+	}
+	if req.MaxLengthFt < 0 {
+		return fiber.NewError(fiber.StatusBadRequest, "max_length_ft must not be negative")
+	}
+	capabilities := services.NormalizeCapabilities(req.Capabilities)
 	item := models.Bay{
-		ID:        primitive.NewObjectID(),
-		Key:       req.Key,
-		Name:      req.Name,
-		CreatedAt: now,
-		UpdatedAt: now,
+		ID:              primitive.NewObjectID(),
+		Key:             req.Key,
+		Name:            req.Name,
+		DefaultDuration: req.DefaultDuration,
+		Capacity:        req.Capacity,
+		Capabilities:    capabilities,
+		MaxLengthFt:     req.MaxLengthFt,
+		CreatedAt:       now,
+		UpdatedAt:       now,
 	}
 	if _, err := h.DB.Collection(bayCollection).InsertOne(h.ctx(c), item); err != nil {
 		if mongo.IsDuplicateKeyError(err) {
@@ -88,7 +119,7 @@ func (h *Handler) CreateBay(c *fiber.Ctx) error {
 			Entity:    "bay",
 			EntityID:  item.ID,
 			UserID:    actor,
-			Meta:      bson.M{"name": item.Name, "key": item.Key},
+			Meta:      bson.M{"name": item.Name, "key": item.Key, "capacity": services.BayCapacity(item), "capabilities": item.Capabilities, "max_length_ft": item.MaxLengthFt},
 			CreatedAt: now,
 		})
 	}
@@ -106,20 +137,64 @@ func (h *Handler) UpdateBay(c *fiber.Ctx) error {
 	if err := c.BodyParser(&req); err != nil {
 		return fiber.ErrBadRequest
 	}
+	// next is the bay as it will be stored, for the audit diff
+	next := prev
+	next.Key, next.Name = req.Key, req.Name
+	set := bson.M{
+		"key":        req.Key,
+		"name":       req.Name,
+		"updated_at": h.now(),
+	}
+	if req.DefaultDuration != nil {
+		if *req.DefaultDuration < 0 {
+			return fiber.NewError(fiber.StatusBadRequest, "default_duration must not be negative")
+		}
+		next.DefaultDuration = *req.DefaultDuration
+		set["default_duration"] = next.DefaultDuration
+	}
+	if req.Capacity != nil {
+		if *req.Capacity < 0 {
+			return fiber.NewError(fiber.StatusBadRequest, "capacity must not be negative")
+		}
+		next.Capacity = *req.Capacity
+		set["capacity"] = next.Capacity
+	}
+	if req.Capabilities != nil {
+		next.Capabilities = services.NormalizeCapabilities(*req.Capabilities)
+		set["capabilities"] = next.Capabilities
+	}
+	if req.MaxLengthFt != nil {
+		if *req.MaxLengthFt < 0 {
+			return fiber.NewError(fiber.StatusBadRequest, "max_length_ft must not be negative")
+		}
+		next.MaxLengthFt = *req.MaxLengthFt
+		set["max_length_ft"] = next.MaxLengthFt
+	}
 	update := bson.M{
-		"$set": bson.M{
-			"key":        req.Key,
-			"name":       req.Name,
-			"updated_at": h.now(),
-		},
+		"$set": set,
+		"$inc": bson.M{"version": 1},
+	}
+	version, checked, err := expectedVersion(c, req.Version)
+	if err != nil {
+		return err
+	}
+	filter := notDeleted(bson.M{"_id": id})
+	if checked {
+		filter = versionFilter(id, version)
 	}
-	res, err := h.DB.Collection(bayCollection).UpdateByID(h.ctx(c), id, update)
+	res, err := h.DB.Collection(bayCollection).UpdateOne(h.ctx(c), filter, update)
 	if err != nil {
 		return fiber.ErrInternalServerError
 	}
 	if res.MatchedCount == 0 {
+		if checked {
+			return h.conflictResponse(c, h.staleVersion(c, bayCollection, id, &models.Bay{}))
+		}
 		return fiber.ErrNotFound
 	}
+	if checked {
+		setETag(c, version+1)
+	}
 	// audit: bay updated diffs
 	{
 		changes := bson.M{}
@@ -129,6 +204,18 @@ func (h *Handler) UpdateBay(c *fiber.Ctx) error {
 		if prev.Name != req.Name {
 			changes["name"] = bson.M{"from": prev.Name, "to": req.Name}
 		}
+		if prev.DefaultDuration != next.DefaultDuration {
+			changes["default_duration"] = bson.M{"from": prev.DefaultDuration, "to": next.DefaultDuration}
+		}
+		if services.BayCapacity(prev) != services.BayCapacity(next) {
+			changes["capacity"] = bson.M{"from": services.BayCapacity(prev), "to": services.BayCapacity(next)}
+		}
+		if !reflect.DeepEqual(services.NormalizeCapabilities(prev.Capabilities), services.NormalizeCapabilities(next.Capabilities)) {
+			changes["capabilities"] = bson.M{"from": prev.Capabilities, "to": next.Capabilities}
+		}
+		if prev.MaxLengthFt != next.MaxLengthFt {
+			changes["max_length_ft"] = bson.M{"from": prev.MaxLengthFt, "to": next.MaxLengthFt}
+		}
 		if len(changes) > 0 {
 			var actor primitive.ObjectID
 			if uid := getUserID(c); uid != "" {
@@ -150,19 +237,51 @@ func (h *Handler) UpdateBay(c *fiber.Ctx) error {
 	return c.SendStatus(fiber.StatusNoContent)
 }
 
+// DeleteBay moves a bay into the trash. Active bookings block the delete
+// unless mode=cascade trashes them too, or mode=reassign moves them to
+// ?target= (the WaitingList bay when omitted) after a conflict check. The
+// WaitingList bay itself cannot be deleted.
 func (h *Handler) DeleteBay(c *fiber.Ctx) error {
 	id, err := asObjectID(c.Params("id"))
 	if err != nil {
 		return fiber.ErrBadRequest
 	}
-	res, err := h.DB.Collection(bayCollection).DeleteOne(h.ctx(c), bson.M{"_id": id})
-	if err != nil {
-		return fiber.ErrInternalServerError
-	}
-	if res.DeletedCount == 0 {
-		return fiber.ErrNotFound
+	wlID, hasWL := h.findWaitingListBayID(c)
+	if hasWL && wlID == id {
+		return fiber.NewError(fiber.StatusConflict, "the WaitingList bay cannot be deleted")
 	}
-	return c.SendStatus(fiber.StatusNoContent)
+	filter := activeBookings(bson.M{"bay_id": id})
+	deps := []dependent{{"bookings", trashKinds["bookings"], filter}}
+	return h.deleteWithDependents(c, deletePlan{
+		kind:       trashKinds["bays"],
+		id:         id,
+		dependents: deps,
+		cascade: func() (bson.M, error) {
+			return h.cascadeDelete(c, trashKinds["bays"], id, deps...)
+		},
+		reassign: func(target primitive.ObjectID) (bson.M, error) {
+			if target.IsZero() {
+				if !hasWL {
+					return nil, fiber.NewError(fiber.StatusBadRequest, "waiting list bay is not configured")
+				}
+				target = wlID
+			} else if err := h.requireLive(c, trashKinds["bays"], target); err != nil {
+				return nil, err
+			}
+			items, err := h.dependentBookings(c, filter)
+			if err != nil {
+				return nil, err
+			}
+			move := func(b *models.Booking) {
+				b.BayID = target
+				h.trackQueue(c, nil, b)
+			}
+			if err := h.moveBookings(c, items, move, "bay deleted", true); err != nil {
+				return nil, err
+			}
+			return bson.M{"target": target, "reassigned": bson.M{"bookings": len(items)}}, nil
+		},
+	})
 }
 
 // ListBayLogs returns audit logs for a bay
@@ -189,7 +308,11 @@ func (h *Handler) ListBayLogs(c *fiber.Ctx) error {
 
 // ListBayOccupancy returns current occupancy per bay at given timestamp (default: now).
 // A bay is considered occupied if there exists a booking with status open/in_progress,
-// start <= at, and (end >= at or end is missing).
+// start <= at, and end >= at. Open-ended bookings occupy the bay for their
+// estimated duration; in-progress ones until they are closed. Bays with a
+// capacity above one report the earliest booking and the number of bookings
+// under "counts". Bays blocked by a blackout at that time are listed under
+// "blackouts".
 func (h *Handler) ListBayOccupancy(c *fiber.Ctx) error {
 	atStr := c.Query("at", "")
 	var at time.Time
@@ -206,24 +329,27 @@ func (h *Handler) ListBayOccupancy(c *fiber.Ctx) error {
 		}
 	}
 	filter := bson.M{
-		"status": bson.M{"$in": []models.BookingStatus{models.BookingOpen, models.BookingInProgress}},
-		"start":  bson.M{"$lte": at},
+		"status":     bson.M{"$in": []models.BookingStatus{models.BookingOpen, models.BookingInProgress}},
+		"start":      bson.M{"$lte": at},
+		"deleted_at": nil,
 		"$or": []bson.M{
 			{"end": bson.M{"$gte": at}},
 			{"end": bson.M{"$exists": false}},
 		},
 	}
 	proj := bson.M{
-		"_id":         1,
-		"number":      1,
-		"bay_id":      1,
-		"vehicle_id":  1,
-		"company_id":  1,
-		"start":       1,
-		"end":         1,
-		"status":      1,
-		"complaint":   1,
-		"description": 1,
+		"_id":                1,
+		"number":             1,
+		"bay_id":             1,
+		"vehicle_id":         1,
+		"company_id":         1,
+		"start":              1,
+		"end":                1,
+		"status":             1,
+		"actual_start":       1,
+		"estimated_duration": 1,
+		"complaint":          1,
+		"description":        1,
 	}
 	cur, err := h.DB.Collection(bookingColForBay).Find(h.ctx(c), filter, options.Find().SetProjection(proj))
 	if err != nil {
@@ -242,13 +368,257 @@ func (h *Handler) ListBayOccupancy(c *fiber.Ctx) error {
 		Status      models.BookingStatus `bson:"status" json:"status"`
 		Complaint   string               `bson:"complaint,omitempty" json:"complaint,omitempty"`
 		Description string               `bson:"description,omitempty" json:"description,omitempty"`
+		Overdue     bool                 `bson:"-" json:"overdue,omitempty"`
 	}
+	now := h.now()
 	occ := map[string]bookingLite{}
+	counts := map[string]int{}
 	for cur.Next(h.ctx(c)) {
-		var b bookingLite
-		if err := cur.Decode(&b); err == nil {
-			occ[b.BayID.Hex()] = b
+		var b models.Booking
+		if err := cur.Decode(&b); err != nil || !services.OccupiesAt(b, at, now) {
+			continue
+		}
+		counts[b.BayID.Hex()]++
+		if prev, ok := occ[b.BayID.Hex()]; ok && prev.Start.Before(b.Start) {
+			continue
+		}
+		occ[b.BayID.Hex()] = bookingLite{
+			ID:          b.ID,
+			Number:      b.Number,
+			BayID:       b.BayID,
+			VehicleID:   b.VehicleID,
+			CompanyID:   b.CompanyID,
+			Start:       b.Start,
+			End:         b.End,
+			Status:      b.Status,
+			Complaint:   b.Complaint,
+			Description: b.Description,
+			Overdue:     services.IsOverdue(b, now),
+		}
+	}
+	blackouts, err := h.findBayBlackouts(c, at, at.Add(time.Nanosecond))
+	if err != nil {
+		return fiber.ErrInternalServerError
+	}
+	blocked := map[string]models.BayBlackout{}
+	for _, bl := range blackouts {
+		blocked[bl.BayID.Hex()] = bl
+	}
+	cal := h.loadShopCalendar(c)
+	shopOpen := cal.Mode == "" || cal.Mode == models.CalendarOff ||
+		len(services.WorkingWindows(cal, at, at.Add(time.Minute), h.TZ)) > 0
+	return c.JSON(fiber.Map{"occupancy": occ, "counts": counts, "blackouts": blocked, "shop_open": shopOpen, "at": at})
+}
+
+// parseQueryTime accepts RFC3339 timestamps with or without fractional seconds.
+func parseQueryTime(s string) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339Nano, s); err == nil {
+		return t, nil
+	}
+	return time.Parse(time.RFC3339, s)
+}
+
+// parseQueryDuration accepts plain minutes ("90") or Go durations ("1h30m").
+func parseQueryDuration(s string) (time.Duration, error) {
+	if n, err := strconv.Atoi(s); err == nil {
+		return time.Duration(n) * time.Minute, nil
+	}
+	return time.ParseDuration(s)
+}
+
+// splitQueryList splits a comma separated query value, dropping blanks.
+func splitQueryList(s string) []string {
+	var out []string
+	for _, v := range strings.Split(s, ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			out = append(out, v)
 		}
 	}
-	return c.JSON(fiber.Map{"occupancy": occ, "at": at})
+	return out
+}
+
+type baySlot struct {
+	BayID   primitive.ObjectID `json:"bay_id"`
+	BayName string             `json:"bay_name"`
+	Start   time.Time          `json:"start"`
+	End     time.Time          `json:"end"`
+}
+
+// GetBayAvailability returns free windows per bay between from and to that can
+// hold a booking of the given duration (minutes or Go duration, default 60m).
+// Query params:
+//   - bay_ids: comma separated bay ids to limit the search
+//   - skills: comma separated technician skills; windows are limited to times
+//     when at least one technician with all of these skills is free
+//   - capabilities: comma separated bay capability tags the work needs
+//   - job_type: adds the capabilities of that job type
+//   - vehicle_id: skips bays too short for the vehicle
+//   - limit: maximum number of ranked slots (default 20)
+//
+// A bay is busy only while it is filled to capacity. Bay blackouts are
+// treated as busy time and, unless the shop calendar mode is
+// off, windows are limited to opening hours. The WaitingList bay is never
+// offered, nor are bays lacking a required capability or too short for the
+// vehicle. Slots are ranked earliest first.
+func (h *Handler) GetBayAvailability(c *fiber.Ctx) error {
+	from, err := parseQueryTime(c.Query("from"))
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "invalid from")
+	}
+	to, err := parseQueryTime(c.Query("to"))
+	if err != nil || !to.After(from) {
+		return fiber.NewError(fiber.StatusBadRequest, "invalid to")
+	}
+	if to.Sub(from) > 31*24*time.Hour {
+		return fiber.NewError(fiber.StatusBadRequest, "range must not exceed 31 days")
+	}
+	duration := time.Hour
+	if v := c.Query("duration"); v != "" {
+		if duration, err = parseQueryDuration(v); err != nil || duration <= 0 {
+			return fiber.NewError(fiber.StatusBadRequest, "invalid duration")
+		}
+	}
+	limit := c.QueryInt("limit", 20)
+	if limit <= 0 || limit > 200 {
+		limit = 20
+	}
+
+	bayFilter := notDeleted(bson.M{})
+	if ids := splitQueryList(c.Query("bay_ids")); len(ids) > 0 {
+		bayIDs, err := parseObjectIDs(ids)
+		if err != nil {
+			return fiber.NewError(fiber.StatusBadRequest, "invalid bay_ids")
+		}
+		bayFilter["_id"] = bson.M{"$in": bayIDs}
+	}
+	required := splitQueryList(c.Query("capabilities"))
+	if jt, ok := h.findJobType(c, c.Query("job_type")); ok {
+		required = append(required, jt.Capabilities...)
+	}
+	lengthFt := 0
+	if v := c.Query("vehicle_id"); v != "" {
+		vehicleID, err := asObjectID(v)
+		if err != nil {
+			return fiber.NewError(fiber.StatusBadRequest, "invalid vehicle_id")
+		}
+		lengthFt = h.vehicleLengthFt(c, vehicleID)
+	}
+	wlID, _ := h.findWaitingListBayID(c)
+	cur, err := h.DB.Collection(bayCollection).Find(h.ctx(c), bayFilter, options.Find().SetSort(bson.D{{Key: "name", Value: 1}}))
+	if err != nil {
+		return fiber.ErrInternalServerError
+	}
+	defer cur.Close(h.ctx(c))
+	var bays []models.Bay
+	if err := cur.All(h.ctx(c), &bays); err != nil {
+		return fiber.ErrInternalServerError
+	}
+	candidates := make([]models.Bay, 0, len(bays))
+	bayIDs := make([]primitive.ObjectID, 0, len(bays))
+	for _, b := range bays {
+		if b.ID == wlID || services.CheckBayCompatibility(b, required, lengthFt) != nil {
+			continue
+		}
+		candidates = append(candidates, b)
+		bayIDs = append(bayIDs, b.ID)
+	}
+
+	now := h.now()
+	bookings, err := h.findConflictingBookings(c, bayIDs...)
+	if err != nil {
+		return fiber.ErrInternalServerError
+	}
+	byBay := map[primitive.ObjectID][]models.Booking{}
+	for _, b := range bookings {
+		byBay[b.BayID] = append(byBay[b.BayID], b)
+	}
+	blackouts, err := h.findBayBlackouts(c, from, to, bayIDs...)
+	if err != nil {
+		return fiber.ErrInternalServerError
+	}
+	blockedBy := map[primitive.ObjectID][]models.BayBlackout{}
+	for _, bl := range blackouts {
+		blockedBy[bl.BayID] = append(blockedBy[bl.BayID], bl)
+	}
+	// Shop working time limits the offered windows unless enforcement is off
+	var working []services.Window
+	cal := h.loadShopCalendar(c)
+	useCalendar := cal.Mode != "" && cal.Mode != models.CalendarOff
+	if useCalendar {
+		working = services.WorkingWindows(cal, from, to, h.TZ)
+	}
+
+	// Optional technician skill constraint: union of free time of matching technicians
+	var techFree []services.Window
+	skills := splitQueryList(c.Query("skills"))
+	if len(skills) > 0 {
+		tcur, err := h.DB.Collection(technicianCollection).Find(h.ctx(c), notDeleted(bson.M{"skills": bson.M{"$all": skills}}))
+		if err != nil {
+			return fiber.ErrInternalServerError
+		}
+		defer tcur.Close(h.ctx(c))
+		var techs []models.Technician
+		if err := tcur.All(h.ctx(c), &techs); err != nil {
+			return fiber.ErrInternalServerError
+		}
+		techIDs := make([]primitive.ObjectID, 0, len(techs))
+		for _, t := range techs {
+			techIDs = append(techIDs, t.ID)
+		}
+		techBookings, err := h.findTechnicianBookings(c, techIDs, wlID)
+		if err != nil {
+			return fiber.ErrInternalServerError
+		}
+		var free []services.Window
+		for _, id := range techIDs {
+			var own []models.Booking
+			for _, b := range techBookings {
+				for _, t := range b.TechnicianIDs {
+					if t == id {
+						own = append(own, b)
+						break
+					}
+				}
+			}
+			free = append(free, services.FreeWindows(from, to, services.BusyWindows(own, now), 0)...)
+		}
+		techFree = services.MergeWindows(free)
+	}
+
+	type bayAvailability struct {
+		BayID   primitive.ObjectID `json:"bay_id"`
+		BayName string             `json:"bay_name"`
+		Windows []services.Window  `json:"windows"`
+	}
+	out := make([]bayAvailability, 0, len(candidates))
+	slots := make([]baySlot, 0)
+	for _, bay := range candidates {
+		busy := services.SaturatedWindows(byBay[bay.ID], services.BayCapacity(bay), now)
+		busy = append(busy, services.BlackoutWindows(blockedBy[bay.ID])...)
+		windows := services.FreeWindows(from, to, busy, duration)
+		if useCalendar {
+			windows = services.FilterWindows(services.IntersectWindows(windows, working), duration)
+		}
+		if len(skills) > 0 {
+			windows = services.FilterWindows(services.IntersectWindows(windows, techFree), duration)
+		}
+		if windows == nil {
+			windows = []services.Window{}
+		}
+		out = append(out, bayAvailability{BayID: bay.ID, BayName: bay.Name, Windows: windows})
+		for _, w := range windows {
+			slots = append(slots, baySlot{BayID: bay.ID, BayName: bay.Name, Start: w.Start, End: w.Start.Add(duration)})
+		}
+	}
+	sort.SliceStable(slots, func(i, j int) bool { return slots[i].Start.Before(slots[j].Start) })
+	if len(slots) > limit {
+		slots = slots[:limit]
+	}
+	return c.JSON(fiber.Map{
+		"from":     from,
+		"to":       to,
+		"duration": int(duration / time.Minute),
+		"bays":     out,
+		"slots":    slots,
+	})
 }
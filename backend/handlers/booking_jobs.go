@@ -0,0 +1,350 @@
+package handlers
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"github.com/tss-booking-system/backend/models"
+	"github.com/tss-booking-system/backend/services"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+type jobLineRequest struct {
+	Title          string               `json:"title"`
+	Complaint      string               `json:"complaint"`
+	Cause          string               `json:"cause"`
+	Correction     string               `json:"correction"`
+	TechnicianIDs  []string             `json:"technician_ids"`
+	EstimatedHours float64              `json:"estimated_hours"`
+	ActualHours    float64              `json:"actual_hours"`
+	Status         models.JobLineStatus `json:"status"`
+}
+
+// loadBooking returns the booking or a 404/500 fiber error.
+func (h *Handler) loadBooking(c *fiber.Ctx, id primitive.ObjectID) (models.Booking, error) {
+	var b models.Booking
+	if err := h.DB.Collection(bookingCollection).FindOne(h.ctx(c), notDeleted(bson.M{"_id": id})).Decode(&b); err != nil {
+		if err == mongo.ErrNoDocuments {
+			return b, fiber.ErrNotFound
+		}
+		return b, fiber.ErrInternalServerError
+	}
+	return b, nil
+}
+
+// jobTechnicians returns the booking's technicians plus those assigned to a
+// job line that are new to the booking, after a conflict check for the
+// newcomers. Conflicts are returned as is for conflictResponse. The schedule
+// stays locked until unlock is called, once the booking is saved.
+func (h *Handler) jobTechnicians(c *fiber.Ctx, b models.Booking, techIDs []primitive.ObjectID) ([]primitive.ObjectID, func(), error) {
+	assigned := map[primitive.ObjectID]bool{}
+	for _, t := range b.TechnicianIDs {
+		assigned[t] = true
+	}
+	updated := b
+	updated.TechnicianIDs = append([]primitive.ObjectID{}, b.TechnicianIDs...)
+	for _, t := range techIDs {
+		if !assigned[t] {
+			assigned[t] = true
+			updated.TechnicianIDs = append(updated.TechnicianIDs, t)
+		}
+	}
+	if len(updated.TechnicianIDs) == len(b.TechnicianIDs) {
+		return b.TechnicianIDs, func() {}, nil
+	}
+	unlock, err := h.lockSchedule(c, updated)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := h.validateBookingConflicts(c, updated, nil); err != nil {
+		unlock()
+		return nil, nil, err
+	}
+	return updated.TechnicianIDs, unlock, nil
+}
+
+// saveJobLine writes a job line change and the booking technicians it
+// implies in one versioned update, so neither lands without the other.
+// Technicians added through the job line are audited as a booking update.
+// Conflicts and stale versions are returned as is for conflictResponse.
+func (h *Handler) saveJobLine(c *fiber.Ctx, b models.Booking, filter, update bson.M, techIDs []primitive.ObjectID) error {
+	technicians, unlock, err := h.jobTechnicians(c, b, techIDs)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	update["$set"].(bson.M)["technician_ids"] = technicians
+	for k, v := range versionFilter(b.ID, b.Version) {
+		filter[k] = v
+	}
+	res, err := h.DB.Collection(bookingCollection).UpdateOne(h.ctx(c), filter, update)
+	if err != nil {
+		return fiber.ErrInternalServerError
+	}
+	if res.MatchedCount == 0 {
+		return h.staleVersion(c, bookingCollection, b.ID, &models.Booking{})
+	}
+	if len(technicians) > len(b.TechnicianIDs) {
+		updated := b
+		updated.TechnicianIDs = technicians
+		changes := bookingChanges(b, updated)
+		changes["via"] = "job_line"
+		_, _ = h.DB.Collection(auditCollection).InsertOne(h.ctx(c), models.AuditLog{
+			ID:        primitive.NewObjectID(),
+			Action:    "booking.updated",
+			Entity:    "booking",
+			EntityID:  b.ID,
+			UserID:    actorID(c),
+			Meta:      changes,
+			CreatedAt: h.now(),
+		})
+	}
+	return nil
+}
+
+// jobLineChanges diffs two versions of a job line for the audit log.
+func jobLineChanges(prev, next models.JobLine) bson.M {
+	changes := bson.M{}
+	if prev.Title != next.Title {
+		changes["title"] = bson.M{"from": prev.Title, "to": next.Title}
+	}
+	if prev.Complaint != next.Complaint {
+		changes["complaint"] = bson.M{"from": prev.Complaint, "to": next.Complaint}
+	}
+	if prev.Cause != next.Cause {
+		changes["cause"] = bson.M{"from": prev.Cause, "to": next.Cause}
+	}
+	if prev.Correction != next.Correction {
+		changes["correction"] = bson.M{"from": prev.Correction, "to": next.Correction}
+	}
+	if prev.EstimatedHours != next.EstimatedHours {
+		changes["estimated_hours"] = bson.M{"from": prev.EstimatedHours, "to": next.EstimatedHours}
+	}
+	if prev.ActualHours != next.ActualHours {
+		changes["actual_hours"] = bson.M{"from": prev.ActualHours, "to": next.ActualHours}
+	}
+	if prev.Status != next.Status {
+		changes["status"] = bson.M{"from": prev.Status, "to": next.Status}
+	}
+	prevTechs := map[primitive.ObjectID]bool{}
+	for _, t := range prev.TechnicianIDs {
+		prevTechs[t] = true
+	}
+	nextTechs := map[primitive.ObjectID]bool{}
+	for _, t := range next.TechnicianIDs {
+		nextTechs[t] = true
+	}
+	var added, removed []string
+	for t := range nextTechs {
+		if !prevTechs[t] {
+			added = append(added, t.Hex())
+		}
+	}
+	for t := range prevTechs {
+		if !nextTechs[t] {
+			removed = append(removed, t.Hex())
+		}
+	}
+	if len(added) > 0 {
+		changes["technicians_added"] = added
+	}
+	if len(removed) > 0 {
+		changes["technicians_removed"] = removed
+	}
+	return changes
+}
+
+// auditJobLine writes a booking audit entry for a job line change and
+// broadcasts the refreshed booking.
+func (h *Handler) auditJobLine(c *fiber.Ctx, bookingID primitive.ObjectID, action string, job models.JobLine, meta bson.M) {
+	if meta == nil {
+		meta = bson.M{}
+	}
+	meta["job_id"] = job.ID.Hex()
+	meta["job_title"] = job.Title
+	_, _ = h.DB.Collection(auditCollection).InsertOne(h.ctx(c), models.AuditLog{
+		ID:        primitive.NewObjectID(),
+		Action:    action,
+		Entity:    "booking",
+		EntityID:  bookingID,
+		UserID:    actorID(c),
+		Meta:      meta,
+		CreatedAt: h.now(),
+	})
+	if b, err := h.loadBooking(c, bookingID); err == nil {
+		pushRealtime(models.RealtimeEvent{Type: "booking.updated", Data: b})
+	}
+}
+
+// ListBookingJobs returns the job lines of a booking.
+func (h *Handler) ListBookingJobs(c *fiber.Ctx) error {
+	id, err := asObjectID(c.Params("id"))
+	if err != nil {
+		return fiber.ErrBadRequest
+	}
+	b, err := h.loadBooking(c, id)
+	if err != nil {
+		return err
+	}
+	if b.Jobs == nil {
+		b.Jobs = []models.JobLine{}
+	}
+	return c.JSON(b.Jobs)
+}
+
+// CreateBookingJob adds a job line to a booking.
+func (h *Handler) CreateBookingJob(c *fiber.Ctx) error {
+	id, err := asObjectID(c.Params("id"))
+	if err != nil {
+		return fiber.ErrBadRequest
+	}
+	var req jobLineRequest
+	if err := c.BodyParser(&req); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "invalid body")
+	}
+	techIDs, err := parseObjectIDs(req.TechnicianIDs)
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "invalid technician_ids")
+	}
+	now := h.now()
+	job := models.JobLine{
+		ID:             primitive.NewObjectID(),
+		Title:          req.Title,
+		Complaint:      req.Complaint,
+		Cause:          req.Cause,
+		Correction:     req.Correction,
+		TechnicianIDs:  techIDs,
+		EstimatedHours: req.EstimatedHours,
+		ActualHours:    req.ActualHours,
+		Status:         req.Status,
+		CreatedAt:      now,
+		UpdatedAt:      now,
+	}
+	if job.Status == "" {
+		job.Status = models.JobPending
+	}
+	if err := services.ValidateJobLine(job); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, err.Error())
+	}
+	b, err := h.loadBooking(c, id)
+	if err != nil {
+		return err
+	}
+	if err := h.saveJobLine(c, b, bson.M{}, bson.M{
+		"$push": bson.M{"jobs": job},
+		"$set":  bson.M{"updated_at": now},
+		"$inc":  bson.M{"version": 1},
+	}, techIDs); err != nil {
+		return h.conflictResponse(c, err)
+	}
+	h.auditJobLine(c, id, "booking.job_added", job, bson.M{
+		"status":          job.Status,
+		"estimated_hours": job.EstimatedHours,
+	})
+	return c.Status(fiber.StatusCreated).JSON(job)
+}
+
+// UpdateBookingJob replaces the editable fields of a job line.
+func (h *Handler) UpdateBookingJob(c *fiber.Ctx) error {
+	id, err := asObjectID(c.Params("id"))
+	if err != nil {
+		return fiber.ErrBadRequest
+	}
+	jobID, err := asObjectID(c.Params("jobId"))
+	if err != nil {
+		return fiber.ErrBadRequest
+	}
+	var req jobLineRequest
+	if err := c.BodyParser(&req); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "invalid body")
+	}
+	techIDs, err := parseObjectIDs(req.TechnicianIDs)
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "invalid technician_ids")
+	}
+	b, err := h.loadBooking(c, id)
+	if err != nil {
+		return err
+	}
+	var prev *models.JobLine
+	for i := range b.Jobs {
+		if b.Jobs[i].ID == jobID {
+			prev = &b.Jobs[i]
+			break
+		}
+	}
+	if prev == nil {
+		return fiber.ErrNotFound
+	}
+	next := *prev
+	next.Title = req.Title
+	next.Complaint = req.Complaint
+	next.Cause = req.Cause
+	next.Correction = req.Correction
+	next.TechnicianIDs = techIDs
+	next.EstimatedHours = req.EstimatedHours
+	next.ActualHours = req.ActualHours
+	if req.Status != "" {
+		next.Status = req.Status
+	}
+	next.UpdatedAt = h.now()
+	if err := services.ValidateJobLine(next); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, err.Error())
+	}
+	if err := h.saveJobLine(c, b,
+		bson.M{"jobs._id": jobID},
+		bson.M{"$set": bson.M{"jobs.$": next, "updated_at": next.UpdatedAt}, "$inc": bson.M{"version": 1}},
+		techIDs,
+	); err != nil {
+		return h.conflictResponse(c, err)
+	}
+	if changes := jobLineChanges(*prev, next); len(changes) > 0 {
+		h.auditJobLine(c, id, "booking.job_updated", next, changes)
+	}
+	return c.JSON(next)
+}
+
+// DeleteBookingJob removes a job line from a booking. An If-Match header is
+// checked against the booking version like the other booking writes.
+func (h *Handler) DeleteBookingJob(c *fiber.Ctx) error {
+	id, err := asObjectID(c.Params("id"))
+	if err != nil {
+		return fiber.ErrBadRequest
+	}
+	jobID, err := asObjectID(c.Params("jobId"))
+	if err != nil {
+		return fiber.ErrBadRequest
+	}
+	b, err := h.loadBooking(c, id)
+	if err != nil {
+		return err
+	}
+	var job *models.JobLine
+	for i := range b.Jobs {
+		if b.Jobs[i].ID == jobID {
+			job = &b.Jobs[i]
+			break
+		}
+	}
+	if job == nil {
+		return fiber.ErrNotFound
+	}
+	if version, ok, err := expectedVersion(c, nil); err != nil {
+		return err
+	} else if ok && version != b.Version {
+		return h.conflictResponse(c, &staleVersionError{Current: b})
+	}
+	res, err := h.DB.Collection(bookingCollection).UpdateOne(h.ctx(c), versionFilter(id, b.Version), bson.M{
+		"$pull": bson.M{"jobs": bson.M{"_id": jobID}},
+		"$set":  bson.M{"updated_at": h.now()},
+		"$inc":  bson.M{"version": 1},
+	})
+	if err != nil {
+		return fiber.ErrInternalServerError
+	}
+	if res.MatchedCount == 0 {
+		return h.conflictResponse(c, h.staleVersion(c, bookingCollection, id, &models.Booking{}))
+	}
+	h.auditJobLine(c, id, "booking.job_removed", *job, bson.M{"status": job.Status})
+	return c.SendStatus(fiber.StatusNoContent)
+}
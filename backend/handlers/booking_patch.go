@@ -0,0 +1,154 @@
+package handlers
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/tss-booking-system/backend/models"
+	"github.com/tss-booking-system/backend/services"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// optionalTime tells an omitted JSON field apart from an explicit null.
+type optionalTime struct {
+	Set   bool
+	Value *time.Time
+}
+
+func (o *optionalTime) UnmarshalJSON(data []byte) error {
+	o.Set = true
+	if string(data) == "null" {
+		o.Value = nil
+		return nil
+	}
+	var t time.Time
+	if err := json.Unmarshal(data, &t); err != nil {
+		return err
+	}
+	o.Value = &t
+	return nil
+}
+
+// bookingPatchRequest holds the fields of a partial booking update. Omitted
+// fields are left untouched; "end": null makes the booking open-ended and an
+// empty company_id clears the company.
+type bookingPatchRequest struct {
+	Complaint            *string                 `json:"complaint"`
+	Description          *string                 `json:"description"`
+	VehicleID            *string                 `json:"vehicle_id"`
+	FullbayServiceID     *string                 `json:"fullbay_service_id"`
+	BayID                *string                 `json:"bay_id"`
+	TechnicianIDs        *[]string               `json:"technician_ids"`
+	CompanyID            *string                 `json:"company_id"`
+	Start                *time.Time              `json:"start"`
+	End                  optionalTime            `json:"end"`
+	Status               *models.BookingStatus   `json:"status"`
+	Notes                *string                 `json:"notes"`
+	JobType              *string                 `json:"job_type"`
+	EstimatedDuration    *int                    `json:"estimated_duration"`
+	Priority             *models.BookingPriority `json:"priority"`
+	RequiredCapabilities *[]string               `json:"required_capabilities"`
+	Version              *int                    `json:"version"`
+}
+
+// PatchBooking applies a partial update: only the fields present in the body
+// are validated and changed. Scheduling checks, the audit diff, scope=following
+// for series and the Telegram update notice work as for UpdateBooking.
+func (h *Handler) PatchBooking(c *fiber.Ctx) error {
+	id, err := asObjectID(c.Params("id"))
+	if err != nil {
+		return fiber.ErrBadRequest
+	}
+	var req bookingPatchRequest
+	if err := c.BodyParser(&req); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "invalid body")
+	}
+	existingBooking, err := h.loadBooking(c, id)
+	if err != nil {
+		return err
+	}
+	if version, ok, err := expectedVersion(c, req.Version); err != nil {
+		return err
+	} else if ok && version != existingBooking.Version {
+		return h.conflictResponse(c, &staleVersionError{Current: existingBooking})
+	}
+
+	b := existingBooking
+	if req.Complaint != nil {
+		b.Complaint = *req.Complaint
+	}
+	if req.Description != nil {
+		b.Description = *req.Description
+	}
+	if req.VehicleID != nil {
+		if b.VehicleID, err = asObjectID(*req.VehicleID); err != nil {
+			return fiber.NewError(fiber.StatusBadRequest, "invalid vehicle_id")
+		}
+	}
+	if req.FullbayServiceID != nil {
+		b.FullbayServiceID = *req.FullbayServiceID
+	}
+	if req.BayID != nil {
+		if b.BayID, err = asObjectID(*req.BayID); err != nil {
+			return fiber.NewError(fiber.StatusBadRequest, "invalid bay_id")
+		}
+	}
+	if req.TechnicianIDs != nil {
+		if b.TechnicianIDs, err = parseObjectIDs(*req.TechnicianIDs); err != nil {
+			return fiber.NewError(fiber.StatusBadRequest, "invalid technician_ids")
+		}
+	}
+	if req.CompanyID != nil {
+		b.CompanyID = primitive.NilObjectID
+		if *req.CompanyID != "" {
+			if b.CompanyID, err = asObjectID(*req.CompanyID); err != nil {
+				return fiber.NewError(fiber.StatusBadRequest, "invalid company_id")
+			}
+		}
+	}
+	if req.Start != nil {
+		b.Start = req.Start.In(h.TZ)
+	}
+	if req.End.Set {
+		b.End = req.End.Value
+	}
+	if b.End != nil && !b.End.After(b.Start) {
+		return fiber.NewError(fiber.StatusBadRequest, "end must be after start")
+	}
+	if req.Status != nil && *req.Status != b.Status {
+		if err := checkStatusTransition(c, existingBooking.Status, *req.Status); err != nil {
+			return err
+		}
+		b.Status = *req.Status
+	}
+	if req.Notes != nil {
+		b.Notes = *req.Notes
+	}
+	if req.JobType != nil {
+		b.JobType = *req.JobType
+	}
+	if req.Priority != nil {
+		if err := services.ValidatePriority(*req.Priority); err != nil {
+			return fiber.NewError(fiber.StatusBadRequest, err.Error())
+		}
+		if *req.Priority != "" {
+			b.Priority = *req.Priority
+		}
+	}
+	if req.RequiredCapabilities != nil {
+		b.RequiredCapabilities = services.NormalizeCapabilities(*req.RequiredCapabilities)
+	}
+	// The estimate is kept unless it is sent, or the end or job type it was
+	// derived from changes.
+	switch {
+	case req.EstimatedDuration != nil:
+		if *req.EstimatedDuration < 0 {
+			return fiber.NewError(fiber.StatusBadRequest, "estimated_duration must not be negative")
+		}
+		b.EstimatedDuration = h.resolveEstimatedDuration(c, b, *req.EstimatedDuration)
+	case req.End.Set || (req.JobType != nil && b.JobType != existingBooking.JobType):
+		b.EstimatedDuration = h.resolveEstimatedDuration(c, b, 0)
+	}
+	return h.commitBookingUpdate(c, existingBooking, b)
+}
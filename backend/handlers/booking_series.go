@@ -0,0 +1,292 @@
+package handlers
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/tss-booking-system/backend/models"
+	"github.com/tss-booking-system/backend/services"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const bookingSeriesCollection = "booking_series"
+
+// occurrenceError prefixes conflict messages with the occurrence date so the
+// office can see which slot of a series is blocked.
+func (h *Handler) occurrenceError(start time.Time, err error) error {
+	if fe, ok := err.(*fiber.Error); ok {
+		return fiber.NewError(fe.Code, fmt.Sprintf("occurrence %s: %s", start.In(h.TZ).Format("01/02/2006"), fe.Message))
+	}
+	return err
+}
+
+// createBookingSeries expands rule from template.Start and inserts one booking
+// per occurrence. Every occurrence gets its own number and must pass conflict
+// validation; nothing is inserted if any occurrence is blocked.
+func (h *Handler) createBookingSeries(c *fiber.Ctx, template models.Booking, rule models.RecurrenceRule) error {
+	starts, err := services.ExpandRecurrence(rule, template.Start, h.TZ)
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, err.Error())
+	}
+	if len(starts) == 0 {
+		return fiber.NewError(fiber.StatusBadRequest, "recurrence rule produces no occurrences")
+	}
+	series := models.BookingSeries{
+		ID:        primitive.NewObjectID(),
+		Rule:      rule,
+		Start:     template.Start,
+		CreatedBy: template.CreatedBy,
+		CreatedAt: template.CreatedAt,
+		UpdatedAt: template.CreatedAt,
+	}
+	// every occurrence uses the template's bay and technicians
+	unlock, err := h.lockSchedule(c, template)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	bookings := make([]models.Booking, 0, len(starts))
+	for i, start := range starts {
+		b := template
+		b.ID = primitive.NewObjectID()
+		b.Start = start
+		if template.End != nil {
+			end := start.Add(template.End.Sub(template.Start))
+			b.End = &end
+		}
+		b.SeriesID = &series.ID
+		b.Occurrence = i + 1
+		// earlier occurrences count against the bay and technicians as well
+		if err := h.validateBookingSchedule(c, &b, bookings); err != nil {
+			return h.conflictResponse(c, h.occurrenceError(start, err))
+		}
+		bookings = append(bookings, b)
+	}
+
+	if _, err := h.DB.Collection(bookingSeriesCollection).InsertOne(h.ctx(c), series); err != nil {
+		return fiber.ErrInternalServerError
+	}
+	docs := make([]interface{}, 0, len(bookings))
+	for i := range bookings {
+		bookings[i].Number = h.nextBookingNumber(c)
+		docs = append(docs, bookings[i])
+	}
+	if _, err := h.DB.Collection(bookingCollection).InsertMany(h.ctx(c), docs); err != nil {
+		for i := len(bookings) - 1; i >= 0; i-- {
+			h.releaseBookingNumber(c, bookings[i].Number)
+		}
+		return fiber.ErrInternalServerError
+	}
+	unlock()
+	for _, b := range bookings {
+		h.auditBookingCreated(c, b)
+		pushRealtime(models.RealtimeEvent{Type: "booking.created", Data: b})
+	}
+	_, _ = h.DB.Collection(auditCollection).InsertOne(h.ctx(c), models.AuditLog{
+		ID:       primitive.NewObjectID(),
+		Action:   "booking.series_created",
+		Entity:   "booking",
+		EntityID: bookings[0].ID,
+		UserID:   actorID(c),
+		Meta: bson.M{
+			"series_id":   series.ID.Hex(),
+			"rule":        series.Rule,
+			"occurrences": len(bookings),
+			"first":       bookings[0].Number,
+			"last":        bookings[len(bookings)-1].Number,
+		},
+		CreatedAt: h.now(),
+	})
+	h.notifyBookingCreated(c, bookings[0])
+	return c.Status(fiber.StatusCreated).JSON(fiber.Map{"series": series, "bookings": bookings})
+}
+
+// findSeriesOccurrences returns bookings of a series starting at occurrence
+// fromOccurrence, ordered by occurrence.
+func (h *Handler) findSeriesOccurrences(c *fiber.Ctx, seriesID primitive.ObjectID, fromOccurrence int) ([]models.Booking, error) {
+	cur, err := h.DB.Collection(bookingCollection).Find(h.ctx(c), bson.M{
+		"series_id":  seriesID,
+		"occurrence": bson.M{"$gte": fromOccurrence},
+		"deleted_at": nil,
+	}, options.Find().SetSort(bson.D{{Key: "occurrence", Value: 1}}))
+	if err != nil {
+		return nil, err
+	}
+	defer cur.Close(h.ctx(c))
+	var items []models.Booking
+	if err := cur.All(h.ctx(c), &items); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+// updateFollowingOccurrences applies an edit of one occurrence to it and every
+// later open occurrence of the same series. Later occurrences are shifted by
+// the same offset and take over the new duration and booking details.
+func (h *Handler) updateFollowingOccurrences(c *fiber.Ctx, existingBooking, updatedBooking models.Booking) error {
+	following, err := h.findSeriesOccurrences(c, *existingBooking.SeriesID, existingBooking.Occurrence)
+	if err != nil {
+		return fiber.ErrInternalServerError
+	}
+	shift := updatedBooking.Start.Sub(existingBooking.Start)
+	moving := make([]primitive.ObjectID, 0, len(following))
+	for _, prev := range following {
+		moving = append(moving, prev.ID)
+	}
+
+	type pair struct{ prev, next models.Booking }
+	var changes []pair
+	var planned []models.Booking
+	for _, prev := range following {
+		next := updatedBooking
+		if prev.ID == existingBooking.ID {
+			// the edited occurrence was checked against the version the client sent
+			prev = existingBooking
+		} else {
+			if prev.Status != models.BookingOpen {
+				continue
+			}
+			next = prev
+			next.Complaint = updatedBooking.Complaint
+			next.Description = updatedBooking.Description
+			next.VehicleID = updatedBooking.VehicleID
+			next.FullbayServiceID = updatedBooking.FullbayServiceID
+			next.BayID = updatedBooking.BayID
+			next.TechnicianIDs = updatedBooking.TechnicianIDs
+			next.CompanyID = updatedBooking.CompanyID
+			next.Notes = updatedBooking.Notes
+			next.JobType = updatedBooking.JobType
+			next.EstimatedDuration = updatedBooking.EstimatedDuration
+			next.Start = prev.Start.Add(shift)
+			next.End = nil
+			if updatedBooking.End != nil {
+				end := next.Start.Add(updatedBooking.End.Sub(updatedBooking.Start))
+				next.End = &end
+			}
+			next.UpdatedAt = updatedBooking.UpdatedAt
+		}
+		if err := h.validateBookingSchedule(c, &next, planned, moving...); err != nil {
+			return h.conflictResponse(c, h.occurrenceError(next.Start, err))
+		}
+		planned = append(planned, next)
+		changes = append(changes, pair{prev: prev, next: next})
+	}
+
+	// Check every occurrence before the first write so a concurrent edit of
+	// one of them does not leave the series half-moved.
+	prevs := make([]models.Booking, 0, len(changes))
+	for _, ch := range changes {
+		prevs = append(prevs, ch.prev)
+	}
+	if err := h.checkBookingVersions(c, prevs...); err != nil {
+		return h.conflictResponse(c, err)
+	}
+
+	meta := bson.M{"scope": "following", "series_id": existingBooking.SeriesID.Hex()}
+	out := make([]models.Booking, 0, len(changes))
+	for _, ch := range changes {
+		next := ch.next
+		if err := h.saveBookingUpdate(c, ch.prev, &next, meta); err != nil {
+			return h.conflictResponse(c, err)
+		}
+		out = append(out, next)
+	}
+	if len(changes) > 0 {
+		h.notifyBookingUpdated(c, changes[0].prev, changes[0].next)
+	}
+	return c.JSON(out)
+}
+
+// cancelFollowingOccurrences cancels b and every later open occurrence of its
+// series, and ends the series rule before b.
+func (h *Handler) cancelFollowingOccurrences(c *fiber.Ctx, b models.Booking) error {
+	following, err := h.findSeriesOccurrences(c, *b.SeriesID, b.Occurrence)
+	if err != nil {
+		return fiber.ErrInternalServerError
+	}
+	// b as loaded by CancelBooking, whose status the transition was checked
+	// against, and the later open occurrences; every version is checked before
+	// the first write
+	targets := []models.Booking{b}
+	for _, occ := range following {
+		if occ.ID != b.ID && occ.Status == models.BookingOpen {
+			targets = append(targets, occ)
+		}
+	}
+	if err := h.checkBookingVersions(c, targets...); err != nil {
+		return h.conflictResponse(c, err)
+	}
+	now := h.now()
+	for _, occ := range targets {
+		set := bson.M{"status": models.BookingCanceled, "updated_at": now}
+		if occ.ID == b.ID {
+			set["end"] = &now
+		}
+		filter := versionFilter(occ.ID, occ.Version)
+		filter["status"] = occ.Status
+		res, err := h.DB.Collection(bookingCollection).UpdateOne(h.ctx(c), filter, bson.M{"$set": set, "$inc": bson.M{"version": 1}})
+		if err != nil {
+			return fiber.ErrInternalServerError
+		}
+		if res.MatchedCount == 0 {
+			return h.conflictResponse(c, h.staleVersion(c, bookingCollection, occ.ID, &models.Booking{}))
+		}
+		if occ.ID == b.ID {
+			h.stopBookingClocks(c, occ.ID)
+		}
+		pushRealtime(models.RealtimeEvent{Type: "booking.canceled", Data: occ.ID.Hex()})
+		_, _ = h.DB.Collection(auditCollection).InsertOne(h.ctx(c), models.AuditLog{
+			ID:        primitive.NewObjectID(),
+			Action:    "booking.canceled",
+			Entity:    "booking",
+			EntityID:  occ.ID,
+			UserID:    actorID(c),
+			Meta:      bson.M{"scope": "following", "series_id": b.SeriesID.Hex()},
+			CreatedAt: now,
+		})
+	}
+	until := b.Start.Add(-time.Second)
+	_, _ = h.DB.Collection(bookingSeriesCollection).UpdateByID(h.ctx(c), *b.SeriesID, bson.M{
+		"$set":   bson.M{"rule.until": until, "updated_at": now},
+		"$unset": bson.M{"rule.count": ""},
+	})
+	b.Status = models.BookingCanceled
+	b.End = &now
+	data := h.buildTelegramData(c, b)
+	_ = h.Telegram.Notify(h.renderTelegramFallback("canceled", b, data))
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+// GetBookingSeries returns the series a booking belongs to with all its occurrences.
+func (h *Handler) GetBookingSeries(c *fiber.Ctx) error {
+	id, err := asObjectID(c.Params("id"))
+	if err != nil {
+		return fiber.ErrBadRequest
+	}
+	var b models.Booking
+	if err := h.DB.Collection(bookingCollection).FindOne(h.ctx(c), bson.M{"_id": id}).Decode(&b); err != nil {
+		if err == mongo.ErrNoDocuments {
+			return fiber.ErrNotFound
+		}
+		return fiber.ErrInternalServerError
+	}
+	if b.SeriesID == nil {
+		return fiber.NewError(fiber.StatusNotFound, "booking is not part of a series")
+	}
+	var series models.BookingSeries
+	if err := h.DB.Collection(bookingSeriesCollection).FindOne(h.ctx(c), bson.M{"_id": *b.SeriesID}).Decode(&series); err != nil {
+		if err == mongo.ErrNoDocuments {
+			return fiber.ErrNotFound
+		}
+		return fiber.ErrInternalServerError
+	}
+	items, err := h.findSeriesOccurrences(c, series.ID, 0)
+	if err != nil {
+		return fiber.ErrInternalServerError
+	}
+	return c.JSON(fiber.Map{"series": series, "bookings": items})
+}
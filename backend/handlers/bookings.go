@@ -3,7 +3,10 @@ package handlers
 import (
 	"bytes"
 	"encoding/csv"
+	"errors"
 	"fmt"
+	"sort"
+	"strconv"
 	"strings"
 	"time"
 
@@ -19,17 +22,25 @@ import (
 const bookingCollection = "bookings"
 
 type bookingRequest struct {
-	Complaint        string               `json:"complaint"`
-	Description      string               `json:"description"`
-	VehicleID        string               `json:"vehicle_id"`
-	FullbayServiceID string               `json:"fullbay_service_id"`
-	BayID            string               `json:"bay_id"`
-	TechnicianIDs    []string             `json:"technician_ids"`
-	CompanyID        string               `json:"company_id"`
-	Start            time.Time            `json:"start"`
-	End              *time.Time           `json:"end"`
-	Status           models.BookingStatus `json:"status"`
-	Notes            string               `json:"notes"`
+	Complaint            string                 `json:"complaint"`
+	Description          string                 `json:"description"`
+	VehicleID            string                 `json:"vehicle_id"`
+	FullbayServiceID     string                 `json:"fullbay_service_id"`
+	BayID                string                 `json:"bay_id"`
+	TechnicianIDs        []string               `json:"technician_ids"`
+	CompanyID            string                 `json:"company_id"`
+	Start                time.Time              `json:"start"`
+	End                  *time.Time             `json:"end"`
+	Status               models.BookingStatus   `json:"status"`
+	Notes                string                 `json:"notes"`
+	JobType              string                 `json:"job_type"`
+	EstimatedDuration    int                    `json:"estimated_duration"`
+	Priority             models.BookingPriority `json:"priority"`
+	RequiredCapabilities []string               `json:"required_capabilities"`
+	Recurrence           *models.RecurrenceRule `json:"recurrence"`
+	Version              *int                   `json:"version"`
+	// odometer and engine hours read at check-in (create only)
+	meterReadingRequest
 }
 
 func parseObjectIDs(values []string) ([]primitive.ObjectID, error) {
@@ -80,7 +91,7 @@ func (h *Handler) ListBookings(c *fiber.Ctx) error {
 	}
 	cur, err := h.DB.Collection(bookingCollection).Find(
 		h.ctx(c),
-		filter,
+		notDeleted(filter),
 		options.Find().SetSort(bson.D{{Key: "created_at", Value: -1}}),
 	)
 	if err != nil {
@@ -92,6 +103,16 @@ func (h *Handler) ListBookings(c *fiber.Ctx) error {
 	if err := cur.All(h.ctx(c), &items); err != nil {
 		return fiber.ErrInternalServerError
 	}
+	h.flagOverdue(items)
+	if c.QueryBool("overdue") {
+		overdue := make([]models.Booking, 0, len(items))
+		for _, b := range items {
+			if b.Overdue {
+				overdue = append(overdue, b)
+			}
+		}
+		items = overdue
+	}
 	// CSV export if requested
 	if exp := strings.ToLower(c.Query("export")); exp == "csv" || exp == "excel" {
 		// Resolve referenced labels (unit plate/vin, bay/company names, technician names)
@@ -165,7 +186,7 @@ func (h *Handler) ListBookings(c *fiber.Ctx) error {
 		w := csv.NewWriter(&buf)
 		_ = w.Write([]string{
 			"number", "complaint", "description", "unit", "bay", "company", "technicians",
-			"start", "end", "status",
+			"start", "end", "status", "jobs", "odometer", "engine_hours",
 		})
 		const pretty = "01/02/2006, 03:04 PM"
 		for _, b := range items {
@@ -205,6 +226,9 @@ func (h *Handler) ListBookings(c *fiber.Ctx) error {
 				b.Start.In(h.TZ).Format(pretty),
 				end,
 				string(b.Status),
+				services.FormatJobLines(b.Jobs, "; "),
+				meterValue(float64(b.Odometer)),
+				meterValue(b.EngineHours),
 			})
 		}
 		w.Flush()
@@ -215,6 +239,14 @@ func (h *Handler) ListBookings(c *fiber.Ctx) error {
 	return c.JSON(items)
 }
 
+// meterValue formats a meter reading for the CSV export, blank when not read.
+func meterValue(v float64) string {
+	if v == 0 {
+		return ""
+	}
+	return strconv.FormatFloat(v, 'f', -1, 64)
+}
+
 func (h *Handler) GetBooking(c *fiber.Ctx) error {
 	id, err := asObjectID(c.Params("id"))
 	if err != nil {
@@ -227,7 +259,16 @@ func (h *Handler) GetBooking(c *fiber.Ctx) error {
 		}
 		return fiber.ErrInternalServerError
 	}
-	return c.JSON(b)
+	b.Overdue = services.IsOverdue(b, h.now())
+	labor, err := h.laborTotals(c, bson.M{"booking_id": b.ID})
+	if err != nil {
+		return fiber.ErrInternalServerError
+	}
+	setETag(c, b.Version)
+	return c.JSON(struct {
+		models.Booking
+		Labor services.LaborTotals `json:"labor"`
+	}{b, labor})
 }
 
 func (h *Handler) CreateBooking(c *fiber.Ctx) error {
@@ -244,7 +285,6 @@ func (h *Handler) CreateBooking(c *fiber.Ctx) error {
 	if err != nil {
 		return fiber.NewError(fiber.StatusBadRequest, "invalid bay_id")
 	}
-	// capacity removed; no need to load bay here
 	var companyID primitive.ObjectID
 	if req.CompanyID != "" {
 		companyID, err = asObjectID(req.CompanyID)
@@ -257,70 +297,147 @@ func (h *Handler) CreateBooking(c *fiber.Ctx) error {
 		return fiber.NewError(fiber.StatusBadRequest, "invalid technician_ids")
 	}
 
-	status := req.Status
-	if status == "" {
-		status = models.BookingOpen
+	if err := services.ValidateInitialStatus(req.Status); err != nil {
+		return fiber.NewError(fiber.StatusUnprocessableEntity, err.Error())
+	}
+	status := models.BookingOpen
+	if err := services.ValidatePriority(req.Priority); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, err.Error())
+	}
+	priority := req.Priority
+	if priority == "" {
+		priority = models.PriorityNormal
 	}
 
 	now := h.now()
 	booking := models.Booking{
-		ID:               primitive.NewObjectID(),
-		Number:           "",
-		Title:            "",
-		Complaint:        req.Complaint,
-		Description:      req.Description,
-		VehicleID:        vehicleID,
-		FullbayServiceID: req.FullbayServiceID,
-		BayID:            bayID,
-		TechnicianIDs:    technicianIDs,
-		CompanyID:        companyID,
-		Start:            req.Start.In(h.TZ),
-		End:              req.End,
-		Status:           status,
-		Notes:            req.Notes,
-		CreatedBy:        primitive.NilObjectID,
-		CreatedAt:        now,
-		UpdatedAt:        now,
-	}
-	// Generate sequential booking number 000001, 000002, ...
-	{
-		var seqDoc struct {
-			Seq int64 `bson:"seq"`
-		}
-		err := h.DB.Collection("counters").
-			FindOneAndUpdate(
-				h.ctx(c),
-				bson.M{"_id": "booking_number"},
-				bson.M{"$inc": bson.M{"seq": 1}},
-				options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After),
-			).Decode(&seqDoc)
-		if err == nil && seqDoc.Seq > 0 {
-			booking.Number = fmt.Sprintf("%06d", seqDoc.Seq)
-		} else {
-			// fallback to timestamp if counter fails
-			booking.Number = fmt.Sprintf("%06d", time.Now().Unix()%1000000)
-		}
-	}
+		ID:                   primitive.NewObjectID(),
+		Number:               "",
+		Title:                "",
+		Complaint:            req.Complaint,
+		Description:          req.Description,
+		VehicleID:            vehicleID,
+		FullbayServiceID:     req.FullbayServiceID,
+		BayID:                bayID,
+		TechnicianIDs:        technicianIDs,
+		CompanyID:            companyID,
+		Start:                req.Start.In(h.TZ),
+		End:                  req.End,
+		Status:               status,
+		Notes:                req.Notes,
+		JobType:              req.JobType,
+		Priority:             priority,
+		RequiredCapabilities: services.NormalizeCapabilities(req.RequiredCapabilities),
+		CreatedBy:            primitive.NilObjectID,
+		CreatedAt:            now,
+		UpdatedAt:            now,
+	}
+	booking.EstimatedDuration = h.resolveEstimatedDuration(c, booking, req.EstimatedDuration)
+	h.trackQueue(c, nil, &booking)
 	if uid := getUserID(c); uid != "" {
 		if userID, err := primitive.ObjectIDFromHex(uid); err == nil {
 			booking.CreatedBy = userID
 		}
 	}
 
-	// Skip conflict validation for the special "WaitingList" bay
-	if wlID, ok := h.findWaitingListBayID(c); !ok || wlID != booking.BayID {
-		existing, err := h.findConflictingBookings(c, booking.BayID)
-		if err != nil {
+	// Recurring bookings generate one booking per occurrence
+	if req.Recurrence != nil {
+		if !req.meterReadingRequest.empty() {
+			return fiber.NewError(fiber.StatusBadRequest, "meter readings cannot be recorded on a recurring booking")
+		}
+		return h.createBookingSeries(c, booking, *req.Recurrence)
+	}
+	var readingOverride bool
+	if !req.meterReadingRequest.empty() {
+		if readingOverride, err = h.checkMeterReading(c, vehicleID, req.meterReadingRequest); err != nil {
+			return meterReadingError(c, err)
+		}
+		booking.Odometer = req.Odometer
+		booking.EngineHours = req.EngineHours
+	}
+
+	// The bay and technicians stay locked from the conflict check until the
+	// booking is stored, so concurrent requests cannot both take the slot.
+	// The reading is stored and applied first and dropped if the booking is
+	// not, so the request cannot fail after the booking exists
+	var reading *models.MeterReading
+	err = h.scheduleBooking(c, booking, func() error {
+		// Validate overlaps and working time (skipped for the "WaitingList" bay)
+		return h.validateBookingSchedule(c, &booking, nil)
+	}, func() error {
+		var prevVehicle models.Vehicle
+		if !req.meterReadingRequest.empty() {
+			r, err := h.recordMeterReading(c, vehicleID, &booking.ID, req.meterReadingRequest, "booking.created", readingOverride)
+			if err != nil {
+				return err
+			}
+			if prevVehicle, err = h.applyMeterReading(c, r); err != nil {
+				h.dropMeterReading(c, r, nil)
+				return err
+			}
+			reading = &r
+		}
+		booking.Number = h.nextBookingNumber(c)
+		if _, err := h.DB.Collection(bookingCollection).InsertOne(h.ctx(c), booking); err != nil {
+			h.releaseBookingNumber(c, booking.Number)
+			if reading != nil {
+				h.dropMeterReading(c, *reading, &prevVehicle)
+			}
 			return fiber.ErrInternalServerError
 		}
-		if err := services.ValidateBookingConflict(booking, existing, 1); err != nil {
-			return fiber.NewError(fiber.StatusConflict, err.Error())
+		h.auditBookingCreated(c, booking)
+		return nil
+	})
+	if err != nil {
+		var rollback *meterRollback
+		if errors.As(err, &rollback) {
+			return meterReadingError(c, err)
 		}
+		return h.conflictResponse(c, err)
+	}
+	if reading != nil {
+		h.auditMeterOverride(c, *reading)
 	}
 
-	if _, err := h.DB.Collection(bookingCollection).InsertOne(h.ctx(c), booking); err != nil {
-		return fiber.ErrInternalServerError
+	pushRealtime(models.RealtimeEvent{Type: "booking.created", Data: booking})
+	h.notifyBookingCreated(c, booking)
+	return c.Status(fiber.StatusCreated).JSON(booking)
+}
+
+// nextBookingNumber generates sequential booking numbers 000001, 000002, ...
+func (h *Handler) nextBookingNumber(c *fiber.Ctx) string {
+	var seqDoc struct {
+		Seq int64 `bson:"seq"`
+	}
+	err := h.DB.Collection("counters").
+		FindOneAndUpdate(
+			h.ctx(c),
+			bson.M{"_id": "booking_number"},
+			bson.M{"$inc": bson.M{"seq": 1}},
+			options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After),
+		).Decode(&seqDoc)
+	if err == nil && seqDoc.Seq > 0 {
+		return fmt.Sprintf("%06d", seqDoc.Seq)
+	}
+	// fallback to timestamp if counter fails
+	return fmt.Sprintf("%06d", time.Now().Unix()%1000000)
+}
+
+// releaseBookingNumber gives back a number whose booking was not stored, as
+// long as no later number has been handed out in the meantime.
+func (h *Handler) releaseBookingNumber(c *fiber.Ctx, number string) {
+	seq, err := strconv.ParseInt(number, 10, 64)
+	if err != nil {
+		return
 	}
+	_, _ = h.DB.Collection("counters").UpdateOne(h.ctx(c),
+		bson.M{"_id": "booking_number", "seq": seq},
+		bson.M{"$inc": bson.M{"seq": -1}},
+	)
+}
+
+// auditBookingCreated writes booking.created and per-technician assignment logs.
+func (h *Handler) auditBookingCreated(c *fiber.Ctx, booking models.Booking) {
 	// audit: booking.created
 	{
 		var actor primitive.ObjectID
@@ -338,6 +455,10 @@ func (h *Handler) CreateBooking(c *fiber.Ctx) error {
 			"end":        booking.End,
 			"status":     booking.Status,
 		}
+		if booking.SeriesID != nil {
+			meta["series_id"] = booking.SeriesID.Hex()
+			meta["occurrence"] = booking.Occurrence
+		}
 		_, _ = h.DB.Collection(auditCollection).InsertOne(h.ctx(c), models.AuditLog{
 			ID:        primitive.NewObjectID(),
 			Action:    "booking.created",
@@ -368,8 +489,10 @@ func (h *Handler) CreateBooking(c *fiber.Ctx) error {
 			})
 		}
 	}
+}
 
-	pushRealtime(models.RealtimeEvent{Type: "booking.created", Data: booking})
+// notifyBookingCreated sends the "new booking" Telegram message.
+func (h *Handler) notifyBookingCreated(c *fiber.Ctx, booking models.Booking) {
 	// Try templated notification
 	var settings models.Settings
 	_ = h.DB.Collection(settingsCollection).FindOne(h.ctx(c), bson.M{"_id": "global"}).Decode(&settings)
@@ -388,7 +511,6 @@ func (h *Handler) CreateBooking(c *fiber.Ctx) error {
 		data := h.buildTelegramData(c, booking)
 		_ = h.Telegram.Notify(h.renderTelegramFallback("created", booking, data))
 	}
-	return c.Status(fiber.StatusCreated).JSON(booking)
 }
 
 func (h *Handler) UpdateBooking(c *fiber.Ctx) error {
@@ -409,7 +531,6 @@ func (h *Handler) UpdateBooking(c *fiber.Ctx) error {
 	if err != nil {
 		return fiber.NewError(fiber.StatusBadRequest, "invalid bay_id")
 	}
-	// capacity removed; no need to load bay here
 	var companyID primitive.ObjectID
 	if req.CompanyID != "" {
 		companyID, err = asObjectID(req.CompanyID)
@@ -423,13 +544,19 @@ func (h *Handler) UpdateBooking(c *fiber.Ctx) error {
 	}
 
 	var existingBooking models.Booking
-	if err := h.DB.Collection(bookingCollection).FindOne(h.ctx(c), bson.M{"_id": id}).Decode(&existingBooking); err != nil {
+	if err := h.DB.Collection(bookingCollection).FindOne(h.ctx(c), notDeleted(bson.M{"_id": id})).Decode(&existingBooking); err != nil {
 		if err == mongo.ErrNoDocuments {
 			return fiber.ErrNotFound
 		}
 		return fiber.ErrInternalServerError
 	}
 
+	if version, ok, err := expectedVersion(c, req.Version); err != nil {
+		return err
+	} else if ok && version != existingBooking.Version {
+		return h.conflictResponse(c, &staleVersionError{Current: existingBooking})
+	}
+
 	updatedBooking := existingBooking
 	updatedBooking.Title = ""
 	updatedBooking.Complaint = req.Complaint
@@ -442,115 +569,125 @@ func (h *Handler) UpdateBooking(c *fiber.Ctx) error {
 	updatedBooking.Start = req.Start.In(h.TZ)
 	updatedBooking.End = req.End
 	if req.Status != "" {
+		if err := checkStatusTransition(c, existingBooking.Status, req.Status); err != nil {
+			return err
+		}
 		updatedBooking.Status = req.Status
 	}
 	updatedBooking.Notes = req.Notes
+	updatedBooking.JobType = req.JobType
+	updatedBooking.RequiredCapabilities = services.NormalizeCapabilities(req.RequiredCapabilities)
+	// As with PATCH, an omitted estimate is kept unless the end or job type
+	// it was derived from changes.
+	switch {
+	case req.EstimatedDuration < 0:
+		return fiber.NewError(fiber.StatusBadRequest, "estimated_duration must not be negative")
+	case req.EstimatedDuration > 0,
+		!sameEnd(existingBooking.End, updatedBooking.End),
+		updatedBooking.JobType != existingBooking.JobType:
+		updatedBooking.EstimatedDuration = h.resolveEstimatedDuration(c, updatedBooking, req.EstimatedDuration)
+	}
+	if req.Priority != "" {
+		if err := services.ValidatePriority(req.Priority); err != nil {
+			return fiber.NewError(fiber.StatusBadRequest, err.Error())
+		}
+		updatedBooking.Priority = req.Priority
+	}
+	return h.commitBookingUpdate(c, existingBooking, updatedBooking)
+}
+
+// commitBookingUpdate validates and stores an edited booking for PUT and
+// PATCH: it tracks the waiting queue, stamps the actual start, runs schedule
+// validation, handles scope=following for series and notifies.
+func (h *Handler) commitBookingUpdate(c *fiber.Ctx, existingBooking, updatedBooking models.Booking) error {
+	unlock, err := h.lockSchedule(c, updatedBooking)
+	if err != nil {
+		return err
+	}
+	defer unlock()
 	updatedBooking.UpdatedAt = h.now()
+	h.trackQueue(c, &existingBooking, &updatedBooking)
+	if updatedBooking.Status == models.BookingInProgress && updatedBooking.ActualStart == nil {
+		now := updatedBooking.UpdatedAt
+		updatedBooking.ActualStart = &now
+	}
 
-	// Skip conflict validation if updating to the special "WaitingList" bay
-	if wlID, ok := h.findWaitingListBayID(c); !ok || wlID != updatedBooking.BayID {
-		conflicts, err := h.findConflictingBookings(c, updatedBooking.BayID)
-		if err != nil {
-			return fiber.ErrInternalServerError
-		}
-		filtered := make([]models.Booking, 0, len(conflicts))
-		for _, b := range conflicts {
-			if b.ID != id {
-				filtered = append(filtered, b)
-			}
-		}
-		if err := services.ValidateBookingConflict(updatedBooking, filtered, 1); err != nil {
-			return fiber.NewError(fiber.StatusConflict, err.Error())
-		}
+	// Validate overlaps and working time (skipped for the "WaitingList" bay)
+	if err := h.validateBookingSchedule(c, &updatedBooking, nil); err != nil {
+		return h.conflictResponse(c, err)
+	}
+
+	// Recurring bookings: apply the change to this and the following occurrences
+	if existingBooking.SeriesID != nil && c.Query("scope") == "following" {
+		return h.updateFollowingOccurrences(c, existingBooking, updatedBooking)
 	}
 
+	if err := h.saveBookingUpdate(c, existingBooking, &updatedBooking, nil); err != nil {
+		return h.conflictResponse(c, err)
+	}
+	unlock()
+	h.notifyBookingUpdated(c, existingBooking, updatedBooking)
+	return c.JSON(updatedBooking)
+}
+
+// saveBookingUpdate persists updatedBooking, writes the audit diff against
+// existingBooking and broadcasts the change. extraMeta is merged into the audit entry when anything changed.
+// The write only applies while the stored version still matches existingBooking;
+// otherwise a staleVersionError with the current booking is returned.
+func (h *Handler) saveBookingUpdate(c *fiber.Ctx, existingBooking models.Booking, next *models.Booking, extraMeta bson.M) error {
+	updatedBooking := *next
 	update := bson.M{
 		"$set": bson.M{
-			"title":              updatedBooking.Title,
-			"complaint":          updatedBooking.Complaint,
-			"description":        updatedBooking.Description,
-			"vehicle_id":         updatedBooking.VehicleID,
-			"fullbay_service_id": updatedBooking.FullbayServiceID,
-			"bay_id":             updatedBooking.BayID,
-			"technician_ids":     updatedBooking.TechnicianIDs,
-			"company_id":         updatedBooking.CompanyID,
-			"start":              updatedBooking.Start,
-			"end":                updatedBooking.End,
-			"status":             updatedBooking.Status,
-			"notes":              updatedBooking.Notes,
-			"updated_at":         updatedBooking.UpdatedAt,
+			"title":                 updatedBooking.Title,
+			"complaint":             updatedBooking.Complaint,
+			"description":           updatedBooking.Description,
+			"vehicle_id":            updatedBooking.VehicleID,
+			"fullbay_service_id":    updatedBooking.FullbayServiceID,
+			"bay_id":                updatedBooking.BayID,
+			"technician_ids":        updatedBooking.TechnicianIDs,
+			"company_id":            updatedBooking.CompanyID,
+			"start":                 updatedBooking.Start,
+			"end":                   updatedBooking.End,
+			"status":                updatedBooking.Status,
+			"actual_start":          updatedBooking.ActualStart,
+			"job_type":              updatedBooking.JobType,
+			"estimated_duration":    updatedBooking.EstimatedDuration,
+			"priority":              updatedBooking.Priority,
+			"required_capabilities": updatedBooking.RequiredCapabilities,
+			"queue_order":           updatedBooking.QueueOrder,
+			"queued_at":             updatedBooking.QueuedAt,
+			"notes":                 updatedBooking.Notes,
+			"updated_at":            updatedBooking.UpdatedAt,
 		},
+		"$inc": bson.M{"version": 1},
 	}
-	if _, err := h.DB.Collection(bookingCollection).UpdateByID(h.ctx(c), id, update); err != nil {
+	res, err := h.DB.Collection(bookingCollection).UpdateOne(h.ctx(c), versionFilter(updatedBooking.ID, existingBooking.Version), update)
+	if err != nil {
 		return fiber.ErrInternalServerError
 	}
+	if res.MatchedCount == 0 {
+		return h.staleVersion(c, bookingCollection, updatedBooking.ID, &models.Booking{})
+	}
+	next.Version = existingBooking.Version + 1
+	updatedBooking.Version = next.Version
 	// audit: capture changes and new technician assignments on update
 	{
-		oldSet := map[primitive.ObjectID]bool{}
-		for _, t := range existingBooking.TechnicianIDs {
-			oldSet[t] = true
-		}
 		var userID primitive.ObjectID
 		if uid := getUserID(c); uid != "" {
 			if u, err := primitive.ObjectIDFromHex(uid); err == nil {
 				userID = u
 			}
 		}
-		// general diffs
-		changes := bson.M{}
-		if existingBooking.VehicleID != updatedBooking.VehicleID {
-			changes["vehicle_id"] = bson.M{"from": existingBooking.VehicleID.Hex(), "to": updatedBooking.VehicleID.Hex()}
-		}
-		if existingBooking.BayID != updatedBooking.BayID {
-			changes["bay_id"] = bson.M{"from": existingBooking.BayID.Hex(), "to": updatedBooking.BayID.Hex()}
-		}
-		if existingBooking.CompanyID != updatedBooking.CompanyID {
-			changes["company_id"] = bson.M{"from": existingBooking.CompanyID.Hex(), "to": updatedBooking.CompanyID.Hex()}
-		}
-		if !existingBooking.Start.Equal(updatedBooking.Start) {
-			changes["start"] = bson.M{"from": existingBooking.Start, "to": updatedBooking.Start}
-		}
-		if (existingBooking.End == nil) != (updatedBooking.End == nil) ||
-			(existingBooking.End != nil && updatedBooking.End != nil && !existingBooking.End.Equal(*updatedBooking.End)) {
-			changes["end"] = bson.M{"from": existingBooking.End, "to": updatedBooking.End}
-		}
-		if existingBooking.Status != updatedBooking.Status {
-			changes["status"] = bson.M{"from": existingBooking.Status, "to": updatedBooking.Status}
-		}
-		if existingBooking.Complaint != updatedBooking.Complaint {
-			changes["complaint"] = bson.M{"from": existingBooking.Complaint, "to": updatedBooking.Complaint}
-		}
-		if existingBooking.Description != updatedBooking.Description {
-			changes["description"] = bson.M{"from": existingBooking.Description, "to": updatedBooking.Description}
-		}
-		// technicians diff
-		newSet := map[primitive.ObjectID]bool{}
-		for _, t := range updatedBooking.TechnicianIDs {
-			newSet[t] = true
-		}
-		var added, removed []string
-		for _, t := range updatedBooking.TechnicianIDs {
-			if !oldSet[t] {
-				added = append(added, t.Hex())
-			}
-		}
-		for t := range oldSet {
-			if !newSet[t] {
-				removed = append(removed, t.Hex())
-			}
-		}
-		if len(added) > 0 {
-			changes["technicians_added"] = added
-		}
-		if len(removed) > 0 {
-			changes["technicians_removed"] = removed
-		}
+		changes := bookingChanges(existingBooking, updatedBooking)
 		if len(changes) > 0 {
+			for k, v := range extraMeta {
+				changes[k] = v
+			}
 			_, _ = h.DB.Collection(auditCollection).InsertOne(h.ctx(c), models.AuditLog{
 				ID:        primitive.NewObjectID(),
 				Action:    "booking.updated",
 				Entity:    "booking",
-				EntityID:  id,
+				EntityID:  updatedBooking.ID,
 				UserID:    userID,
 				Meta:      changes,
 				CreatedAt: h.now(),
@@ -559,6 +696,12 @@ func (h *Handler) UpdateBooking(c *fiber.Ctx) error {
 	}
 
 	pushRealtime(models.RealtimeEvent{Type: "booking.updated", Data: updatedBooking})
+	return nil
+}
+
+// notifyBookingUpdated sends the templated Telegram message for an update,
+// distinguishing reschedules from other edits.
+func (h *Handler) notifyBookingUpdated(c *fiber.Ctx, existingBooking, updatedBooking models.Booking) {
 	// Notify via template if present
 	{
 		var settings models.Settings
@@ -580,7 +723,76 @@ func (h *Handler) UpdateBooking(c *fiber.Ctx) error {
 			_ = h.Telegram.Notify(msg)
 		}
 	}
-	return c.JSON(updatedBooking)
+}
+
+// bookingChanges returns the audit diff between two versions of a booking.
+func bookingChanges(existingBooking, updatedBooking models.Booking) bson.M {
+	oldSet := map[primitive.ObjectID]bool{}
+	for _, t := range existingBooking.TechnicianIDs {
+		oldSet[t] = true
+	}
+	// general diffs
+	changes := bson.M{}
+	if existingBooking.VehicleID != updatedBooking.VehicleID {
+		changes["vehicle_id"] = bson.M{"from": existingBooking.VehicleID.Hex(), "to": updatedBooking.VehicleID.Hex()}
+	}
+	if existingBooking.BayID != updatedBooking.BayID {
+		changes["bay_id"] = bson.M{"from": existingBooking.BayID.Hex(), "to": updatedBooking.BayID.Hex()}
+	}
+	if existingBooking.CompanyID != updatedBooking.CompanyID {
+		changes["company_id"] = bson.M{"from": existingBooking.CompanyID.Hex(), "to": updatedBooking.CompanyID.Hex()}
+	}
+	if !existingBooking.Start.Equal(updatedBooking.Start) {
+		changes["start"] = bson.M{"from": existingBooking.Start, "to": updatedBooking.Start}
+	}
+	if (existingBooking.End == nil) != (updatedBooking.End == nil) ||
+		(existingBooking.End != nil && updatedBooking.End != nil && !existingBooking.End.Equal(*updatedBooking.End)) {
+		changes["end"] = bson.M{"from": existingBooking.End, "to": updatedBooking.End}
+	}
+	if existingBooking.Status != updatedBooking.Status {
+		changes["status"] = bson.M{"from": existingBooking.Status, "to": updatedBooking.Status}
+	}
+	if existingBooking.Complaint != updatedBooking.Complaint {
+		changes["complaint"] = bson.M{"from": existingBooking.Complaint, "to": updatedBooking.Complaint}
+	}
+	if existingBooking.Description != updatedBooking.Description {
+		changes["description"] = bson.M{"from": existingBooking.Description, "to": updatedBooking.Description}
+	}
+	if existingBooking.JobType != updatedBooking.JobType {
+		changes["job_type"] = bson.M{"from": existingBooking.JobType, "to": updatedBooking.JobType}
+	}
+	if existingBooking.Priority != updatedBooking.Priority {
+		changes["priority"] = bson.M{"from": existingBooking.Priority, "to": updatedBooking.Priority}
+	}
+	if existingBooking.EstimatedDuration != updatedBooking.EstimatedDuration {
+		changes["estimated_duration"] = bson.M{"from": existingBooking.EstimatedDuration, "to": updatedBooking.EstimatedDuration}
+	}
+	if strings.Join(existingBooking.RequiredCapabilities, ",") != strings.Join(updatedBooking.RequiredCapabilities, ",") {
+		changes["required_capabilities"] = bson.M{"from": existingBooking.RequiredCapabilities, "to": updatedBooking.RequiredCapabilities}
+	}
+	// technicians diff
+	newSet := map[primitive.ObjectID]bool{}
+	for _, t := range updatedBooking.TechnicianIDs {
+		newSet[t] = true
+	}
+	var added, removed []string
+	for _, t := range updatedBooking.TechnicianIDs {
+		if !oldSet[t] {
+			added = append(added, t.Hex())
+		}
+	}
+	for t := range oldSet {
+		if !newSet[t] {
+			removed = append(removed, t.Hex())
+		}
+	}
+	if len(added) > 0 {
+		changes["technicians_added"] = added
+	}
+	if len(removed) > 0 {
+		changes["technicians_removed"] = removed
+	}
+	return changes
 }
 
 func (h *Handler) CancelBooking(c *fiber.Ctx) error {
@@ -590,20 +802,40 @@ func (h *Handler) CancelBooking(c *fiber.Ctx) error {
 	}
 	// load booking for telegram details
 	var b models.Booking
-	if err := h.DB.Collection(bookingCollection).FindOne(h.ctx(c), bson.M{"_id": id}).Decode(&b); err != nil {
+	if err := h.DB.Collection(bookingCollection).FindOne(h.ctx(c), notDeleted(bson.M{"_id": id})).Decode(&b); err != nil {
 		if err == mongo.ErrNoDocuments {
 			return fiber.ErrNotFound
 		}
 		return fiber.ErrInternalServerError
 	}
+	prevStatus := b.Status
+	if err := checkStatusTransition(c, prevStatus, models.BookingCanceled); err != nil {
+		return err
+	}
+	// Recurring bookings: cancel this and the following occurrences
+	if b.SeriesID != nil && c.Query("scope") == "following" {
+		return h.cancelFollowingOccurrences(c, b)
+	}
 	now := h.now()
-	update := bson.M{"$set": bson.M{"status": models.BookingCanceled, "end": &now, "updated_at": now}}
-	res, err := h.DB.Collection(bookingCollection).UpdateByID(h.ctx(c), id, update)
+	update := bson.M{
+		"$set": bson.M{"status": models.BookingCanceled, "end": &now, "updated_at": now},
+		"$inc": bson.M{"version": 1},
+	}
+	res, err := h.DB.Collection(bookingCollection).UpdateOne(h.ctx(c), bson.M{"_id": id, "status": prevStatus}, update)
 	if err != nil {
 		return fiber.ErrInternalServerError
 	}
 	if res.MatchedCount == 0 {
-		return fiber.ErrNotFound
+		// another request changed the status since it was loaded
+		return h.conflictResponse(c, h.staleVersion(c, bookingCollection, id, &models.Booking{}))
+	}
+	h.stopBookingClocks(c, id)
+	// a single canceled occurrence becomes an exception of its series
+	if b.SeriesID != nil {
+		_, _ = h.DB.Collection(bookingSeriesCollection).UpdateByID(h.ctx(c), *b.SeriesID, bson.M{
+			"$addToSet": bson.M{"rule.exceptions": b.Start},
+			"$set":      bson.M{"updated_at": now},
+		})
 	}
 	pushRealtime(models.RealtimeEvent{Type: "booking.canceled", Data: id.Hex()})
 	b.Status = models.BookingCanceled
@@ -624,7 +856,7 @@ func (h *Handler) CancelBooking(c *fiber.Ctx) error {
 			Entity:    "booking",
 			EntityID:  id,
 			UserID:    actor,
-			Meta:      bson.M{},
+			Meta:      bson.M{"from": prevStatus, "to": models.BookingCanceled},
 			CreatedAt: h.now(),
 		})
 	}
@@ -638,21 +870,30 @@ func (h *Handler) CloseBooking(c *fiber.Ctx) error {
 	}
 	// load booking for telegram details
 	var b models.Booking
-	if err := h.DB.Collection(bookingCollection).FindOne(h.ctx(c), bson.M{"_id": id}).Decode(&b); err != nil {
+	if err := h.DB.Collection(bookingCollection).FindOne(h.ctx(c), notDeleted(bson.M{"_id": id})).Decode(&b); err != nil {
 		if err == mongo.ErrNoDocuments {
 			return fiber.ErrNotFound
 		}
 		return fiber.ErrInternalServerError
 	}
+	prevStatus := b.Status
+	if err := checkStatusTransition(c, prevStatus, models.BookingClosed); err != nil {
+		return err
+	}
 	now := h.now()
-	update := bson.M{"$set": bson.M{"status": models.BookingClosed, "end": &now, "updated_at": now}}
-	res, err := h.DB.Collection(bookingCollection).UpdateByID(h.ctx(c), id, update)
+	update := bson.M{
+		"$set": bson.M{"status": models.BookingClosed, "end": &now, "updated_at": now},
+		"$inc": bson.M{"version": 1},
+	}
+	res, err := h.DB.Collection(bookingCollection).UpdateOne(h.ctx(c), bson.M{"_id": id, "status": prevStatus}, update)
 	if err != nil {
 		return fiber.ErrInternalServerError
 	}
 	if res.MatchedCount == 0 {
-		return fiber.ErrNotFound
+		// another request changed the status since it was loaded
+		return h.conflictResponse(c, h.staleVersion(c, bookingCollection, id, &models.Booking{}))
 	}
+	h.stopBookingClocks(c, id)
 	pushRealtime(models.RealtimeEvent{Type: "booking.closed", Data: id.Hex()})
 	b.Status = models.BookingClosed
 	b.End = &now
@@ -672,24 +913,180 @@ func (h *Handler) CloseBooking(c *fiber.Ctx) error {
 			Entity:    "booking",
 			EntityID:  id,
 			UserID:    actor,
-			Meta:      bson.M{},
+			Meta:      bson.M{"from": prevStatus, "to": models.BookingClosed},
 			CreatedAt: h.now(),
 		})
 	}
 	return c.SendStatus(fiber.StatusNoContent)
 }
 
-func (h *Handler) DeleteBooking(c *fiber.Ctx) error {
+// StartBooking moves an open booking to in_progress and stamps the actual start
+// time. The body may carry the odometer and engine hours read at check-in.
+func (h *Handler) StartBooking(c *fiber.Ctx) error {
 	id, err := asObjectID(c.Params("id"))
 	if err != nil {
 		return fiber.ErrBadRequest
 	}
-	res, err := h.DB.Collection(bookingCollection).DeleteOne(h.ctx(c), bson.M{"_id": id})
+	var reading meterReadingRequest
+	if len(c.Body()) > 0 {
+		if err := c.BodyParser(&reading); err != nil {
+			return fiber.NewError(fiber.StatusBadRequest, "invalid body")
+		}
+	}
+	var b models.Booking
+	if err := h.DB.Collection(bookingCollection).FindOne(h.ctx(c), notDeleted(bson.M{"_id": id})).Decode(&b); err != nil {
+		if err == mongo.ErrNoDocuments {
+			return fiber.ErrNotFound
+		}
+		return fiber.ErrInternalServerError
+	}
+	prevStatus := b.Status
+	if prevStatus == models.BookingInProgress {
+		return fiber.NewError(fiber.StatusUnprocessableEntity, "booking is already in progress")
+	}
+	if err := checkStatusTransition(c, prevStatus, models.BookingInProgress); err != nil {
+		return err
+	}
+	var readingOverride bool
+	if !reading.empty() {
+		if readingOverride, err = h.checkMeterReading(c, b.VehicleID, reading); err != nil {
+			return meterReadingError(c, err)
+		}
+	}
+	now := h.now()
+	set := bson.M{"status": models.BookingInProgress, "actual_start": &now, "updated_at": now}
+	if reading.Odometer > 0 {
+		set["odometer"] = reading.Odometer
+		b.Odometer = reading.Odometer
+	}
+	if reading.EngineHours > 0 {
+		set["engine_hours"] = reading.EngineHours
+		b.EngineHours = reading.EngineHours
+	}
+	update := bson.M{
+		"$set": set,
+		"$inc": bson.M{"version": 1},
+	}
+	// store and apply the reading before the status change; it is dropped
+	// again when the booking cannot be started
+	var stored *models.MeterReading
+	var prevVehicle models.Vehicle
+	if !reading.empty() {
+		r, err := h.recordMeterReading(c, b.VehicleID, &b.ID, reading, "booking.started", readingOverride)
+		if err != nil {
+			return err
+		}
+		if prevVehicle, err = h.applyMeterReading(c, r); err != nil {
+			h.dropMeterReading(c, r, nil)
+			return meterReadingError(c, err)
+		}
+		stored = &r
+	}
+	res, err := h.DB.Collection(bookingCollection).UpdateOne(h.ctx(c), bson.M{"_id": id, "status": prevStatus}, update)
+	if err != nil || res.MatchedCount == 0 {
+		if stored != nil {
+			h.dropMeterReading(c, *stored, &prevVehicle)
+		}
+		if err != nil {
+			return fiber.ErrInternalServerError
+		}
+		// another request changed the status since it was loaded
+		return h.conflictResponse(c, h.staleVersion(c, bookingCollection, id, &models.Booking{}))
+	}
+	b.Status = models.BookingInProgress
+	b.ActualStart = &now
+	b.UpdatedAt = now
+	b.Version++
+	if stored != nil {
+		h.auditMeterOverride(c, *stored)
+	}
+	pushRealtime(models.RealtimeEvent{Type: "booking.started", Data: b})
+	_, _ = h.DB.Collection(auditCollection).InsertOne(h.ctx(c), models.AuditLog{
+		ID:        primitive.NewObjectID(),
+		Action:    "booking.started",
+		Entity:    "booking",
+		EntityID:  id,
+		UserID:    actorID(c),
+		Meta:      bson.M{"from": prevStatus, "to": models.BookingInProgress, "actual_start": now},
+		CreatedAt: now,
+	})
+	return c.JSON(b)
+}
+
+// ReopenBooking puts a closed or canceled booking back to open (admin only).
+func (h *Handler) ReopenBooking(c *fiber.Ctx) error {
+	id, err := asObjectID(c.Params("id"))
+	if err != nil {
+		return fiber.ErrBadRequest
+	}
+	var b models.Booking
+	if err := h.DB.Collection(bookingCollection).FindOne(h.ctx(c), notDeleted(bson.M{"_id": id})).Decode(&b); err != nil {
+		if err == mongo.ErrNoDocuments {
+			return fiber.ErrNotFound
+		}
+		return fiber.ErrInternalServerError
+	}
+	prevStatus := b.Status
+	if prevStatus != models.BookingClosed && prevStatus != models.BookingCanceled {
+		return fiber.NewError(fiber.StatusUnprocessableEntity, "only closed or canceled bookings can be reopened")
+	}
+	if err := checkStatusTransition(c, prevStatus, models.BookingOpen); err != nil {
+		return err
+	}
+	b.Status = models.BookingOpen
+	b.UpdatedAt = h.now()
+	// the reopened booking must still fit into its bay
+	unlock, err := h.lockSchedule(c, b)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	if err := h.validateBookingConflicts(c, b, nil); err != nil {
+		return h.conflictResponse(c, err)
+	}
+	update := bson.M{
+		"$set": bson.M{"status": models.BookingOpen, "updated_at": b.UpdatedAt},
+		"$inc": bson.M{"version": 1},
+	}
+	res, err := h.DB.Collection(bookingCollection).UpdateOne(h.ctx(c), bson.M{"_id": id, "status": prevStatus}, update)
 	if err != nil {
 		return fiber.ErrInternalServerError
 	}
-	if res.DeletedCount == 0 {
-		return fiber.ErrNotFound
+	// another request changed the status since it was loaded
+	if res.MatchedCount == 0 {
+		return h.conflictResponse(c, h.staleVersion(c, bookingCollection, id, &models.Booking{}))
+	}
+	b.Version++
+	pushRealtime(models.RealtimeEvent{Type: "booking.updated", Data: b})
+	_, _ = h.DB.Collection(auditCollection).InsertOne(h.ctx(c), models.AuditLog{
+		ID:        primitive.NewObjectID(),
+		Action:    "booking.reopened",
+		Entity:    "booking",
+		EntityID:  id,
+		UserID:    actorID(c),
+		Meta:      bson.M{"from": prevStatus, "to": models.BookingOpen},
+		CreatedAt: b.UpdatedAt,
+	})
+	return c.JSON(b)
+}
+
+// checkStatusTransition maps a rejected status change to a 422 response.
+func checkStatusTransition(c *fiber.Ctx, from, to models.BookingStatus) error {
+	if err := services.ValidateStatusTransition(from, to, getRole(c)); err != nil {
+		return fiber.NewError(fiber.StatusUnprocessableEntity, err.Error())
+	}
+	return nil
+}
+
+// DeleteBooking moves the booking into the trash; it stays readable by id
+// and can be restored until it is purged.
+func (h *Handler) DeleteBooking(c *fiber.Ctx) error {
+	id, err := asObjectID(c.Params("id"))
+	if err != nil {
+		return fiber.ErrBadRequest
+	}
+	if err := h.softDelete(c, trashKinds["bookings"], id, nil); err != nil {
+		return err
 	}
 	pushRealtime(models.RealtimeEvent{Type: "booking.deleted", Data: id.Hex()})
 	return c.SendStatus(fiber.StatusNoContent)
@@ -792,6 +1189,14 @@ func (h *Handler) buildTelegramData(c *fiber.Ctx, b models.Booking) map[string]s
 		}
 		data["technician_names"] = strings.Join(names, ", ")
 	}
+	// Job lines
+	if len(b.Jobs) > 0 {
+		est, act := services.JobHours(b.Jobs)
+		data["jobs"] = services.FormatJobLines(b.Jobs, "\n")
+		data["jobs_count"] = strconv.Itoa(len(b.Jobs))
+		data["jobs_estimated_hours"] = strconv.FormatFloat(est, 'f', 1, 64)
+		data["jobs_actual_hours"] = strconv.FormatFloat(act, 'f', 1, 64)
+	}
 	return data
 }
 
@@ -871,6 +1276,9 @@ func (h *Handler) renderTelegramFallback(kind string, b models.Booking, data map
 	} else {
 		sb.WriteString("\n")
 	}
+	if jobs := data["jobs"]; jobs != "" {
+		fmt.Fprintf(&sb, "<b>Jobs:</b>\n%s\n\n", jobs)
+	}
 	fmt.Fprintf(&sb, "<b>Start:</b> %s\n", start)
 	if end != "" {
 		fmt.Fprintf(&sb, "<b>End:</b> %s\n", end)
@@ -878,10 +1286,12 @@ func (h *Handler) renderTelegramFallback(kind string, b models.Booking, data map
 	return sb.String()
 }
 
-func (h *Handler) findConflictingBookings(c *fiber.Ctx, bayID primitive.ObjectID) ([]models.Booking, error) {
+// findConflictingBookings returns open/in_progress bookings in the given bays.
+func (h *Handler) findConflictingBookings(c *fiber.Ctx, bayIDs ...primitive.ObjectID) ([]models.Booking, error) {
 	filter := bson.M{
-		"status": bson.M{"$in": []models.BookingStatus{models.BookingOpen, models.BookingInProgress}},
-		"bay_id": bayID,
+		"status":     bson.M{"$in": []models.BookingStatus{models.BookingOpen, models.BookingInProgress}},
+		"bay_id":     bson.M{"$in": bayIDs},
+		"deleted_at": nil,
 	}
 	cur, err := h.DB.Collection(bookingCollection).Find(h.ctx(c), filter)
 	if err != nil {
@@ -896,20 +1306,152 @@ func (h *Handler) findConflictingBookings(c *fiber.Ctx, bayID primitive.ObjectID
 	return items, nil
 }
 
+// findTechnicianBookings returns open/in_progress bookings that have any of the
+// given technicians assigned. Bookings in excludeBayID (the WaitingList bay)
+// are skipped because they are not scheduled yet.
+func (h *Handler) findTechnicianBookings(c *fiber.Ctx, technicianIDs []primitive.ObjectID, excludeBayID primitive.ObjectID) ([]models.Booking, error) {
+	if len(technicianIDs) == 0 {
+		return nil, nil
+	}
+	filter := bson.M{
+		"status":         bson.M{"$in": []models.BookingStatus{models.BookingOpen, models.BookingInProgress}},
+		"technician_ids": bson.M{"$in": technicianIDs},
+		"deleted_at":     nil,
+	}
+	if excludeBayID != primitive.NilObjectID {
+		filter["bay_id"] = bson.M{"$ne": excludeBayID}
+	}
+	cur, err := h.DB.Collection(bookingCollection).Find(h.ctx(c), filter)
+	if err != nil {
+		return nil, err
+	}
+	defer cur.Close(h.ctx(c))
+
+	var items []models.Booking
+	if err := cur.All(h.ctx(c), &items); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+// validateBookingConflicts runs bay and technician conflict checks for b.
+// A bay holds up to its capacity of overlapping bookings at once.
+// Bookings in the special "WaitingList" bay are not scheduled, so they are
+// neither validated nor counted against technicians on other bookings.
+// planned holds bookings that are about to be saved together with b (series
+// occurrences); stored bookings listed in ignore (besides b itself) are
+// skipped, which is used when several bookings of a series move together.
+func (h *Handler) validateBookingConflicts(c *fiber.Ctx, b models.Booking, planned []models.Booking, ignore ...primitive.ObjectID) error {
+	wlID, hasWL := h.findWaitingListBayID(c)
+	if hasWL && wlID == b.BayID {
+		return nil
+	}
+	existing, err := h.findConflictingBookings(c, b.BayID)
+	if err != nil {
+		return fiber.ErrInternalServerError
+	}
+	var bay models.Bay
+	_ = h.DB.Collection(bayCollection).FindOne(h.ctx(c), bson.M{"_id": b.BayID}).Decode(&bay)
+	if err := services.CheckBayCompatibility(bay, h.requiredCapabilities(c, b), h.vehicleLengthFt(c, b.VehicleID)); err != nil {
+		return fiber.NewError(fiber.StatusUnprocessableEntity, err.Error())
+	}
+	blackouts, err := h.findBayBlackouts(c, b.Start, services.EffectiveEnd(b), b.BayID)
+	if err != nil {
+		return fiber.ErrInternalServerError
+	}
+	techBookings, err := h.findTechnicianBookings(c, b.TechnicianIDs, wlID)
+	if err != nil {
+		return fiber.ErrInternalServerError
+	}
+	state := services.ScheduleState{
+		BayBookings:        existing,
+		TechnicianBookings: techBookings,
+		Blackouts:          blackouts,
+		Capacity:           services.BayCapacity(bay),
+	}
+	err = services.ValidateSchedule(b, state, planned, h.now(), ignore...)
+	var techErr *services.TechnicianConflictError
+	if err != nil && !errors.As(err, &techErr) {
+		return fiber.NewError(fiber.StatusConflict, err.Error())
+	}
+	return err
+}
+
+// requiredCapabilities returns the bay capabilities b needs: its own plus
+// those of its job type.
+func (h *Handler) requiredCapabilities(c *fiber.Ctx, b models.Booking) []string {
+	required := b.RequiredCapabilities
+	if jt, ok := h.findJobType(c, b.JobType); ok {
+		required = append(append([]string{}, required...), jt.Capabilities...)
+	}
+	return services.NormalizeCapabilities(required)
+}
+
+// vehicleLengthFt returns the recorded length of a vehicle, 0 when unknown.
+func (h *Handler) vehicleLengthFt(c *fiber.Ctx, id primitive.ObjectID) int {
+	if id.IsZero() {
+		return 0
+	}
+	var v models.Vehicle
+	if err := h.DB.Collection(vehicleCollection).FindOne(h.ctx(c), bson.M{"_id": id}).Decode(&v); err != nil {
+		return 0
+	}
+	return v.LengthFt
+}
+
+// conflictResponse renders technician conflicts as a 409 with the list of
+// colliding technicians and booking numbers, and stale versions as a 409 with
+// the current document; other errors pass through.
+func (h *Handler) conflictResponse(c *fiber.Ctx, err error) error {
+	var staleErr *staleVersionError
+	if errors.As(err, &staleErr) {
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+			"error":   staleErr.Error(),
+			"current": staleErr.Current,
+		})
+	}
+	var techErr *services.TechnicianConflictError
+	if !errors.As(err, &techErr) {
+		return err
+	}
+	ids := make([]primitive.ObjectID, 0, len(techErr.Conflicts))
+	for _, cf := range techErr.Conflicts {
+		ids = append(ids, cf.TechnicianID)
+	}
+	names := map[primitive.ObjectID]string{}
+	if cur, err := h.DB.Collection(technicianCollection).Find(h.ctx(c), bson.M{"_id": bson.M{"$in": ids}}); err == nil {
+		defer cur.Close(h.ctx(c))
+		for cur.Next(h.ctx(c)) {
+			var t models.Technician
+			if err := cur.Decode(&t); err == nil {
+				names[t.ID] = t.Name
+			}
+		}
+	}
+	for i := range techErr.Conflicts {
+		techErr.Conflicts[i].TechnicianName = names[techErr.Conflicts[i].TechnicianID]
+	}
+	return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+		"error":     techErr.Error(),
+		"conflicts": techErr.Conflicts,
+	})
+}
+
 func (h *Handler) DashboardSummary(c *fiber.Ctx) error {
 	now := h.now()
 	startOfDay := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, h.TZ)
 	endOfDay := startOfDay.Add(24 * time.Hour)
 
-	openFilter := bson.M{"status": bson.M{"$in": []models.BookingStatus{models.BookingOpen, models.BookingInProgress}}}
+	openFilter := notDeleted(bson.M{"status": bson.M{"$in": []models.BookingStatus{models.BookingOpen, models.BookingInProgress}}})
 	openCount, _ := h.DB.Collection(bookingCollection).CountDocuments(h.ctx(c), openFilter)
 
 	todayFilter := bson.M{
-		"start": bson.M{"$gte": startOfDay, "$lt": endOfDay},
+		"start":      bson.M{"$gte": startOfDay, "$lt": endOfDay},
+		"deleted_at": nil,
 	}
 	todayCount, _ := h.DB.Collection(bookingCollection).CountDocuments(h.ctx(c), todayFilter)
 
-	baysCount, _ := h.DB.Collection(bayCollection).CountDocuments(h.ctx(c), bson.D{})
+	baysCount, _ := h.DB.Collection(bayCollection).CountDocuments(h.ctx(c), notDeleted(bson.M{}))
 
 	// Top aggregates (all-time)
 	type kv struct {
@@ -919,6 +1461,7 @@ func (h *Handler) DashboardSummary(c *fiber.Ctx) error {
 	// Top technicians
 	var topTechAgg []kv
 	if cur, err := h.DB.Collection(bookingCollection).Aggregate(h.ctx(c), mongo.Pipeline{
+		{{Key: "$match", Value: notDeleted(bson.M{})}},
 		{{Key: "$unwind", Value: "$technician_ids"}},
 		{{Key: "$group", Value: bson.M{"_id": "$technician_ids", "count": bson.M{"$sum": 1}}}},
 		{{Key: "$sort", Value: bson.M{"count": -1}}},
@@ -948,6 +1491,7 @@ func (h *Handler) DashboardSummary(c *fiber.Ctx) error {
 	// Top units
 	var topUnitsAgg []kv
 	if cur, err := h.DB.Collection(bookingCollection).Aggregate(h.ctx(c), mongo.Pipeline{
+		{{Key: "$match", Value: notDeleted(bson.M{})}},
 		{{Key: "$group", Value: bson.M{"_id": "$vehicle_id", "count": bson.M{"$sum": 1}}}},
 		{{Key: "$sort", Value: bson.M{"count": -1}}},
 		{{Key: "$limit", Value: 5}},
@@ -959,6 +1503,24 @@ func (h *Handler) DashboardSummary(c *fiber.Ctx) error {
 	for _, u := range topUnitsAgg {
 		unitIDs = append(unitIDs, u.ID)
 	}
+	// Comebacks: units back within the window with a similar complaint
+	comebacks, _ := h.recentComebacks(c, services.DefaultComebackDays*24*time.Hour)
+	var comebackAgg []kv
+	comebackIdx := map[primitive.ObjectID]int{}
+	for _, cb := range comebacks {
+		i, ok := comebackIdx[cb.VehicleID]
+		if !ok {
+			i = len(comebackAgg)
+			comebackIdx[cb.VehicleID] = i
+			comebackAgg = append(comebackAgg, kv{ID: cb.VehicleID})
+			unitIDs = append(unitIDs, cb.VehicleID)
+		}
+		comebackAgg[i].Count++
+	}
+	sort.SliceStable(comebackAgg, func(i, j int) bool { return comebackAgg[i].Count > comebackAgg[j].Count })
+	if len(comebackAgg) > 5 {
+		comebackAgg = comebackAgg[:5]
+	}
 	unitLabels := map[primitive.ObjectID]string{}
 	if len(unitIDs) > 0 {
 		cur, _ := h.DB.Collection(vehicleCollection).Find(h.ctx(c), bson.M{"_id": bson.M{"$in": unitIDs}})
@@ -977,10 +1539,14 @@ func (h *Handler) DashboardSummary(c *fiber.Ctx) error {
 	for _, u := range topUnitsAgg {
 		topUnits = append(topUnits, fiber.Map{"id": u.ID.Hex(), "name": unitLabels[u.ID], "count": u.Count})
 	}
+	comebackUnits := make([]fiber.Map, 0, len(comebackAgg))
+	for _, u := range comebackAgg {
+		comebackUnits = append(comebackUnits, fiber.Map{"id": u.ID.Hex(), "name": unitLabels[u.ID], "count": u.Count})
+	}
 	// Top companies
 	var topCompaniesAgg []kv
 	if cur, err := h.DB.Collection(bookingCollection).Aggregate(h.ctx(c), mongo.Pipeline{
-		{{Key: "$match", Value: bson.M{"company_id": bson.M{"$ne": primitive.NilObjectID}}}},
+		{{Key: "$match", Value: notDeleted(bson.M{"company_id": bson.M{"$ne": primitive.NilObjectID}})}},
 		{{Key: "$group", Value: bson.M{"_id": "$company_id", "count": bson.M{"$sum": 1}}}},
 		{{Key: "$sort", Value: bson.M{"count": -1}}},
 		{{Key: "$limit", Value: 5}},
@@ -1009,6 +1575,7 @@ func (h *Handler) DashboardSummary(c *fiber.Ctx) error {
 	// Top bays
 	var topBaysAgg []kv
 	if cur, err := h.DB.Collection(bookingCollection).Aggregate(h.ctx(c), mongo.Pipeline{
+		{{Key: "$match", Value: notDeleted(bson.M{})}},
 		{{Key: "$group", Value: bson.M{"_id": "$bay_id", "count": bson.M{"$sum": 1}}}},
 		{{Key: "$sort", Value: bson.M{"count": -1}}},
 		{{Key: "$limit", Value: 5}},
@@ -1039,17 +1606,22 @@ func (h *Handler) DashboardSummary(c *fiber.Ctx) error {
 		"open_bookings":  openCount,
 		"today_bookings": todayCount,
 		"bays":           baysCount,
+		"comebacks":      len(comebacks),
+		"comeback_days":  services.DefaultComebackDays,
 		"timestamp":      now,
 		"top": fiber.Map{
-			"technicians": topTechnicians,
-			"units":       topUnits,
-			"companies":   topCompanies,
-			"bays":        topBays,
+			"technicians":    topTechnicians,
+			"units":          topUnits,
+			"comeback_units": comebackUnits,
+			"companies":      topCompanies,
+			"bays":           topBays,
 		},
 	})
 }
 
 // Agenda возвращает события в диапазоне дат.
+// Recurring series are stored as one booking per occurrence, so every
+// occurrence inside from/to is returned with its series_id and occurrence.
 func (h *Handler) Agenda(c *fiber.Ctx) error {
 	from := c.Query("from")
 	to := c.Query("to")
@@ -1073,8 +1645,9 @@ func (h *Handler) Agenda(c *fiber.Ctx) error {
 	}
 
 	filter := bson.M{
-		"status": bson.M{"$in": []models.BookingStatus{models.BookingOpen, models.BookingInProgress}},
-		"start":  bson.M{"$lt": toTime},
+		"status":     bson.M{"$in": []models.BookingStatus{models.BookingOpen, models.BookingInProgress}},
+		"start":      bson.M{"$lt": toTime},
+		"deleted_at": nil,
 		"$or": []bson.M{
 			{"end": bson.M{"$gte": fromTime}},
 			{"end": bson.M{"$exists": false}},
@@ -1094,6 +1667,7 @@ func (h *Handler) Agenda(c *fiber.Ctx) error {
 	if err := cur.All(h.ctx(c), &items); err != nil {
 		return fiber.ErrInternalServerError
 	}
+	h.flagOverdue(items)
 	return c.JSON(items)
 }
 
@@ -1125,6 +1699,7 @@ func (h *Handler) ReadyBookings(c *fiber.Ctx) error {
 			"$gte": fromTime,
 			"$lt":  toTime,
 		},
+		"deleted_at": nil,
 	}
 	cur, err := h.DB.Collection(bookingCollection).Find(h.ctx(c), filter, options.Find().SetSort(bson.D{{Key: "end", Value: -1}}))
 	if err != nil {
@@ -1138,7 +1713,9 @@ func (h *Handler) ReadyBookings(c *fiber.Ctx) error {
 	return c.JSON(items)
 }
 
-// WaitingListBookings returns bookings assigned to the special WaitingList bay.
+// WaitingListBookings returns bookings assigned to the special WaitingList bay
+// in queue order (priority, manual order, then time queued) with their queue
+// position and time in queue.
 // These bookings are not shown on the main calendar and are listed separately.
 func (h *Handler) WaitingListBookings(c *fiber.Ctx) error {
 	wlID, ok := h.findWaitingListBayID(c)
@@ -1147,8 +1724,9 @@ func (h *Handler) WaitingListBookings(c *fiber.Ctx) error {
 		return c.JSON([]models.Booking{})
 	}
 	filter := bson.M{
-		"bay_id": wlID,
-		"status": bson.M{"$in": []models.BookingStatus{models.BookingOpen, models.BookingInProgress}},
+		"bay_id":     wlID,
+		"status":     bson.M{"$in": []models.BookingStatus{models.BookingOpen, models.BookingInProgress}},
+		"deleted_at": nil,
 	}
 	// Keep optional time filters if provided, but do not require them.
 	if from := c.Query("from"); from != "" {
@@ -1166,7 +1744,7 @@ func (h *Handler) WaitingListBookings(c *fiber.Ctx) error {
 			}
 		}
 	}
-	cur, err := h.DB.Collection(bookingCollection).Find(h.ctx(c), filter, options.Find().SetSort(bson.D{{Key: "created_at", Value: -1}}))
+	cur, err := h.DB.Collection(bookingCollection).Find(h.ctx(c), filter)
 	if err != nil {
 		return fiber.ErrInternalServerError
 	}
@@ -1175,12 +1753,14 @@ func (h *Handler) WaitingListBookings(c *fiber.Ctx) error {
 	if err := cur.All(h.ctx(c), &items); err != nil {
 		return fiber.ErrInternalServerError
 	}
+	services.SortWaitingQueue(items, h.now())
+	h.flagOverdue(items)
 	return c.JSON(items)
 }
 
 func (h *Handler) loadBay(c *fiber.Ctx, bayID primitive.ObjectID) (models.Bay, error) {
 	var bay models.Bay
-	err := h.DB.Collection(bayCollection).FindOne(h.ctx(c), bson.M{"_id": bayID}).Decode(&bay)
+	err := h.DB.Collection(bayCollection).FindOne(h.ctx(c), notDeleted(bson.M{"_id": bayID})).Decode(&bay)
 	if err != nil {
 		if err == mongo.ErrNoDocuments {
 			return bay, fiber.NewError(fiber.StatusBadRequest, "bay not found")
@@ -1202,3 +1782,38 @@ func (h *Handler) findWaitingListBayID(c *fiber.Ctx) (primitive.ObjectID, bool)
 	}
 	return doc.ID, true
 }
+
+// resolveEstimatedDuration returns the estimate in minutes for b: the explicit
+// value, the span between start and end, the job type default from settings,
+// or the bay default, in that order. Zero means the service-wide default.
+func (h *Handler) resolveEstimatedDuration(c *fiber.Ctx, b models.Booking, explicit int) int {
+	if explicit > 0 {
+		return explicit
+	}
+	if b.End != nil && b.End.After(b.Start) {
+		return int(b.End.Sub(b.Start) / time.Minute)
+	}
+	if jt, ok := h.findJobType(c, b.JobType); ok && jt.EstimatedDuration > 0 {
+		return jt.EstimatedDuration
+	}
+	if bay, err := h.loadBay(c, b.BayID); err == nil && bay.DefaultDuration > 0 {
+		return bay.DefaultDuration
+	}
+	return 0
+}
+
+// sameEnd reports whether two optional end times are equal.
+func sameEnd(a, b *time.Time) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return a.Equal(*b)
+}
+
+// flagOverdue marks active bookings that run past their estimate.
+func (h *Handler) flagOverdue(items []models.Booking) {
+	now := h.now()
+	for i := range items {
+		items[i].Overdue = services.IsOverdue(items[i], now)
+	}
+}
@@ -0,0 +1,219 @@
+package handlers
+
+import (
+	"errors"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/tss-booking-system/backend/models"
+	"github.com/tss-booking-system/backend/services"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const bayBlackoutCollection = "bay_blackouts"
+
+type bayBlackoutRequest struct {
+	Start  time.Time `json:"start"`
+	End    time.Time `json:"end"`
+	Reason string    `json:"reason"`
+}
+
+// loadShopCalendar returns the stored shop calendar; a missing document means
+// no working time rules.
+func (h *Handler) loadShopCalendar(c *fiber.Ctx) models.ShopCalendar {
+	var cal models.ShopCalendar
+	if err := h.DB.Collection(settingsCollection).FindOne(h.ctx(c), bson.M{"_id": "calendar"}).Decode(&cal); err != nil {
+		return models.ShopCalendar{ID: "calendar", Mode: models.CalendarOff}
+	}
+	return cal
+}
+
+// GetShopCalendar returns weekly opening hours, holidays and the enforcement mode.
+func (h *Handler) GetShopCalendar(c *fiber.Ctx) error {
+	cal := h.loadShopCalendar(c)
+	if cal.WeeklyHours == nil {
+		cal.WeeklyHours = []models.OpeningHours{}
+	}
+	if cal.Holidays == nil {
+		cal.Holidays = []models.Holiday{}
+	}
+	return c.JSON(cal)
+}
+
+// SaveShopCalendar replaces the shop calendar.
+func (h *Handler) SaveShopCalendar(c *fiber.Ctx) error {
+	var req models.ShopCalendar
+	if err := c.BodyParser(&req); err != nil {
+		return fiber.ErrBadRequest
+	}
+	if err := services.ValidateShopCalendar(req); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, err.Error())
+	}
+	if req.Mode == "" {
+		req.Mode = models.CalendarWarn
+	}
+	update := bson.M{
+		"$set": bson.M{
+			"mode":         req.Mode,
+			"weekly_hours": req.WeeklyHours,
+			"holidays":     req.Holidays,
+			"updated_at":   h.now(),
+		},
+	}
+	if _, err := h.DB.Collection(settingsCollection).UpdateByID(h.ctx(c), "calendar", update, optionsForUpsert()); err != nil {
+		return fiber.ErrInternalServerError
+	}
+	return c.JSON(h.loadShopCalendar(c))
+}
+
+// findBayBlackouts returns blackouts overlapping [from, to) for the given bays
+// (all bays when none are given).
+func (h *Handler) findBayBlackouts(c *fiber.Ctx, from, to time.Time, bayIDs ...primitive.ObjectID) ([]models.BayBlackout, error) {
+	filter := bson.M{
+		"start": bson.M{"$lt": to},
+		"end":   bson.M{"$gt": from},
+	}
+	if len(bayIDs) > 0 {
+		filter["bay_id"] = bson.M{"$in": bayIDs}
+	}
+	cur, err := h.DB.Collection(bayBlackoutCollection).Find(h.ctx(c), filter, options.Find().SetSort(bson.D{{Key: "start", Value: 1}}))
+	if err != nil {
+		return nil, err
+	}
+	defer cur.Close(h.ctx(c))
+	items := make([]models.BayBlackout, 0)
+	if err := cur.All(h.ctx(c), &items); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+// checkWorkingTime applies the shop calendar to b. In reject mode bookings
+// outside working time fail with 422; in warn mode a warning is attached.
+// The WaitingList bay is not scheduled and therefore never checked.
+func (h *Handler) checkWorkingTime(c *fiber.Ctx, b *models.Booking) error {
+	if wlID, ok := h.findWaitingListBayID(c); ok && wlID == b.BayID {
+		return nil
+	}
+	cal := h.loadShopCalendar(c)
+	err := services.CheckWorkingTime(cal, *b, h.TZ)
+	if err == nil {
+		return nil
+	}
+	if cal.Mode == models.CalendarReject {
+		return fiber.NewError(fiber.StatusUnprocessableEntity, err.Error())
+	}
+	b.Warnings = append(b.Warnings, err.Error())
+	return nil
+}
+
+// validateBookingSchedule runs conflict validation, the working time check
+// and the technician shift/time off check.
+func (h *Handler) validateBookingSchedule(c *fiber.Ctx, b *models.Booking, planned []models.Booking, ignore ...primitive.ObjectID) error {
+	if err := h.validateBookingConflicts(c, *b, planned, ignore...); err != nil {
+		return err
+	}
+	if err := h.checkWorkingTime(c, b); err != nil {
+		return err
+	}
+	return h.checkTechnicianSchedules(c, b)
+}
+
+// ListBayBlackouts returns upcoming (or from/to bounded) blackouts of a bay.
+func (h *Handler) ListBayBlackouts(c *fiber.Ctx) error {
+	id, err := asObjectID(c.Params("id"))
+	if err != nil {
+		return fiber.ErrBadRequest
+	}
+	from := h.now()
+	to := from.AddDate(1, 0, 0)
+	if v := c.Query("from"); v != "" {
+		if from, err = parseQueryTime(v); err != nil {
+			return fiber.NewError(fiber.StatusBadRequest, "invalid from")
+		}
+	}
+	if v := c.Query("to"); v != "" {
+		if to, err = parseQueryTime(v); err != nil {
+			return fiber.NewError(fiber.StatusBadRequest, "invalid to")
+		}
+	}
+	items, err := h.findBayBlackouts(c, from, to, id)
+	if err != nil {
+		return fiber.ErrInternalServerError
+	}
+	return c.JSON(items)
+}
+
+// CreateBayBlackout blocks a bay for a time range (e.g. rack calibration).
+func (h *Handler) CreateBayBlackout(c *fiber.Ctx) error {
+	bayID, err := asObjectID(c.Params("id"))
+	if err != nil {
+		return fiber.ErrBadRequest
+	}
+	var req bayBlackoutRequest
+	if err := c.BodyParser(&req); err != nil {
+		return fiber.ErrBadRequest
+	}
+	if !req.End.After(req.Start) {
+		return fiber.NewError(fiber.StatusBadRequest, "end must be after start")
+	}
+	if _, err := h.loadBay(c, bayID); err != nil {
+		return err
+	}
+	now := h.now()
+	item := models.BayBlackout{
+		ID:        primitive.NewObjectID(),
+		BayID:     bayID,
+		Start:     req.Start.In(h.TZ),
+		End:       req.End.In(h.TZ),
+		Reason:    req.Reason,
+		CreatedBy: actorID(c),
+		CreatedAt: now,
+	}
+	if _, err := h.DB.Collection(bayBlackoutCollection).InsertOne(h.ctx(c), item); err != nil {
+		return fiber.ErrInternalServerError
+	}
+	_, _ = h.DB.Collection(auditCollection).InsertOne(h.ctx(c), models.AuditLog{
+		ID:        primitive.NewObjectID(),
+		Action:    "bay.blackout_created",
+		Entity:    "bay",
+		EntityID:  bayID,
+		UserID:    actorID(c),
+		Meta:      bson.M{"blackout_id": item.ID.Hex(), "start": item.Start, "end": item.End, "reason": item.Reason},
+		CreatedAt: now,
+	})
+	return c.Status(fiber.StatusCreated).JSON(item)
+}
+
+// DeleteBayBlackout removes a blackout window.
+func (h *Handler) DeleteBayBlackout(c *fiber.Ctx) error {
+	bayID, err := asObjectID(c.Params("id"))
+	if err != nil {
+		return fiber.ErrBadRequest
+	}
+	blackoutID, err := asObjectID(c.Params("blackoutId"))
+	if err != nil {
+		return fiber.ErrBadRequest
+	}
+	var item models.BayBlackout
+	err = h.DB.Collection(bayBlackoutCollection).FindOneAndDelete(h.ctx(c), bson.M{"_id": blackoutID, "bay_id": bayID}).Decode(&item)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return fiber.ErrNotFound
+	}
+	if err != nil {
+		return fiber.ErrInternalServerError
+	}
+	_, _ = h.DB.Collection(auditCollection).InsertOne(h.ctx(c), models.AuditLog{
+		ID:        primitive.NewObjectID(),
+		Action:    "bay.blackout_deleted",
+		Entity:    "bay",
+		EntityID:  bayID,
+		UserID:    actorID(c),
+		Meta:      bson.M{"blackout_id": item.ID.Hex(), "start": item.Start, "end": item.End, "reason": item.Reason},
+		CreatedAt: h.now(),
+	})
+	return c.SendStatus(fiber.StatusNoContent)
+}
@@ -14,10 +14,11 @@ type companyPostRequest struct {
 	Name    string `json:"name"`
 	Contact string `json:"contact"`
 	Phone   string `json:"phone"`
+	Version *int   `json:"version"`
 }
 
 func (h *Handler) ListCompanies(c *fiber.Ctx) error {
-	filter := bson.D{}
+	filter := bson.D{{Key: "deleted_at", Value: nil}}
 	if q := c.Query("q"); q != "" {
 		filter = append(filter, bson.E{Key: "$or", Value: []bson.M{
 			{"name": bson.M{"$regex": q, "$options": "i"}},
@@ -63,6 +64,7 @@ func (h *Handler) GetCompany(c *fiber.Ctx) error {
 	if err := h.DB.Collection(companyCollection).FindOne(h.ctx(c), bson.M{"_id": id}).Decode(&comp); err != nil {
 		return fiber.ErrInternalServerError
 	}
+	setETag(c, comp.Version)
 	return c.JSON(comp)
 }
 
@@ -122,6 +124,10 @@ func (h *Handler) UpdateCompany(c *fiber.Ctx) error {
 	if err := c.BodyParser(&req); err != nil {
 		return fiber.ErrBadRequest
 	}
+	version, checked, err := expectedVersion(c, req.Version)
+	if err != nil {
+		return err
+	}
 	// load previous
 	var prev models.Company
 	_ = h.DB.Collection(companyCollection).FindOne(h.ctx(c), bson.M{"_id": id}).Decode(&prev)
@@ -132,14 +138,25 @@ func (h *Handler) UpdateCompany(c *fiber.Ctx) error {
 			"phone":      req.Phone,
 			"updated_at": h.now(),
 		},
+		"$inc": bson.M{"version": 1},
 	}
-	res, err := h.DB.Collection(companyCollection).UpdateByID(h.ctx(c), id, update)
+	filter := notDeleted(bson.M{"_id": id})
+	if checked {
+		filter = versionFilter(id, version)
+	}
+	res, err := h.DB.Collection(companyCollection).UpdateOne(h.ctx(c), filter, update)
 	if err != nil {
 		return fiber.ErrInternalServerError
 	}
 	if res.MatchedCount == 0 {
+		if checked {
+			return h.conflictResponse(c, h.staleVersion(c, companyCollection, id, &models.Company{}))
+		}
 		return fiber.ErrNotFound
 	}
+	if checked {
+		setETag(c, version+1)
+	}
 	changes := bson.M{}
 	if prev.Name != req.Name {
 		changes["name"] = bson.M{"from": prev.Name, "to": req.Name}
@@ -170,19 +187,46 @@ func (h *Handler) UpdateCompany(c *fiber.Ctx) error {
 	return c.SendStatus(fiber.StatusNoContent)
 }
 
+// DeleteCompany moves a company into the trash. Contacts, units and active
+// bookings block the delete unless mode=cascade trashes them too, or
+// mode=reassign&target=<company> moves them, along with booking history, to
+// another company.
 func (h *Handler) DeleteCompany(c *fiber.Ctx) error {
 	id, err := asObjectID(c.Params("id"))
 	if err != nil {
 		return fiber.ErrBadRequest
 	}
-	res, err := h.DB.Collection(companyCollection).DeleteOne(h.ctx(c), bson.M{"_id": id})
-	if err != nil {
-		return fiber.ErrInternalServerError
-	}
-	if res.DeletedCount == 0 {
-		return fiber.ErrNotFound
-	}
-	return c.SendStatus(fiber.StatusNoContent)
+	deps := []dependent{
+		{"contacts", trashKinds["contacts"], notDeleted(bson.M{"company_id": id})},
+		{"vehicles", trashKinds["vehicles"], notDeleted(bson.M{"company_id": id})},
+		{"bookings", trashKinds["bookings"], activeBookings(bson.M{"company_id": id})},
+	}
+	return h.deleteWithDependents(c, deletePlan{
+		kind:       trashKinds["companies"],
+		id:         id,
+		dependents: deps,
+		cascade: func() (bson.M, error) {
+			return h.cascadeDelete(c, trashKinds["companies"], id, deps...)
+		},
+		reassign: func(target primitive.ObjectID) (bson.M, error) {
+			if err := h.requireLive(c, trashKinds["companies"], target); err != nil {
+				return nil, err
+			}
+			moved := bson.M{}
+			for _, d := range []dependent{
+				{"contacts", trashKinds["contacts"], notDeleted(bson.M{"company_id": id})},
+				{"vehicles", trashKinds["vehicles"], notDeleted(bson.M{"company_id": id})},
+				{"bookings", trashKinds["bookings"], notDeleted(bson.M{"company_id": id})},
+			} {
+				n, err := h.reassignField(c, d.kind, d.filter, "company_id", id, target, "company deleted")
+				if err != nil {
+					return nil, err
+				}
+				moved[d.name] = n
+			}
+			return bson.M{"target": target, "reassigned": moved}, nil
+		},
+	})
 }
 
 func (h *Handler) ListCompanyLogs(c *fiber.Ctx) error {
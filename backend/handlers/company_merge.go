@@ -0,0 +1,178 @@
+package handlers
+
+import (
+	"sort"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/tss-booking-system/backend/models"
+	"github.com/tss-booking-system/backend/services"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type companyMergeRequest struct {
+	SourceIDs []string `json:"source_ids"`
+	DryRun    bool     `json:"dry_run"`
+}
+
+// companyMergeSource is one company merged into the target and what moves
+// (or, on a dry run, would move) from it.
+type companyMergeSource struct {
+	ID       primitive.ObjectID `json:"id"`
+	Name     string             `json:"name"`
+	Vehicles int                `json:"vehicles"`
+	Contacts int                `json:"contacts"`
+	Bookings int                `json:"bookings"`
+}
+
+// companyRefs lists the records that point at a company by company_id.
+func companyRefs(id primitive.ObjectID) []dependent {
+	return []dependent{
+		{"vehicles", trashKinds["vehicles"], notDeleted(bson.M{"company_id": id})},
+		{"contacts", trashKinds["contacts"], notDeleted(bson.M{"company_id": id})},
+		{"bookings", trashKinds["bookings"], notDeleted(bson.M{"company_id": id})},
+	}
+}
+
+func (s *companyMergeSource) set(name string, n int) {
+	switch name {
+	case "vehicles":
+		s.Vehicles = n
+	case "contacts":
+		s.Contacts = n
+	case "bookings":
+		s.Bookings = n
+	}
+}
+
+// MergeCompanies moves all units, contacts, bookings and PM program
+// attachments of the source companies into the company in the URL and moves
+// the sources to the trash.
+// Source names become aliases of the target so the fleet import keeps
+// resolving them. With dry_run (body or ?dry_run=true) nothing is written and
+// the response previews what would move.
+func (h *Handler) MergeCompanies(c *fiber.Ctx) error {
+	targetID, err := asObjectID(c.Params("id"))
+	if err != nil {
+		return fiber.ErrBadRequest
+	}
+	var req companyMergeRequest
+	if err := c.BodyParser(&req); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "invalid body")
+	}
+	dryRun := req.DryRun || c.QueryBool("dry_run")
+	ids, err := parseObjectIDs(req.SourceIDs)
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "invalid source_ids")
+	}
+	if ids, err = services.MergeSources(targetID, ids); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, err.Error())
+	}
+	var target models.Company
+	if err := h.DB.Collection(companyCollection).FindOne(h.ctx(c), notDeleted(bson.M{"_id": targetID})).Decode(&target); err != nil {
+		return fiber.ErrNotFound
+	}
+	cur, err := h.DB.Collection(companyCollection).Find(h.ctx(c), notDeleted(bson.M{"_id": bson.M{"$in": ids}}))
+	if err != nil {
+		return fiber.ErrInternalServerError
+	}
+	var sources []models.Company
+	if err := cur.All(h.ctx(c), &sources); err != nil {
+		return fiber.ErrInternalServerError
+	}
+	if len(sources) != len(ids) {
+		return fiber.NewError(fiber.StatusBadRequest, "source company not found")
+	}
+
+	programs, err := h.repointPMPrograms(c, "company_ids", ids, targetID, "company merged", dryRun)
+	if err != nil {
+		return err
+	}
+	result := make([]companyMergeSource, 0, len(sources))
+	totals := companyMergeSource{}
+	var aliases []string
+	for _, src := range sources {
+		item := companyMergeSource{ID: src.ID, Name: src.Name}
+		for _, d := range companyRefs(src.ID) {
+			var n int
+			if dryRun {
+				count, err := h.DB.Collection(d.kind.collection).CountDocuments(h.ctx(c), d.filter)
+				if err != nil {
+					return fiber.ErrInternalServerError
+				}
+				n = int(count)
+			} else if n, err = h.reassignField(c, d.kind, d.filter, "company_id", src.ID, targetID, "company merged"); err != nil {
+				return err
+			}
+			item.set(d.name, n)
+		}
+		totals.Vehicles += item.Vehicles
+		totals.Contacts += item.Contacts
+		totals.Bookings += item.Bookings
+		result = append(result, item)
+		aliases = append(aliases, src.Name)
+		aliases = append(aliases, src.Aliases...)
+		if !dryRun {
+			if err := h.softDelete(c, trashKinds["companies"], src.ID, bson.M{"merged_into": targetID}); err != nil {
+				return err
+			}
+		}
+	}
+	if !dryRun {
+		now := h.now()
+		if _, err := h.DB.Collection(companyCollection).UpdateByID(h.ctx(c), targetID, bson.M{
+			"$addToSet": bson.M{"aliases": bson.M{"$each": aliases}},
+			"$set":      bson.M{"updated_at": now},
+			"$inc":      bson.M{"version": 1},
+		}); err != nil {
+			return fiber.ErrInternalServerError
+		}
+		_, _ = h.DB.Collection(auditCollection).InsertOne(h.ctx(c), models.AuditLog{
+			ID:        primitive.NewObjectID(),
+			Action:    "company.merged",
+			Entity:    "company",
+			EntityID:  targetID,
+			UserID:    actorID(c),
+			Meta:      bson.M{"sources": result, "moved": bson.M{"vehicles": totals.Vehicles, "contacts": totals.Contacts, "bookings": totals.Bookings, "pm_programs": programs}},
+			CreatedAt: now,
+		})
+	}
+	return c.JSON(fiber.Map{
+		"dry_run": dryRun,
+		"target":  fiber.Map{"id": target.ID, "name": target.Name},
+		"sources": result,
+		"totals":  fiber.Map{"vehicles": totals.Vehicles, "contacts": totals.Contacts, "bookings": totals.Bookings, "pm_programs": programs},
+	})
+}
+
+// ListDuplicateCompanies groups live companies whose names only differ in
+// case, punctuation or legal form, as merge candidates.
+func (h *Handler) ListDuplicateCompanies(c *fiber.Ctx) error {
+	cur, err := h.DB.Collection(companyCollection).Find(h.ctx(c), notDeleted(bson.M{}), options.Find().SetSort(bson.D{{Key: "created_at", Value: 1}}))
+	if err != nil {
+		return fiber.ErrInternalServerError
+	}
+	defer cur.Close(h.ctx(c))
+	var items []models.Company
+	if err := cur.All(h.ctx(c), &items); err != nil {
+		return fiber.ErrInternalServerError
+	}
+	groups := map[string][]models.Company{}
+	var keys []string
+	for _, comp := range items {
+		key := services.CompanyNameKey(comp.Name)
+		if _, ok := groups[key]; !ok {
+			keys = append(keys, key)
+		}
+		groups[key] = append(groups[key], comp)
+	}
+	sort.Strings(keys)
+	out := make([]fiber.Map, 0)
+	for _, key := range keys {
+		if len(groups[key]) > 1 {
+			out = append(out, fiber.Map{"key": key, "companies": groups[key]})
+		}
+	}
+	return c.JSON(out)
+}
@@ -23,7 +23,7 @@ func (h *Handler) ListCompanyContacts(c *fiber.Ctx) error {
 		return fiber.ErrBadRequest
 	}
 	opts := options.Find().SetSort(bson.D{{Key: "created_at", Value: -1}})
-	cur, err := h.DB.Collection(contactCollection).Find(h.ctx(c), bson.M{"company_id": companyID}, opts)
+	cur, err := h.DB.Collection(contactCollection).Find(h.ctx(c), notDeleted(bson.M{"company_id": companyID}), opts)
 	if err != nil {
 		return fiber.ErrInternalServerError
 	}
@@ -60,6 +60,7 @@ func (h *Handler) CreateCompanyContact(c *fiber.Ctx) error {
 	// If company has empty primary contact, backfill from first contact
 	_, _ = h.DB.Collection(companyCollection).UpdateByID(h.ctx(c), companyID, bson.M{
 		"$set": bson.M{"contact": req.Name, "phone": req.Phone, "updated_at": now},
+		"$inc": bson.M{"version": 1},
 	})
 	return c.Status(fiber.StatusCreated).JSON(contact)
 }
@@ -79,7 +80,7 @@ func (h *Handler) UpdateContact(c *fiber.Ctx) error {
 		"email":      req.Email,
 		"updated_at": h.now(),
 	}}
-	res, err := h.DB.Collection(contactCollection).UpdateByID(h.ctx(c), id, update)
+	res, err := h.DB.Collection(contactCollection).UpdateOne(h.ctx(c), notDeleted(bson.M{"_id": id}), update)
 	if err != nil {
 		return fiber.ErrInternalServerError
 	}
@@ -94,12 +95,8 @@ func (h *Handler) DeleteContact(c *fiber.Ctx) error {
 	if err != nil {
 		return fiber.ErrBadRequest
 	}
-	res, err := h.DB.Collection(contactCollection).DeleteOne(h.ctx(c), bson.M{"_id": id})
-	if err != nil {
-		return fiber.ErrInternalServerError
-	}
-	if res.DeletedCount == 0 {
-		return fiber.ErrNotFound
+	if err := h.softDelete(c, trashKinds["contacts"], id, nil); err != nil {
+		return err
 	}
 	return c.SendStatus(fiber.StatusNoContent)
 }
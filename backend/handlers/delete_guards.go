@@ -0,0 +1,260 @@
+package handlers
+
+import (
+	"reflect"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/tss-booking-system/backend/models"
+	"github.com/tss-booking-system/backend/services"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// dependent is a set of live records referencing a record about to be deleted.
+type dependent struct {
+	name   string
+	kind   trashKind
+	filter bson.M
+}
+
+// deletePlan describes how a record with dependents is deleted. cascade and
+// reassign run for their mode and return extra audit meta; reassign gets the
+// ?target= id, which is zero when omitted.
+type deletePlan struct {
+	kind       trashKind
+	id         primitive.ObjectID
+	dependents []dependent
+	cascade    func() (bson.M, error)
+	reassign   func(target primitive.ObjectID) (bson.M, error)
+}
+
+// activeBookings narrows filter to live bookings that still hold a slot.
+func activeBookings(filter bson.M) bson.M {
+	filter["status"] = bson.M{"$in": []models.BookingStatus{models.BookingOpen, models.BookingInProgress}}
+	return notDeleted(filter)
+}
+
+// deleteWithDependents moves plan.id into the trash once its dependents are
+// dealt with. Without ?mode= a record that is still referenced is refused
+// with 409 and the dependent counts; mode=cascade and mode=reassign (with
+// ?target=) run the plan first. The delete audit entry records mode and counts;
+// success answers 204.
+func (h *Handler) deleteWithDependents(c *fiber.Ctx, plan deletePlan) error {
+	mode, err := services.ParseDeleteMode(c.Query("mode"))
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, err.Error())
+	}
+	var target primitive.ObjectID
+	if v := c.Query("target"); v != "" {
+		if target, err = asObjectID(v); err != nil || target == plan.id {
+			return fiber.NewError(fiber.StatusBadRequest, services.ErrInvalidReassignTarget.Error())
+		}
+	}
+	if n, err := h.DB.Collection(plan.kind.collection).CountDocuments(h.ctx(c), notDeleted(bson.M{"_id": plan.id})); err != nil {
+		return fiber.ErrInternalServerError
+	} else if n == 0 {
+		return fiber.ErrNotFound
+	}
+	counts := services.Dependents{}
+	for _, d := range plan.dependents {
+		n, err := h.DB.Collection(d.kind.collection).CountDocuments(h.ctx(c), d.filter)
+		if err != nil {
+			return fiber.ErrInternalServerError
+		}
+		counts[d.name] = n
+	}
+	meta := bson.M{"dependents": counts}
+	if counts.Total() > 0 {
+		var extra bson.M
+		switch mode {
+		case services.DeleteCascade:
+			extra, err = plan.cascade()
+		case services.DeleteReassign:
+			extra, err = plan.reassign(target)
+		default:
+			return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+				"error":      services.ErrHasDependents.Error(),
+				"dependents": counts,
+			})
+		}
+		if err != nil {
+			return h.conflictResponse(c, err)
+		}
+		meta["mode"] = mode
+		for k, v := range extra {
+			meta[k] = v
+		}
+	}
+	if err := h.softDelete(c, plan.kind, plan.id, meta); err != nil {
+		return err
+	}
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+// requireLive fails with 400 unless id is a record of kind outside the trash.
+func (h *Handler) requireLive(c *fiber.Ctx, kind trashKind, id primitive.ObjectID) error {
+	if id.IsZero() {
+		return fiber.NewError(fiber.StatusBadRequest, services.ErrInvalidReassignTarget.Error())
+	}
+	n, err := h.DB.Collection(kind.collection).CountDocuments(h.ctx(c), notDeleted(bson.M{"_id": id}))
+	if err != nil {
+		return fiber.ErrInternalServerError
+	}
+	if n == 0 {
+		return fiber.NewError(fiber.StatusBadRequest, services.ErrInvalidReassignTarget.Error())
+	}
+	return nil
+}
+
+func (h *Handler) dependentIDs(c *fiber.Ctx, d dependent) ([]primitive.ObjectID, error) {
+	cur, err := h.DB.Collection(d.kind.collection).Find(h.ctx(c), d.filter, options.Find().SetProjection(bson.M{"_id": 1}))
+	if err != nil {
+		return nil, fiber.ErrInternalServerError
+	}
+	defer cur.Close(h.ctx(c))
+	var docs []struct {
+		ID primitive.ObjectID `bson:"_id"`
+	}
+	if err := cur.All(h.ctx(c), &docs); err != nil {
+		return nil, fiber.ErrInternalServerError
+	}
+	ids := make([]primitive.ObjectID, 0, len(docs))
+	for _, d := range docs {
+		ids = append(ids, d.ID)
+	}
+	return ids, nil
+}
+
+func (h *Handler) dependentBookings(c *fiber.Ctx, filter bson.M) ([]models.Booking, error) {
+	cur, err := h.DB.Collection(bookingCollection).Find(h.ctx(c), filter, options.Find().SetSort(bson.D{{Key: "start", Value: 1}}))
+	if err != nil {
+		return nil, fiber.ErrInternalServerError
+	}
+	defer cur.Close(h.ctx(c))
+	var items []models.Booking
+	if err := cur.All(h.ctx(c), &items); err != nil {
+		return nil, fiber.ErrInternalServerError
+	}
+	return items, nil
+}
+
+// cascadeDelete moves every dependent record into the trash, each with its
+// own audit entry naming the record that caused it.
+func (h *Handler) cascadeDelete(c *fiber.Ctx, cause trashKind, causeID primitive.ObjectID, deps ...dependent) (bson.M, error) {
+	meta := bson.M{"cascade_from": bson.M{"entity": cause.entity, "id": causeID}}
+	deleted := bson.M{}
+	for _, d := range deps {
+		ids, err := h.dependentIDs(c, d)
+		if err != nil {
+			return nil, err
+		}
+		for _, id := range ids {
+			if err := h.softDelete(c, d.kind, id, meta); err != nil && err != fiber.ErrNotFound {
+				return nil, err
+			}
+			if d.kind.collection == bookingCollection {
+				pushRealtime(models.RealtimeEvent{Type: "booking.deleted", Data: id.Hex()})
+			}
+		}
+		deleted[d.name] = len(ids)
+	}
+	return bson.M{"cascaded": deleted}, nil
+}
+
+// reassignField points field of every record matching filter from one record
+// to another and audits each move as <entity>.updated with reason.
+func (h *Handler) reassignField(c *fiber.Ctx, kind trashKind, filter bson.M, field string, from, to primitive.ObjectID, reason string) (int, error) {
+	ids, err := h.dependentIDs(c, dependent{kind: kind, filter: filter})
+	if err != nil || len(ids) == 0 {
+		return 0, err
+	}
+	now := h.now()
+	if _, err := h.DB.Collection(kind.collection).UpdateMany(h.ctx(c), bson.M{"_id": bson.M{"$in": ids}}, bson.M{
+		"$set": bson.M{field: to, "updated_at": now},
+		"$inc": bson.M{"version": 1},
+	}); err != nil {
+		return 0, fiber.ErrInternalServerError
+	}
+	actor := actorID(c)
+	logs := make([]interface{}, 0, len(ids))
+	for _, id := range ids {
+		logs = append(logs, models.AuditLog{
+			ID:        primitive.NewObjectID(),
+			Action:    kind.entity + ".updated",
+			Entity:    kind.entity,
+			EntityID:  id,
+			UserID:    actor,
+			Meta:      bson.M{field: bson.M{"from": from, "to": to}, "reason": reason},
+			CreatedAt: now,
+		})
+	}
+	_, _ = h.DB.Collection(auditCollection).InsertMany(h.ctx(c), logs)
+	return len(ids), nil
+}
+
+// moveBookings applies change to every booking and saves each with a
+// booking.updated audit entry. With check the results are validated against
+// the schedule together first, and nothing is written when any conflicts or
+// when any booking was saved by someone else since it was loaded.
+func (h *Handler) moveBookings(c *fiber.Ctx, items []models.Booking, change func(*models.Booking), reason string, check bool) error {
+	now := h.now()
+	nexts := make([]models.Booking, len(items))
+	ids := make([]primitive.ObjectID, len(items))
+	for i, b := range items {
+		nexts[i] = b
+		change(&nexts[i])
+		nexts[i].UpdatedAt = now
+		ids[i] = b.ID
+	}
+	if check {
+		unlock, err := h.lockSchedule(c, nexts...)
+		if err != nil {
+			return err
+		}
+		defer unlock()
+		for i := range nexts {
+			if err := h.validateBookingConflicts(c, nexts[i], nexts[:i], ids...); err != nil {
+				return err
+			}
+		}
+	}
+	if err := h.checkBookingVersions(c, items...); err != nil {
+		return err
+	}
+	for i := range nexts {
+		if err := h.saveBookingUpdate(c, items[i], &nexts[i], bson.M{"reason": reason}); err != nil {
+			return err
+		}
+		if !reflect.DeepEqual(items[i].Jobs, nexts[i].Jobs) {
+			res, err := h.DB.Collection(bookingCollection).UpdateOne(h.ctx(c), versionFilter(ids[i], nexts[i].Version), bson.M{
+				"$set": bson.M{"jobs": nexts[i].Jobs},
+				"$inc": bson.M{"version": 1},
+			})
+			if err != nil {
+				return fiber.ErrInternalServerError
+			}
+			if res.MatchedCount == 0 {
+				return h.staleVersion(c, bookingCollection, ids[i], &models.Booking{})
+			}
+			nexts[i].Version++
+		}
+	}
+	return nil
+}
+
+// swapTechnician returns a change for moveBookings replacing from with to on
+// the booking and its job lines; a zero to unassigns.
+func swapTechnician(from, to primitive.ObjectID) func(*models.Booking) {
+	return func(b *models.Booking) {
+		b.TechnicianIDs = services.ReplaceTechnician(b.TechnicianIDs, from, to)
+		jobs := make([]models.JobLine, len(b.Jobs))
+		for i, j := range b.Jobs {
+			j.TechnicianIDs = services.ReplaceTechnician(j.TechnicianIDs, from, to)
+			jobs[i] = j
+		}
+		if b.Jobs != nil {
+			b.Jobs = jobs
+		}
+	}
+}
@@ -18,14 +18,19 @@ type Handler struct {
 	JWT      *services.JWTService
 	Telegram *services.TelegramService
 	TZ       *time.Location
+	Locks    *services.Locker
+	// TrashRetention is how long soft-deleted records are kept
+	TrashRetention time.Duration
 }
 
 func NewHandler(db *mongo.Database, jwt *services.JWTService, tg *services.TelegramService, tz *time.Location) *Handler {
 	return &Handler{
-		DB:       db,
-		JWT:      jwt,
-		Telegram: tg,
-		TZ:       tz,
+		DB:             db,
+		JWT:            jwt,
+		Telegram:       tg,
+		TZ:             tz,
+		Locks:          services.NewLocker(mongoLeaseStore{col: db.Collection(scheduleLockCollection)}),
+		TrashRetention: defaultTrashRetention,
 	}
 }
 
@@ -100,3 +105,18 @@ func getUserID(c *fiber.Ctx) string {
 	}
 	return ""
 }
+
+func getRole(c *fiber.Ctx) models.UserRole {
+	if v, ok := c.Locals(string(localRole)).(models.UserRole); ok {
+		return v
+	}
+	return ""
+}
+
+// actorID returns the authenticated user's id for audit entries.
+func actorID(c *fiber.Ctx) primitive.ObjectID {
+	if id, err := primitive.ObjectIDFromHex(getUserID(c)); err == nil {
+		return id
+	}
+	return primitive.NilObjectID
+}
@@ -0,0 +1,91 @@
+package handlers
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"github.com/tss-booking-system/backend/models"
+	"github.com/tss-booking-system/backend/services"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+const idempotencyCollection = "idempotency_keys"
+
+// Idempotency makes create endpoints safe to retry. When a request carries an
+// Idempotency-Key header, the first response is stored and replayed for
+// retries with the same key and body within services.IdempotencyWindow.
+// Reusing a key with a different body fails with 422; a retry arriving while
+// the first request still runs gets 409. Only 2xx responses are stored:
+// requests that fail with an error or any other status (including 409s
+// answered with a body) release the key, so a retry runs again against the
+// current state. Without the header the request passes through unchanged.
+func (h *Handler) Idempotency() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		key := c.Get("Idempotency-Key")
+		if key == "" {
+			return c.Next()
+		}
+		if err := services.ValidateIdempotencyKey(key); err != nil {
+			return fiber.NewError(fiber.StatusBadRequest, err.Error())
+		}
+		col := h.DB.Collection(idempotencyCollection)
+		now := h.now()
+		rec := models.IdempotencyRecord{
+			ID:          services.IdempotencyScope(getUserID(c), c.Method(), c.Path(), key),
+			Fingerprint: services.RequestFingerprint(c.Body()),
+			CreatedAt:   now,
+			ExpiresAt:   now.Add(services.IdempotencyWindow),
+		}
+		if _, err := col.InsertOne(h.ctx(c), rec); err != nil {
+			if !mongo.IsDuplicateKeyError(err) {
+				return fiber.ErrInternalServerError
+			}
+			var prev models.IdempotencyRecord
+			if err := col.FindOne(h.ctx(c), bson.M{"_id": rec.ID}).Decode(&prev); err != nil {
+				return fiber.NewError(fiber.StatusConflict, "request with this Idempotency-Key is in progress, retry later")
+			}
+			if prev.ExpiresAt.After(now) {
+				return replayIdempotent(c, prev, rec.Fingerprint)
+			}
+			// Expired but not purged by the TTL monitor yet: start over
+			res, err := col.ReplaceOne(h.ctx(c), bson.M{"_id": rec.ID, "expires_at": prev.ExpiresAt}, rec)
+			if err != nil {
+				return fiber.ErrInternalServerError
+			}
+			if res.MatchedCount == 0 {
+				return fiber.NewError(fiber.StatusConflict, "request with this Idempotency-Key is in progress, retry later")
+			}
+		}
+
+		if err := c.Next(); err != nil {
+			_, _ = col.DeleteOne(h.ctx(c), bson.M{"_id": rec.ID})
+			return err
+		}
+		status := c.Response().StatusCode()
+		if status < fiber.StatusOK || status >= fiber.StatusMultipleChoices {
+			_, _ = col.DeleteOne(h.ctx(c), bson.M{"_id": rec.ID})
+			return nil
+		}
+		_, _ = col.UpdateByID(h.ctx(c), rec.ID, bson.M{"$set": bson.M{
+			"completed":    true,
+			"status_code":  status,
+			"content_type": string(c.Response().Header.ContentType()),
+			"body":         append([]byte(nil), c.Response().Body()...),
+		}})
+		return nil
+	}
+}
+
+// replayIdempotent answers a retried request from its stored record.
+func replayIdempotent(c *fiber.Ctx, prev models.IdempotencyRecord, fingerprint string) error {
+	if prev.Fingerprint != fingerprint {
+		return fiber.NewError(fiber.StatusUnprocessableEntity, "Idempotency-Key was already used with a different request")
+	}
+	if !prev.Completed {
+		return fiber.NewError(fiber.StatusConflict, "request with this Idempotency-Key is in progress, retry later")
+	}
+	c.Set("Idempotent-Replayed", "true")
+	if prev.ContentType != "" {
+		c.Set(fiber.HeaderContentType, prev.ContentType)
+	}
+	return c.Status(prev.StatusCode).Send(prev.Body)
+}
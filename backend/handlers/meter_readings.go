@@ -0,0 +1,216 @@
+package handlers
+
+import (
+	"errors"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/tss-booking-system/backend/models"
+	"github.com/tss-booking-system/backend/services"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const meterReadingCollection = "meter_readings"
+
+// meterReadingRequest is an odometer and/or engine hours reading. Zero means
+// the meter was not read.
+type meterReadingRequest struct {
+	Odometer    int     `json:"odometer"`
+	EngineHours float64 `json:"engine_hours"`
+	// AllowLowerReading lets an admin record a reading below the last one,
+	// e.g. after a cluster or engine swap.
+	AllowLowerReading bool `json:"allow_lower_reading"`
+}
+
+func (r meterReadingRequest) empty() bool {
+	return r.Odometer == 0 && r.EngineHours == 0
+}
+
+// checkMeterReading validates req against the unit's last reading. A lower
+// reading is refused unless an admin set allow_lower_reading. It reports
+// whether a lower reading was let through.
+func (h *Handler) checkMeterReading(c *fiber.Ctx, vehicleID primitive.ObjectID, req meterReadingRequest) (bool, error) {
+	var v models.Vehicle
+	if err := h.DB.Collection(vehicleCollection).FindOne(h.ctx(c), bson.M{"_id": vehicleID}).Decode(&v); err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return false, fiber.NewError(fiber.StatusBadRequest, "vehicle not found")
+		}
+		return false, fiber.ErrInternalServerError
+	}
+	err := services.CheckMeterReading(v.Odometer, v.EngineHours, req.Odometer, req.EngineHours)
+	if !errors.Is(err, services.ErrMeterRollback) {
+		if err != nil {
+			return false, fiber.NewError(fiber.StatusBadRequest, err.Error())
+		}
+		return false, nil
+	}
+	if !req.AllowLowerReading {
+		return false, &meterRollback{err: err, last: v}
+	}
+	if getRole(c) != models.RoleAdmin {
+		return false, fiber.NewError(fiber.StatusForbidden, "only admins can record a lower reading")
+	}
+	return true, nil
+}
+
+// meterRollback carries the unit's last reading to meterReadingError.
+type meterRollback struct {
+	err  error
+	last models.Vehicle
+}
+
+func (e *meterRollback) Error() string { return e.err.Error() }
+
+// meterReadingError answers a rejected reading: 409 with the unit's last
+// reading for a rollback, the error itself otherwise.
+func meterReadingError(c *fiber.Ctx, err error) error {
+	var rb *meterRollback
+	if !errors.As(err, &rb) {
+		return err
+	}
+	return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+		"error": rb.Error(),
+		"last": fiber.Map{
+			"odometer":     rb.last.Odometer,
+			"engine_hours": rb.last.EngineHours,
+			"reading_at":   rb.last.ReadingAt,
+		},
+	})
+}
+
+// recordMeterReading stores a checked reading in the unit's history. Booking
+// handlers record and apply it before writing the booking and drop it again
+// when that write fails, so a stored booking never misses its reading.
+func (h *Handler) recordMeterReading(c *fiber.Ctx, vehicleID primitive.ObjectID, bookingID *primitive.ObjectID, req meterReadingRequest, source string, override bool) (models.MeterReading, error) {
+	reading := models.MeterReading{
+		ID:          primitive.NewObjectID(),
+		VehicleID:   vehicleID,
+		BookingID:   bookingID,
+		Odometer:    req.Odometer,
+		EngineHours: req.EngineHours,
+		Source:      source,
+		Override:    override,
+		RecordedBy:  actorID(c),
+		RecordedAt:  h.now(),
+	}
+	if _, err := h.DB.Collection(meterReadingCollection).InsertOne(h.ctx(c), reading); err != nil {
+		return reading, fiber.ErrInternalServerError
+	}
+	return reading, nil
+}
+
+// dropMeterReading removes a reading whose booking write did not go through.
+// When the reading was already applied, prev is the unit as it was before and
+// its readings are put back unless a newer reading has landed since.
+func (h *Handler) dropMeterReading(c *fiber.Ctx, reading models.MeterReading, prev *models.Vehicle) {
+	_, _ = h.DB.Collection(meterReadingCollection).DeleteOne(h.ctx(c), bson.M{"_id": reading.ID})
+	if prev == nil {
+		return
+	}
+	restore := bson.M{"$set": bson.M{"odometer": prev.Odometer, "engine_hours": prev.EngineHours, "reading_at": prev.ReadingAt}}
+	_, _ = h.DB.Collection(vehicleCollection).UpdateOne(h.ctx(c), bson.M{"_id": reading.VehicleID, "reading_at": reading.RecordedAt}, restore)
+}
+
+// applyMeterReading makes a stored reading the unit's current reading and
+// returns the unit as it was before. Unless the reading is an override, the
+// write only matches while the unit's meters are not above it, so a lower
+// reading that passed checkMeterReading alongside a concurrent higher one
+// gets a meterRollback instead of moving the meters back.
+func (h *Handler) applyMeterReading(c *fiber.Ctx, reading models.MeterReading) (models.Vehicle, error) {
+	filter := bson.M{"_id": reading.VehicleID}
+	set := bson.M{"reading_at": reading.RecordedAt}
+	if reading.Odometer > 0 {
+		set["odometer"] = reading.Odometer
+		if !reading.Override {
+			filter["odometer"] = bson.M{"$not": bson.M{"$gt": reading.Odometer}}
+		}
+	}
+	if reading.EngineHours > 0 {
+		set["engine_hours"] = reading.EngineHours
+		if !reading.Override {
+			filter["engine_hours"] = bson.M{"$not": bson.M{"$gt": reading.EngineHours}}
+		}
+	}
+	var prev models.Vehicle
+	err := h.DB.Collection(vehicleCollection).FindOneAndUpdate(h.ctx(c), filter, bson.M{"$set": set}).Decode(&prev)
+	if err == nil {
+		return prev, nil
+	}
+	if !errors.Is(err, mongo.ErrNoDocuments) {
+		return prev, fiber.ErrInternalServerError
+	}
+	// a higher reading landed after the check
+	var last models.Vehicle
+	if err := h.DB.Collection(vehicleCollection).FindOne(h.ctx(c), bson.M{"_id": reading.VehicleID}).Decode(&last); err != nil {
+		return prev, fiber.ErrInternalServerError
+	}
+	rollback := services.CheckMeterReading(last.Odometer, last.EngineHours, reading.Odometer, reading.EngineHours)
+	if !errors.Is(rollback, services.ErrMeterRollback) {
+		rollback = services.ErrMeterRollback
+	}
+	return prev, &meterRollback{err: rollback, last: last}
+}
+
+// auditMeterOverride records an accepted rollback once its write went through.
+func (h *Handler) auditMeterOverride(c *fiber.Ctx, reading models.MeterReading) {
+	if !reading.Override {
+		return
+	}
+	_, _ = h.DB.Collection(auditCollection).InsertOne(h.ctx(c), models.AuditLog{
+		ID:        primitive.NewObjectID(),
+		Action:    "vehicle.reading_overridden",
+		Entity:    "vehicle",
+		EntityID:  reading.VehicleID,
+		UserID:    actorID(c),
+		Meta:      bson.M{"odometer": reading.Odometer, "engine_hours": reading.EngineHours, "source": reading.Source},
+		CreatedAt: reading.RecordedAt,
+	})
+}
+
+// ListVehicleReadings returns a unit's odometer and engine hours readings,
+// newest first.
+func (h *Handler) ListVehicleReadings(c *fiber.Ctx) error {
+	id, err := asObjectID(c.Params("id"))
+	if err != nil {
+		return fiber.ErrBadRequest
+	}
+	opts := options.Find().SetSort(bson.D{{Key: "recorded_at", Value: -1}}).SetLimit(500)
+	cur, err := h.DB.Collection(meterReadingCollection).Find(h.ctx(c), bson.M{"vehicle_id": id}, opts)
+	if err != nil {
+		return fiber.ErrInternalServerError
+	}
+	defer cur.Close(h.ctx(c))
+	items := []models.MeterReading{}
+	if err := cur.All(h.ctx(c), &items); err != nil {
+		return fiber.ErrInternalServerError
+	}
+	return c.JSON(items)
+}
+
+// CreateVehicleReading records a reading taken outside a booking.
+func (h *Handler) CreateVehicleReading(c *fiber.Ctx) error {
+	id, err := asObjectID(c.Params("id"))
+	if err != nil {
+		return fiber.ErrBadRequest
+	}
+	var req meterReadingRequest
+	if err := c.BodyParser(&req); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "invalid body")
+	}
+	override, err := h.checkMeterReading(c, id, req)
+	if err != nil {
+		return meterReadingError(c, err)
+	}
+	reading, err := h.recordMeterReading(c, id, nil, req, "manual", override)
+	if err != nil {
+		return err
+	}
+	if _, err := h.applyMeterReading(c, reading); err != nil {
+		h.dropMeterReading(c, reading, nil)
+		return meterReadingError(c, err)
+	}
+	h.auditMeterOverride(c, reading)
+	return c.Status(fiber.StatusCreated).JSON(reading)
+}
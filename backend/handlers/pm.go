@@ -0,0 +1,564 @@
+package handlers
+
+import (
+	"errors"
+	"sort"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/tss-booking-system/backend/models"
+	"github.com/tss-booking-system/backend/services"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const pmProgramCollection = "pm_programs"
+
+// defaultPMWithin and defaultPMWithinMiles are the look-ahead of the PM due
+// list.
+const (
+	defaultPMWithin      = 30 * 24 * time.Hour
+	defaultPMWithinMiles = 1000
+)
+
+type pmProgramRequest struct {
+	Name          string   `json:"name"`
+	JobType       string   `json:"job_type"`
+	IntervalDays  int      `json:"interval_days"`
+	IntervalMiles int      `json:"interval_miles"`
+	VehicleIDs    []string `json:"vehicle_ids"`
+	CompanyIDs    []string `json:"company_ids"`
+	Version       *int     `json:"version"`
+}
+
+// program parses the request into a PM program and validates it.
+func (r pmProgramRequest) program() (models.PMProgram, error) {
+	vehicleIDs, err := parseObjectIDs(r.VehicleIDs)
+	if err != nil {
+		return models.PMProgram{}, fiber.NewError(fiber.StatusBadRequest, "invalid vehicle_ids")
+	}
+	companyIDs, err := parseObjectIDs(r.CompanyIDs)
+	if err != nil {
+		return models.PMProgram{}, fiber.NewError(fiber.StatusBadRequest, "invalid company_ids")
+	}
+	if vehicleIDs == nil {
+		vehicleIDs = []primitive.ObjectID{}
+	}
+	if companyIDs == nil {
+		companyIDs = []primitive.ObjectID{}
+	}
+	p := models.PMProgram{
+		Name:          r.Name,
+		JobType:       r.JobType,
+		IntervalDays:  r.IntervalDays,
+		IntervalMiles: r.IntervalMiles,
+		VehicleIDs:    vehicleIDs,
+		CompanyIDs:    companyIDs,
+	}
+	if err := services.ValidatePMProgram(p); err != nil {
+		return p, fiber.NewError(fiber.StatusBadRequest, err.Error())
+	}
+	return p, nil
+}
+
+func (h *Handler) findPMPrograms(c *fiber.Ctx, filter bson.M) ([]models.PMProgram, error) {
+	cur, err := h.DB.Collection(pmProgramCollection).Find(h.ctx(c), notDeleted(filter), options.Find().SetSort(bson.D{{Key: "name", Value: 1}}))
+	if err != nil {
+		return nil, err
+	}
+	defer cur.Close(h.ctx(c))
+	items := make([]models.PMProgram, 0)
+	if err := cur.All(h.ctx(c), &items); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+// ListPMPrograms returns PM programs by name. ?vehicle_id= and ?company_id=
+// limit the list to programs attached to that unit or company.
+func (h *Handler) ListPMPrograms(c *fiber.Ctx) error {
+	filter := bson.M{}
+	if v := c.Query("vehicle_id"); v != "" {
+		id, err := asObjectID(v)
+		if err != nil {
+			return fiber.NewError(fiber.StatusBadRequest, "invalid vehicle_id")
+		}
+		filter["vehicle_ids"] = id
+	}
+	if v := c.Query("company_id"); v != "" {
+		id, err := asObjectID(v)
+		if err != nil {
+			return fiber.NewError(fiber.StatusBadRequest, "invalid company_id")
+		}
+		filter["company_ids"] = id
+	}
+	items, err := h.findPMPrograms(c, filter)
+	if err != nil {
+		return fiber.ErrInternalServerError
+	}
+	return c.JSON(items)
+}
+
+// CreatePMProgram adds a PM program.
+func (h *Handler) CreatePMProgram(c *fiber.Ctx) error {
+	var req pmProgramRequest
+	if err := c.BodyParser(&req); err != nil {
+		return fiber.ErrBadRequest
+	}
+	item, err := req.program()
+	if err != nil {
+		return err
+	}
+	now := h.now()
+	item.ID = primitive.NewObjectID()
+	item.CreatedAt = now
+	item.UpdatedAt = now
+	if _, err := h.DB.Collection(pmProgramCollection).InsertOne(h.ctx(c), item); err != nil {
+		return fiber.ErrInternalServerError
+	}
+	_, _ = h.DB.Collection(auditCollection).InsertOne(h.ctx(c), models.AuditLog{
+		ID:        primitive.NewObjectID(),
+		Action:    "pm_program.created",
+		Entity:    "pm_program",
+		EntityID:  item.ID,
+		UserID:    actorID(c),
+		Meta:      bson.M{"name": item.Name, "job_type": item.JobType, "interval_days": item.IntervalDays, "interval_miles": item.IntervalMiles},
+		CreatedAt: now,
+	})
+	return c.Status(fiber.StatusCreated).JSON(item)
+}
+
+// UpdatePMProgram replaces a PM program's settings and attachments.
+func (h *Handler) UpdatePMProgram(c *fiber.Ctx) error {
+	id, err := asObjectID(c.Params("id"))
+	if err != nil {
+		return fiber.ErrBadRequest
+	}
+	var req pmProgramRequest
+	if err := c.BodyParser(&req); err != nil {
+		return fiber.ErrBadRequest
+	}
+	next, err := req.program()
+	if err != nil {
+		return err
+	}
+	version, checked, err := expectedVersion(c, req.Version)
+	if err != nil {
+		return err
+	}
+	var prev models.PMProgram
+	if err := h.DB.Collection(pmProgramCollection).FindOne(h.ctx(c), notDeleted(bson.M{"_id": id})).Decode(&prev); err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return fiber.ErrNotFound
+		}
+		return fiber.ErrInternalServerError
+	}
+	filter := notDeleted(bson.M{"_id": id})
+	if checked {
+		filter = versionFilter(id, version)
+	}
+	now := h.now()
+	res, err := h.DB.Collection(pmProgramCollection).UpdateOne(h.ctx(c), filter, bson.M{
+		"$set": bson.M{
+			"name":           next.Name,
+			"job_type":       next.JobType,
+			"interval_days":  next.IntervalDays,
+			"interval_miles": next.IntervalMiles,
+			"vehicle_ids":    next.VehicleIDs,
+			"company_ids":    next.CompanyIDs,
+			"updated_at":     now,
+		},
+		"$inc": bson.M{"version": 1},
+	})
+	if err != nil {
+		return fiber.ErrInternalServerError
+	}
+	if res.MatchedCount == 0 {
+		if checked {
+			return h.conflictResponse(c, h.staleVersion(c, pmProgramCollection, id, &models.PMProgram{}))
+		}
+		return fiber.ErrNotFound
+	}
+	if checked {
+		setETag(c, version+1)
+	}
+	changes := bson.M{}
+	if prev.Name != next.Name {
+		changes["name"] = bson.M{"from": prev.Name, "to": next.Name}
+	}
+	if prev.JobType != next.JobType {
+		changes["job_type"] = bson.M{"from": prev.JobType, "to": next.JobType}
+	}
+	if prev.IntervalDays != next.IntervalDays {
+		changes["interval_days"] = bson.M{"from": prev.IntervalDays, "to": next.IntervalDays}
+	}
+	if prev.IntervalMiles != next.IntervalMiles {
+		changes["interval_miles"] = bson.M{"from": prev.IntervalMiles, "to": next.IntervalMiles}
+	}
+	if len(prev.VehicleIDs) != len(next.VehicleIDs) || len(prev.CompanyIDs) != len(next.CompanyIDs) {
+		changes["attached"] = bson.M{"vehicles": len(next.VehicleIDs), "companies": len(next.CompanyIDs)}
+	}
+	if len(changes) > 0 {
+		_, _ = h.DB.Collection(auditCollection).InsertOne(h.ctx(c), models.AuditLog{
+			ID:        primitive.NewObjectID(),
+			Action:    "pm_program.updated",
+			Entity:    "pm_program",
+			EntityID:  id,
+			UserID:    actorID(c),
+			Meta:      changes,
+			CreatedAt: now,
+		})
+	}
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+// DeletePMProgram moves a PM program to the trash. Bookings it created stay.
+func (h *Handler) DeletePMProgram(c *fiber.Ctx) error {
+	id, err := asObjectID(c.Params("id"))
+	if err != nil {
+		return fiber.ErrBadRequest
+	}
+	var item models.PMProgram
+	err = h.DB.Collection(pmProgramCollection).FindOne(h.ctx(c), notDeleted(bson.M{"_id": id})).Decode(&item)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return fiber.ErrNotFound
+	}
+	if err != nil {
+		return fiber.ErrInternalServerError
+	}
+	if err := h.softDelete(c, trashKinds["pm_programs"], id, bson.M{"name": item.Name, "job_type": item.JobType}); err != nil {
+		return err
+	}
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+// repointPMPrograms replaces the from ids with to in field (company_ids or
+// vehicle_ids) of every PM program, trashed ones included so a restored
+// program follows the merge, and returns how many programs changed. With
+// dryRun it only counts them.
+func (h *Handler) repointPMPrograms(c *fiber.Ctx, field string, from []primitive.ObjectID, to primitive.ObjectID, reason string, dryRun bool) (int, error) {
+	filter := bson.M{field: bson.M{"$in": from}}
+	if dryRun {
+		n, err := h.DB.Collection(pmProgramCollection).CountDocuments(h.ctx(c), filter)
+		if err != nil {
+			return 0, fiber.ErrInternalServerError
+		}
+		return int(n), nil
+	}
+	ids, err := h.dependentIDs(c, dependent{kind: trashKinds["pm_programs"], filter: filter})
+	if err != nil || len(ids) == 0 {
+		return 0, err
+	}
+	now := h.now()
+	// one pipeline update swaps the ids without duplicating to
+	if _, err := h.DB.Collection(pmProgramCollection).UpdateMany(h.ctx(c), bson.M{"_id": bson.M{"$in": ids}}, bson.A{
+		bson.M{"$set": bson.M{
+			field:        bson.M{"$setUnion": bson.A{bson.M{"$setDifference": bson.A{"$" + field, from}}, bson.A{to}}},
+			"updated_at": now,
+			"version":    bson.M{"$add": bson.A{bson.M{"$ifNull": bson.A{"$version", 0}}, 1}},
+		}},
+	}); err != nil {
+		return 0, fiber.ErrInternalServerError
+	}
+	actor := actorID(c)
+	logs := make([]interface{}, 0, len(ids))
+	for _, id := range ids {
+		logs = append(logs, models.AuditLog{
+			ID:        primitive.NewObjectID(),
+			Action:    "pm_program.updated",
+			Entity:    "pm_program",
+			EntityID:  id,
+			UserID:    actor,
+			Meta:      bson.M{field: bson.M{"from": from, "to": to}, "reason": reason},
+			CreatedAt: now,
+		})
+	}
+	_, _ = h.DB.Collection(auditCollection).InsertMany(h.ctx(c), logs)
+	return len(ids), nil
+}
+
+// pmDueItem is one unit due for one PM program. BookingID is the unit's open
+// booking for the program's job type, if one is already scheduled.
+type pmDueItem struct {
+	services.PMState
+	Vehicle     models.Vehicle      `json:"vehicle"`
+	CompanyName string              `json:"company_name,omitempty"`
+	Program     models.PMProgram    `json:"program"`
+	BookingID   *primitive.ObjectID `json:"booking_id,omitempty"`
+}
+
+// pmKey identifies a unit and job type in the booking lookups.
+type pmKey struct {
+	VehicleID primitive.ObjectID `bson:"vehicle_id"`
+	JobType   string             `bson:"job_type"`
+}
+
+// pmDue lists units whose PM falls due within the look-ahead, soonest first.
+// Mileage intervals count from the odometer read on the last completed
+// service to the unit's current reading. companyID, when set, limits the
+// list to that company's units.
+func (h *Handler) pmDue(c *fiber.Ctx, window services.PMWindow, companyID primitive.ObjectID) ([]pmDueItem, error) {
+	programs, err := h.findPMPrograms(c, bson.M{})
+	if err != nil || len(programs) == 0 {
+		return []pmDueItem{}, err
+	}
+	vehicleIDs, companyIDs := []primitive.ObjectID{}, []primitive.ObjectID{}
+	jobTypes := map[string]bool{}
+	for _, p := range programs {
+		vehicleIDs = append(vehicleIDs, p.VehicleIDs...)
+		companyIDs = append(companyIDs, p.CompanyIDs...)
+		jobTypes[p.JobType] = true
+	}
+	filter := notDeleted(bson.M{"$or": []bson.M{
+		{"_id": bson.M{"$in": vehicleIDs}},
+		{"company_id": bson.M{"$in": companyIDs}},
+	}})
+	if !companyID.IsZero() {
+		filter["company_id"] = companyID
+	}
+	cur, err := h.DB.Collection(vehicleCollection).Find(h.ctx(c), filter)
+	if err != nil {
+		return nil, err
+	}
+	var vehicles []models.Vehicle
+	if err := cur.All(h.ctx(c), &vehicles); err != nil {
+		return nil, err
+	}
+	if len(vehicles) == 0 {
+		return []pmDueItem{}, nil
+	}
+	ids := make([]primitive.ObjectID, 0, len(vehicles))
+	owners := map[primitive.ObjectID]struct{}{}
+	for _, v := range vehicles {
+		ids = append(ids, v.ID)
+		owners[v.CompanyID] = struct{}{}
+	}
+	types := make([]string, 0, len(jobTypes))
+	for t := range jobTypes {
+		types = append(types, t)
+	}
+
+	// last completed service (and the highest odometer read on one) per
+	// unit and job type
+	var done []struct {
+		ID       pmKey     `bson:"_id"`
+		Last     time.Time `bson:"last"`
+		Odometer int       `bson:"odometer"`
+	}
+	agg, err := h.DB.Collection(bookingCollection).Aggregate(h.ctx(c), mongo.Pipeline{
+		{{Key: "$match", Value: notDeleted(bson.M{
+			"status":     models.BookingClosed,
+			"vehicle_id": bson.M{"$in": ids},
+			"job_type":   bson.M{"$in": types},
+		})}},
+		{{Key: "$group", Value: bson.M{
+			"_id":      bson.M{"vehicle_id": "$vehicle_id", "job_type": "$job_type"},
+			"last":     bson.M{"$max": bson.M{"$ifNull": bson.A{"$end", "$start"}}},
+			"odometer": bson.M{"$max": "$odometer"},
+		}}},
+	})
+	if err != nil {
+		return nil, err
+	}
+	if err := agg.All(h.ctx(c), &done); err != nil {
+		return nil, err
+	}
+	lastDone := map[pmKey]time.Time{}
+	lastOdometer := map[pmKey]int{}
+	for _, d := range done {
+		lastDone[d.ID] = d.Last
+		lastOdometer[d.ID] = d.Odometer
+		// services closed without a reading fall back to the unit's history
+		if d.Odometer == 0 {
+			if lastOdometer[d.ID], err = h.odometerAt(c, d.ID.VehicleID, d.Last); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	// bookings already scheduled for the service
+	scheduled := map[pmKey]primitive.ObjectID{}
+	active := activeBookings(bson.M{"vehicle_id": bson.M{"$in": ids}, "job_type": bson.M{"$in": types}})
+	bcur, err := h.DB.Collection(bookingCollection).Find(h.ctx(c), active, options.Find().SetProjection(bson.M{"vehicle_id": 1, "job_type": 1}))
+	if err != nil {
+		return nil, err
+	}
+	var open []models.Booking
+	if err := bcur.All(h.ctx(c), &open); err != nil {
+		return nil, err
+	}
+	for _, b := range open {
+		scheduled[pmKey{b.VehicleID, b.JobType}] = b.ID
+	}
+
+	companyNames, err := h.namesByID(c, companyCollection, owners)
+	if err != nil {
+		return nil, err
+	}
+	now := h.now()
+	items := make([]pmDueItem, 0)
+	for _, v := range vehicles {
+		for _, p := range services.ApplicablePMPrograms(programs, v) {
+			key := pmKey{v.ID, p.JobType}
+			var last *time.Time
+			if t, ok := lastDone[key]; ok {
+				last = &t
+			}
+			st := services.PMDueState(p, last, lastOdometer[key], v.Odometer, now, window)
+			if !st.Due {
+				continue
+			}
+			item := pmDueItem{PMState: st, Vehicle: v, CompanyName: companyNames[v.CompanyID], Program: p}
+			if id, ok := scheduled[key]; ok {
+				item.BookingID = &id
+			}
+			items = append(items, item)
+		}
+	}
+	sort.SliceStable(items, func(i, j int) bool { return services.PMDueBefore(items[i].PMState, items[j].PMState) })
+	return items, nil
+}
+
+// odometerAt returns the unit's latest odometer reading taken at or before
+// at, or 0 when none was.
+func (h *Handler) odometerAt(c *fiber.Ctx, vehicleID primitive.ObjectID, at time.Time) (int, error) {
+	var r models.MeterReading
+	filter := bson.M{"vehicle_id": vehicleID, "odometer": bson.M{"$gt": 0}, "recorded_at": bson.M{"$lte": at}}
+	opts := options.FindOne().SetSort(bson.D{{Key: "recorded_at", Value: -1}})
+	if err := h.DB.Collection(meterReadingCollection).FindOne(h.ctx(c), filter, opts).Decode(&r); err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return 0, nil
+		}
+		return 0, err
+	}
+	return r.Odometer, nil
+}
+
+// pmDueQuery reads ?within= (default 30d), ?within_miles= (default 1000) and
+// ?company_id=.
+func pmDueQuery(c *fiber.Ctx) (services.PMWindow, primitive.ObjectID, error) {
+	var window services.PMWindow
+	within, err := services.ParseWithin(c.Query("within"), defaultPMWithin)
+	if err != nil {
+		return window, primitive.NilObjectID, fiber.NewError(fiber.StatusBadRequest, err.Error())
+	}
+	miles := c.QueryInt("within_miles", defaultPMWithinMiles)
+	if miles < 0 {
+		return window, primitive.NilObjectID, fiber.NewError(fiber.StatusBadRequest, "within_miles must not be negative")
+	}
+	window = services.PMWindow{Within: within, Miles: miles}
+	var companyID primitive.ObjectID
+	if v := c.Query("company_id"); v != "" {
+		if companyID, err = asObjectID(v); err != nil {
+			return window, primitive.NilObjectID, fiber.NewError(fiber.StatusBadRequest, "invalid company_id")
+		}
+	}
+	return window, companyID, nil
+}
+
+// ListPMDue returns the units due (or overdue) for a PM program within
+// ?within= (e.g. 30d, 4w; default 30 days) or ?within_miles= (default 1000),
+// soonest first. Units never serviced under a program are due now.
+func (h *Handler) ListPMDue(c *fiber.Ctx) error {
+	window, companyID, err := pmDueQuery(c)
+	if err != nil {
+		return err
+	}
+	items, err := h.pmDue(c, window, companyID)
+	if err != nil {
+		return fiber.ErrInternalServerError
+	}
+	return c.JSON(items)
+}
+
+type pmDueBookingsRequest struct {
+	Items []struct {
+		VehicleID string `json:"vehicle_id"`
+		ProgramID string `json:"program_id"`
+	} `json:"items"`
+}
+
+// CreatePMDueBookings puts units due for PM on the waiting list, one booking
+// per unit and program with the program's job type. The same ?within=,
+// ?within_miles= and ?company_id= as the due list apply; items in the body
+// narrow it to chosen unit/program pairs. Units that already have an open
+// booking for the job type are skipped.
+func (h *Handler) CreatePMDueBookings(c *fiber.Ctx) error {
+	var req pmDueBookingsRequest
+	if len(c.Body()) > 0 {
+		if err := c.BodyParser(&req); err != nil {
+			return fiber.NewError(fiber.StatusBadRequest, "invalid body")
+		}
+	}
+	window, companyID, err := pmDueQuery(c)
+	if err != nil {
+		return err
+	}
+	chosen := map[[2]primitive.ObjectID]bool{}
+	for _, it := range req.Items {
+		vid, err := asObjectID(it.VehicleID)
+		if err != nil {
+			return fiber.NewError(fiber.StatusBadRequest, "invalid vehicle_id")
+		}
+		pid, err := asObjectID(it.ProgramID)
+		if err != nil {
+			return fiber.NewError(fiber.StatusBadRequest, "invalid program_id")
+		}
+		chosen[[2]primitive.ObjectID{vid, pid}] = true
+	}
+	wlID, ok := h.findWaitingListBayID(c)
+	if !ok {
+		return fiber.NewError(fiber.StatusBadRequest, "waiting list bay is not configured")
+	}
+	due, err := h.pmDue(c, window, companyID)
+	if err != nil {
+		return fiber.ErrInternalServerError
+	}
+
+	now := h.now()
+	created := make([]models.Booking, 0)
+	skipped := 0
+	taken := map[pmKey]bool{}
+	for _, item := range due {
+		if len(chosen) > 0 && !chosen[[2]primitive.ObjectID{item.Vehicle.ID, item.Program.ID}] {
+			continue
+		}
+		key := pmKey{item.Vehicle.ID, item.Program.JobType}
+		if item.BookingID != nil || taken[key] {
+			skipped++
+			continue
+		}
+		taken[key] = true
+		booking := models.Booking{
+			ID:            primitive.NewObjectID(),
+			Complaint:     item.Program.Name + " due",
+			VehicleID:     item.Vehicle.ID,
+			BayID:         wlID,
+			TechnicianIDs: []primitive.ObjectID{},
+			CompanyID:     item.Vehicle.CompanyID,
+			Start:         now.In(h.TZ),
+			Status:        models.BookingOpen,
+			JobType:       item.Program.JobType,
+			Priority:      models.PriorityNormal,
+			CreatedBy:     actorID(c),
+			CreatedAt:     now,
+			UpdatedAt:     now,
+		}
+		booking.EstimatedDuration = h.resolveEstimatedDuration(c, booking, 0)
+		h.trackQueue(c, nil, &booking)
+		booking.Number = h.nextBookingNumber(c)
+		if _, err := h.DB.Collection(bookingCollection).InsertOne(h.ctx(c), booking); err != nil {
+			h.releaseBookingNumber(c, booking.Number)
+			return fiber.ErrInternalServerError
+		}
+		h.auditBookingCreated(c, booking)
+		pushRealtime(models.RealtimeEvent{Type: "booking.created", Data: booking})
+		created = append(created, booking)
+	}
+	return c.Status(fiber.StatusCreated).JSON(fiber.Map{
+		"created": created,
+		"skipped": skipped,
+	})
+}
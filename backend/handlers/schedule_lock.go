@@ -0,0 +1,78 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/tss-booking-system/backend/models"
+	"github.com/tss-booking-system/backend/services"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+const scheduleLockCollection = "schedule_locks"
+
+// mongoLeaseStore keeps schedule leases in MongoDB so that every backend
+// instance sees them. The deployment runs a standalone mongod, which has no
+// multi-document transactions.
+type mongoLeaseStore struct {
+	col *mongo.Collection
+}
+
+func (s mongoLeaseStore) TryAcquire(ctx context.Context, name, owner string, expires time.Time) (bool, error) {
+	_, err := s.col.InsertOne(ctx, bson.M{"_id": name, "owner": owner, "expires_at": expires})
+	if err == nil {
+		return true, nil
+	}
+	if !mongo.IsDuplicateKeyError(err) {
+		return false, err
+	}
+	// take over a lease whose holder died without releasing it
+	res, err := s.col.UpdateOne(ctx,
+		bson.M{"_id": name, "expires_at": bson.M{"$lt": time.Now()}},
+		bson.M{"$set": bson.M{"owner": owner, "expires_at": expires}},
+	)
+	if err != nil {
+		return false, err
+	}
+	return res.MatchedCount == 1, nil
+}
+
+func (s mongoLeaseStore) Release(ctx context.Context, name, owner string) error {
+	_, err := s.col.DeleteOne(ctx, bson.M{"_id": name, "owner": owner})
+	return err
+}
+
+// lockSchedule serialises changes touching the bays and technicians of the
+// given bookings. Callers hold the returned unlock from the conflict check
+// until the write is done.
+func (h *Handler) lockSchedule(c *fiber.Ctx, bookings ...models.Booking) (func(), error) {
+	unlock, err := h.Locks.Lock(h.ctx(c), services.ScheduleLockNames(bookings...)...)
+	if err != nil {
+		return nil, scheduleLockError(err)
+	}
+	return unlock, nil
+}
+
+// scheduleBooking runs check and write for b through services.Locker.Schedule,
+// the sequence the concurrency test of the locker drives. Lock failures come
+// back as HTTP errors, check and write errors unchanged.
+func (h *Handler) scheduleBooking(c *fiber.Ctx, b models.Booking, check, write func() error) error {
+	err := h.Locks.Schedule(h.ctx(c), []models.Booking{b}, check, write)
+	var lockErr *services.LockError
+	if errors.As(err, &lockErr) {
+		return scheduleLockError(lockErr.Err)
+	}
+	return err
+}
+
+// scheduleLockError answers a lock that timed out with 503 and any other lock
+// failure with 500.
+func scheduleLockError(err error) error {
+	if errors.Is(err, services.ErrLockTimeout) {
+		return fiber.NewError(fiber.StatusServiceUnavailable, err.Error())
+	}
+	return fiber.ErrInternalServerError
+}
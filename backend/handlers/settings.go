@@ -57,6 +57,69 @@ func (h *Handler) SaveTelegramSettings(c *fiber.Ctx) error {
 	return c.JSON(fiber.Map{"success": true})
 }
 
+type jobTypesRequest struct {
+	JobTypes []models.JobType `json:"job_types"`
+}
+
+// GetJobTypes returns the configured job types and their default estimates.
+func (h *Handler) GetJobTypes(c *fiber.Ctx) error {
+	var settings models.Settings
+	err := h.DB.Collection(settingsCollection).FindOne(h.ctx(c), bson.M{"_id": "global"}).Decode(&settings)
+	if err != nil && err != mongo.ErrNoDocuments {
+		return fiber.ErrInternalServerError
+	}
+	if settings.JobTypes == nil {
+		settings.JobTypes = []models.JobType{}
+	}
+	return c.JSON(settings.JobTypes)
+}
+
+// findJobType returns the configured job type with the given key.
+func (h *Handler) findJobType(c *fiber.Ctx, key string) (models.JobType, bool) {
+	if key == "" {
+		return models.JobType{}, false
+	}
+	var settings models.Settings
+	if err := h.DB.Collection(settingsCollection).FindOne(h.ctx(c), bson.M{"_id": "global"}).Decode(&settings); err != nil {
+		return models.JobType{}, false
+	}
+	for _, jt := range settings.JobTypes {
+		if jt.Key == key {
+			return jt, true
+		}
+	}
+	return models.JobType{}, false
+}
+
+// SaveJobTypes replaces the list of job types.
+func (h *Handler) SaveJobTypes(c *fiber.Ctx) error {
+	var req jobTypesRequest
+	if err := c.BodyParser(&req); err != nil {
+		return fiber.ErrBadRequest
+	}
+	seen := map[string]bool{}
+	for i, jt := range req.JobTypes {
+		req.JobTypes[i].Capabilities = services.NormalizeCapabilities(jt.Capabilities)
+		if jt.Key == "" || seen[jt.Key] {
+			return fiber.NewError(fiber.StatusBadRequest, "job type keys must be unique and not empty")
+		}
+		if jt.EstimatedDuration < 0 {
+			return fiber.NewError(fiber.StatusBadRequest, "estimated_duration must not be negative")
+		}
+		seen[jt.Key] = true
+	}
+	update := bson.M{
+		"$set": bson.M{
+			"job_types":  req.JobTypes,
+			"updated_at": time.Now(),
+		},
+	}
+	if _, err := h.DB.Collection(settingsCollection).UpdateByID(h.ctx(c), "global", update, optionsForUpsert()); err != nil {
+		return fiber.ErrInternalServerError
+	}
+	return c.JSON(req.JobTypes)
+}
+
 func optionsForUpsert() *options.UpdateOptions {
 	upsert := true
 	return &options.UpdateOptions{Upsert: &upsert}
@@ -153,6 +216,9 @@ func (h *Handler) PreviewTelegramTemplate(c *fiber.Ctx) error {
 		}
 		data["technician_names"] = strings.Join(names, ", ")
 	}
+	if len(booking.Jobs) > 0 {
+		data["jobs"] = services.FormatJobLines(booking.Jobs, "\n")
+	}
 
 	msg := services.Render(tpl, data)
 	return c.JSON(fiber.Map{"message": msg, "data": data})
@@ -0,0 +1,293 @@
+package handlers
+
+import (
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/tss-booking-system/backend/models"
+	"github.com/tss-booking-system/backend/services"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const timeOffCollection = "technician_time_off"
+
+type timeOffRequest struct {
+	Start  time.Time `json:"start"`
+	End    time.Time `json:"end"`
+	Kind   string    `json:"kind"`
+	Reason string    `json:"reason"`
+}
+
+// findTimeOff returns time off overlapping [from, to) for the given
+// technicians (all technicians when none are given).
+func (h *Handler) findTimeOff(c *fiber.Ctx, from, to time.Time, techIDs ...primitive.ObjectID) ([]models.TimeOff, error) {
+	filter := bson.M{
+		"start": bson.M{"$lt": to},
+		"end":   bson.M{"$gt": from},
+	}
+	if len(techIDs) > 0 {
+		filter["technician_id"] = bson.M{"$in": techIDs}
+	}
+	cur, err := h.DB.Collection(timeOffCollection).Find(h.ctx(c), filter, options.Find().SetSort(bson.D{{Key: "start", Value: 1}}))
+	if err != nil {
+		return nil, err
+	}
+	defer cur.Close(h.ctx(c))
+	items := make([]models.TimeOff, 0)
+	if err := cur.All(h.ctx(c), &items); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+// checkTechnicianSchedules verifies that the booking's technicians are on
+// shift and not on leave. The shop calendar mode decides the outcome: reject
+// fails with 422, any other mode attaches warnings.
+func (h *Handler) checkTechnicianSchedules(c *fiber.Ctx, b *models.Booking) error {
+	if len(b.TechnicianIDs) == 0 {
+		return nil
+	}
+	if wlID, ok := h.findWaitingListBayID(c); ok && wlID == b.BayID {
+		return nil
+	}
+	cur, err := h.DB.Collection(technicianCollection).Find(h.ctx(c), bson.M{"_id": bson.M{"$in": b.TechnicianIDs}})
+	if err != nil {
+		return fiber.ErrInternalServerError
+	}
+	defer cur.Close(h.ctx(c))
+	var techs []models.Technician
+	if err := cur.All(h.ctx(c), &techs); err != nil {
+		return fiber.ErrInternalServerError
+	}
+	timeOff, err := h.findTimeOff(c, b.Start, services.EffectiveEnd(*b), b.TechnicianIDs...)
+	if err != nil {
+		return fiber.ErrInternalServerError
+	}
+	var problems []string
+	for _, t := range techs {
+		if err := services.CheckTechnicianSchedule(t, timeOff, *b, h.TZ); err != nil {
+			problems = append(problems, err.Error())
+		}
+	}
+	if len(problems) == 0 {
+		return nil
+	}
+	if h.loadShopCalendar(c).Mode == models.CalendarReject {
+		return fiber.NewError(fiber.StatusUnprocessableEntity, strings.Join(problems, "; "))
+	}
+	b.Warnings = append(b.Warnings, problems...)
+	return nil
+}
+
+// ListTechnicianTimeOff returns upcoming (or from/to bounded) time off of a technician.
+func (h *Handler) ListTechnicianTimeOff(c *fiber.Ctx) error {
+	id, err := asObjectID(c.Params("id"))
+	if err != nil {
+		return fiber.ErrBadRequest
+	}
+	from := h.now()
+	to := from.AddDate(1, 0, 0)
+	if v := c.Query("from"); v != "" {
+		if from, err = parseQueryTime(v); err != nil {
+			return fiber.NewError(fiber.StatusBadRequest, "invalid from")
+		}
+	}
+	if v := c.Query("to"); v != "" {
+		if to, err = parseQueryTime(v); err != nil {
+			return fiber.NewError(fiber.StatusBadRequest, "invalid to")
+		}
+	}
+	items, err := h.findTimeOff(c, from, to, id)
+	if err != nil {
+		return fiber.ErrInternalServerError
+	}
+	return c.JSON(items)
+}
+
+// CreateTechnicianTimeOff records vacation, sick leave or training.
+func (h *Handler) CreateTechnicianTimeOff(c *fiber.Ctx) error {
+	techID, err := asObjectID(c.Params("id"))
+	if err != nil {
+		return fiber.ErrBadRequest
+	}
+	var req timeOffRequest
+	if err := c.BodyParser(&req); err != nil {
+		return fiber.ErrBadRequest
+	}
+	if !req.End.After(req.Start) {
+		return fiber.NewError(fiber.StatusBadRequest, "end must be after start")
+	}
+	if err := h.DB.Collection(technicianCollection).FindOne(h.ctx(c), notDeleted(bson.M{"_id": techID})).Err(); err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return fiber.ErrNotFound
+		}
+		return fiber.ErrInternalServerError
+	}
+	now := h.now()
+	item := models.TimeOff{
+		ID:           primitive.NewObjectID(),
+		TechnicianID: techID,
+		Start:        req.Start.In(h.TZ),
+		End:          req.End.In(h.TZ),
+		Kind:         req.Kind,
+		Reason:       req.Reason,
+		CreatedBy:    actorID(c),
+		CreatedAt:    now,
+	}
+	if _, err := h.DB.Collection(timeOffCollection).InsertOne(h.ctx(c), item); err != nil {
+		return fiber.ErrInternalServerError
+	}
+	_, _ = h.DB.Collection(auditCollection).InsertOne(h.ctx(c), models.AuditLog{
+		ID:        primitive.NewObjectID(),
+		Action:    "technician.time_off_created",
+		Entity:    "technician",
+		EntityID:  techID,
+		UserID:    actorID(c),
+		Meta:      bson.M{"time_off_id": item.ID.Hex(), "start": item.Start, "end": item.End, "kind": item.Kind},
+		CreatedAt: now,
+	})
+	return c.Status(fiber.StatusCreated).JSON(item)
+}
+
+// DeleteTechnicianTimeOff removes a time off entry.
+func (h *Handler) DeleteTechnicianTimeOff(c *fiber.Ctx) error {
+	techID, err := asObjectID(c.Params("id"))
+	if err != nil {
+		return fiber.ErrBadRequest
+	}
+	timeOffID, err := asObjectID(c.Params("timeOffId"))
+	if err != nil {
+		return fiber.ErrBadRequest
+	}
+	var item models.TimeOff
+	err = h.DB.Collection(timeOffCollection).FindOneAndDelete(h.ctx(c), bson.M{"_id": timeOffID, "technician_id": techID}).Decode(&item)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return fiber.ErrNotFound
+	}
+	if err != nil {
+		return fiber.ErrInternalServerError
+	}
+	_, _ = h.DB.Collection(auditCollection).InsertOne(h.ctx(c), models.AuditLog{
+		ID:        primitive.NewObjectID(),
+		Action:    "technician.time_off_deleted",
+		Entity:    "technician",
+		EntityID:  techID,
+		UserID:    actorID(c),
+		Meta:      bson.M{"time_off_id": item.ID.Hex(), "start": item.Start, "end": item.End, "kind": item.Kind},
+		CreatedAt: h.now(),
+	})
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+// GetTechnicianAvailability returns, per technician, on-shift time, time off,
+// booked time and the resulting free windows between from and to.
+// Technicians without shifts follow the shop opening hours when the shop
+// calendar is enforced. Optional filters: technician_ids, skills.
+func (h *Handler) GetTechnicianAvailability(c *fiber.Ctx) error {
+	from, err := parseQueryTime(c.Query("from"))
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "invalid from")
+	}
+	to, err := parseQueryTime(c.Query("to"))
+	if err != nil || !to.After(from) {
+		return fiber.NewError(fiber.StatusBadRequest, "invalid to")
+	}
+	if to.Sub(from) > 31*24*time.Hour {
+		return fiber.NewError(fiber.StatusBadRequest, "range must not exceed 31 days")
+	}
+	filter := notDeleted(bson.M{})
+	if ids := splitQueryList(c.Query("technician_ids")); len(ids) > 0 {
+		techIDs, err := parseObjectIDs(ids)
+		if err != nil {
+			return fiber.NewError(fiber.StatusBadRequest, "invalid technician_ids")
+		}
+		filter["_id"] = bson.M{"$in": techIDs}
+	}
+	if skills := splitQueryList(c.Query("skills")); len(skills) > 0 {
+		filter["skills"] = bson.M{"$all": skills}
+	}
+	cur, err := h.DB.Collection(technicianCollection).Find(h.ctx(c), filter, options.Find().SetSort(bson.D{{Key: "name", Value: 1}}))
+	if err != nil {
+		return fiber.ErrInternalServerError
+	}
+	defer cur.Close(h.ctx(c))
+	var techs []models.Technician
+	if err := cur.All(h.ctx(c), &techs); err != nil {
+		return fiber.ErrInternalServerError
+	}
+	techIDs := make([]primitive.ObjectID, 0, len(techs))
+	for _, t := range techs {
+		techIDs = append(techIDs, t.ID)
+	}
+	wlID, _ := h.findWaitingListBayID(c)
+	bookings, err := h.findTechnicianBookings(c, techIDs, wlID)
+	if err != nil {
+		return fiber.ErrInternalServerError
+	}
+	timeOff, err := h.findTimeOff(c, from, to, techIDs...)
+	if err != nil {
+		return fiber.ErrInternalServerError
+	}
+	shopHours := []services.Window{{Start: from, End: to}}
+	if cal := h.loadShopCalendar(c); cal.Mode != "" && cal.Mode != models.CalendarOff {
+		shopHours = services.WorkingWindows(cal, from, to, h.TZ)
+	}
+
+	type technicianAvailability struct {
+		TechnicianID primitive.ObjectID `json:"technician_id"`
+		Name         string             `json:"name"`
+		Skills       []string           `json:"skills"`
+		OnShift      []services.Window  `json:"on_shift"`
+		TimeOff      []models.TimeOff   `json:"time_off"`
+		Busy         []services.Window  `json:"busy"`
+		Available    []services.Window  `json:"available"`
+	}
+	now := h.now()
+	out := make([]technicianAvailability, 0, len(techs))
+	for _, t := range techs {
+		var own []models.Booking
+		for _, b := range bookings {
+			for _, id := range b.TechnicianIDs {
+				if id == t.ID {
+					own = append(own, b)
+					break
+				}
+			}
+		}
+		leave := make([]models.TimeOff, 0)
+		for _, off := range timeOff {
+			if off.TechnicianID == t.ID {
+				leave = append(leave, off)
+			}
+		}
+		onShift := shopHours
+		if len(t.Shifts) > 0 {
+			onShift = services.ShiftWindows(t.Shifts, from, to, h.TZ)
+		}
+		busy := services.IntersectWindows(services.MergeWindows(services.BusyWindows(own, now)), []services.Window{{Start: from, End: to}})
+		blocked := append(services.TimeOffWindows(leave), busy...)
+		available := services.IntersectWindows(services.FreeWindows(from, to, blocked, 0), onShift)
+		out = append(out, technicianAvailability{
+			TechnicianID: t.ID,
+			Name:         t.Name,
+			Skills:       t.Skills,
+			OnShift:      nonNilWindows(onShift),
+			TimeOff:      leave,
+			Busy:         nonNilWindows(busy),
+			Available:    nonNilWindows(available),
+		})
+	}
+	return c.JSON(fiber.Map{"from": from, "to": to, "technicians": out})
+}
+
+func nonNilWindows(w []services.Window) []services.Window {
+	if w == nil {
+		return []services.Window{}
+	}
+	return w
+}
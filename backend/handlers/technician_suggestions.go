@@ -0,0 +1,132 @@
+package handlers
+
+import (
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/tss-booking-system/backend/models"
+	"github.com/tss-booking-system/backend/services"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// rankTechnicians loads every technician with their bookings and time off
+// around the requested window and ranks them.
+func (h *Handler) rankTechnicians(c *fiber.Ctx, req services.SuggestionRequest) ([]services.TechnicianSuggestion, error) {
+	req.Now = h.now()
+	cur, err := h.DB.Collection(technicianCollection).Find(h.ctx(c), notDeleted(bson.M{}))
+	if err != nil {
+		return nil, err
+	}
+	defer cur.Close(h.ctx(c))
+	var techs []models.Technician
+	if err := cur.All(h.ctx(c), &techs); err != nil {
+		return nil, err
+	}
+	techIDs := make([]primitive.ObjectID, 0, len(techs))
+	for _, t := range techs {
+		techIDs = append(techIDs, t.ID)
+	}
+	wlID, _ := h.findWaitingListBayID(c)
+	bookings, err := h.findTechnicianBookings(c, techIDs, wlID)
+	if err != nil {
+		return nil, err
+	}
+	timeOff, err := h.findTimeOff(c, req.Start, req.End, techIDs...)
+	if err != nil {
+		return nil, err
+	}
+	candidates := make([]services.TechnicianCandidate, 0, len(techs))
+	for _, t := range techs {
+		cand := services.TechnicianCandidate{Technician: t}
+		for _, b := range bookings {
+			for _, id := range b.TechnicianIDs {
+				if id == t.ID {
+					cand.Bookings = append(cand.Bookings, b)
+					break
+				}
+			}
+		}
+		for _, off := range timeOff {
+			if off.TechnicianID == t.ID {
+				cand.TimeOff = append(cand.TimeOff, off)
+			}
+		}
+		candidates = append(candidates, cand)
+	}
+	return services.RankTechnicians(req, candidates, h.TZ), nil
+}
+
+func limitSuggestions(c *fiber.Ctx, items []services.TechnicianSuggestion) []services.TechnicianSuggestion {
+	limit := c.QueryInt("limit", 10)
+	if limit > 0 && len(items) > limit {
+		return items[:limit]
+	}
+	return items
+}
+
+// SuggestTechniciansForBooking ranks technicians for an existing booking using
+// its job type skills, complaint/description/job lines and time window.
+func (h *Handler) SuggestTechniciansForBooking(c *fiber.Ctx) error {
+	id, err := asObjectID(c.Params("id"))
+	if err != nil {
+		return fiber.ErrBadRequest
+	}
+	b, err := h.loadBooking(c, id)
+	if err != nil {
+		return err
+	}
+	req := services.SuggestionRequest{
+		Start:           b.Start,
+		End:             services.EffectiveEnd(b),
+		IgnoreBookingID: b.ID,
+	}
+	if jt, ok := h.findJobType(c, b.JobType); ok {
+		req.RequiredSkills = jt.Skills
+	}
+	text := []string{b.Complaint, b.Description}
+	for _, j := range b.Jobs {
+		text = append(text, j.Title, j.Complaint)
+	}
+	req.Text = strings.Join(text, " ")
+	items, err := h.rankTechnicians(c, req)
+	if err != nil {
+		return fiber.ErrInternalServerError
+	}
+	return c.JSON(limitSuggestions(c, items))
+}
+
+// SuggestTechnicians ranks technicians for work that is not booked yet.
+// Query params: from (required), to (defaults to from plus the job type
+// estimate or one hour), job_type, complaint, skills (extra required skills).
+func (h *Handler) SuggestTechnicians(c *fiber.Ctx) error {
+	from, err := parseQueryTime(c.Query("from"))
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "invalid from")
+	}
+	req := services.SuggestionRequest{
+		Start: from,
+		End:   from.Add(time.Hour),
+		Text:  c.Query("complaint"),
+	}
+	if jt, ok := h.findJobType(c, c.Query("job_type")); ok {
+		req.RequiredSkills = append(req.RequiredSkills, jt.Skills...)
+		if jt.EstimatedDuration > 0 {
+			req.End = from.Add(time.Duration(jt.EstimatedDuration) * time.Minute)
+		}
+	}
+	req.RequiredSkills = append(req.RequiredSkills, splitQueryList(c.Query("skills"))...)
+	if v := c.Query("to"); v != "" {
+		to, err := parseQueryTime(v)
+		if err != nil || !to.After(from) {
+			return fiber.NewError(fiber.StatusBadRequest, "invalid to")
+		}
+		req.End = to
+	}
+	items, err := h.rankTechnicians(c, req)
+	if err != nil {
+		return fiber.ErrInternalServerError
+	}
+	return c.JSON(limitSuggestions(c, items))
+}
@@ -1,8 +1,11 @@
 package handlers
 
 import (
+	"reflect"
+
 	"github.com/gofiber/fiber/v2"
 	"github.com/tss-booking-system/backend/models"
+	"github.com/tss-booking-system/backend/services"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
@@ -13,15 +16,17 @@ const technicianCollection = "technicians"
 const auditCollection = "audit_logs"
 
 type technicianRequest struct {
-	Name   string   `json:"name"`
-	Skills []string `json:"skills"`
-	Phone  string   `json:"phone"`
-	Email  string   `json:"email"`
+	Name    string          `json:"name"`
+	Skills  []string        `json:"skills"`
+	Phone   string          `json:"phone"`
+	Email   string          `json:"email"`
+	Shifts  *[]models.Shift `json:"shifts"`
+	Version *int            `json:"version"`
 }
 
 func (h *Handler) ListTechnicians(c *fiber.Ctx) error {
 	opts := options.Find().SetSort(bson.D{{Key: "created_at", Value: -1}})
-	cur, err := h.DB.Collection(technicianCollection).Find(h.ctx(c), bson.D{}, opts)
+	cur, err := h.DB.Collection(technicianCollection).Find(h.ctx(c), notDeleted(bson.M{}), opts)
 	if err != nil {
 		return fiber.ErrInternalServerError
 	}
@@ -47,7 +52,35 @@ func (h *Handler) GetOneTechnician(c *fiber.Ctx) error {
 		}
 		return fiber.ErrInternalServerError
 	}
-	return c.JSON(t)
+	// Labor totals, optionally limited to a from/to pay period
+	filter := bson.M{"technician_id": t.ID}
+	rng := bson.M{}
+	if v := c.Query("from"); v != "" {
+		from, err := parseQueryTime(v)
+		if err != nil {
+			return fiber.NewError(fiber.StatusBadRequest, "invalid from")
+		}
+		rng["$gte"] = from
+	}
+	if v := c.Query("to"); v != "" {
+		to, err := parseQueryTime(v)
+		if err != nil {
+			return fiber.NewError(fiber.StatusBadRequest, "invalid to")
+		}
+		rng["$lt"] = to
+	}
+	if len(rng) > 0 {
+		filter["start"] = rng
+	}
+	labor, err := h.laborTotals(c, filter)
+	if err != nil {
+		return fiber.ErrInternalServerError
+	}
+	setETag(c, t.Version)
+	return c.JSON(struct {
+		models.Technician
+		Labor services.LaborTotals `json:"labor"`
+	}{t, labor})
 }
 
 func (h *Handler) CreateTechnician(c *fiber.Ctx) error {
@@ -65,6 +98,12 @@ func (h *Handler) CreateTechnician(c *fiber.Ctx) error {
 		CreatedAt: now,
 		UpdatedAt: now,
 	}
+	if req.Shifts != nil {
+		if err := services.ValidateShifts(*req.Shifts); err != nil {
+			return fiber.NewError(fiber.StatusBadRequest, err.Error())
+		}
+		item.Shifts = *req.Shifts
+	}
 	if _, err := h.DB.Collection(technicianCollection).InsertOne(h.ctx(c), item); err != nil {
 		return fiber.ErrInternalServerError
 	}
@@ -87,6 +126,7 @@ func (h *Handler) CreateTechnician(c *fiber.Ctx) error {
 				"skills": item.Skills,
 				"phone":  item.Phone,
 				"email":  item.Email,
+				"shifts": item.Shifts,
 			},
 			CreatedAt: now,
 		}
@@ -107,22 +147,42 @@ func (h *Handler) UpdateTechnician(c *fiber.Ctx) error {
 	if err := c.BodyParser(&req); err != nil {
 		return fiber.ErrBadRequest
 	}
-	update := bson.M{
-		"$set": bson.M{
-			"name":       req.Name,
-			"skills":     req.Skills,
-			"phone":      req.Phone,
-			"email":      req.Email,
-			"updated_at": h.now(),
-		},
+	version, checked, err := expectedVersion(c, req.Version)
+	if err != nil {
+		return err
+	}
+	set := bson.M{
+		"name":       req.Name,
+		"skills":     req.Skills,
+		"phone":      req.Phone,
+		"email":      req.Email,
+		"updated_at": h.now(),
+	}
+	// shifts are only replaced when sent
+	if req.Shifts != nil {
+		if err := services.ValidateShifts(*req.Shifts); err != nil {
+			return fiber.NewError(fiber.StatusBadRequest, err.Error())
+		}
+		set["shifts"] = *req.Shifts
+	}
+	update := bson.M{"$set": set, "$inc": bson.M{"version": 1}}
+	filter := notDeleted(bson.M{"_id": id})
+	if checked {
+		filter = versionFilter(id, version)
 	}
-	res, err := h.DB.Collection(technicianCollection).UpdateByID(h.ctx(c), id, update)
+	res, err := h.DB.Collection(technicianCollection).UpdateOne(h.ctx(c), filter, update)
 	if err != nil {
 		return fiber.ErrInternalServerError
 	}
 	if res.MatchedCount == 0 {
+		if checked {
+			return h.conflictResponse(c, h.staleVersion(c, technicianCollection, id, &models.Technician{}))
+		}
 		return fiber.ErrNotFound
 	}
+	if checked {
+		setETag(c, version+1)
+	}
 	// audit: technician updated (diff)
 	{
 		changes := bson.M{}
@@ -149,6 +209,9 @@ func (h *Handler) UpdateTechnician(c *fiber.Ctx) error {
 		if prev.Email != req.Email {
 			changes["email"] = bson.M{"from": prev.Email, "to": req.Email}
 		}
+		if req.Shifts != nil && !reflect.DeepEqual(prev.Shifts, *req.Shifts) && (len(prev.Shifts) > 0 || len(*req.Shifts) > 0) {
+			changes["shifts"] = bson.M{"from": prev.Shifts, "to": *req.Shifts}
+		}
 		if len(changes) > 0 {
 			var userID primitive.ObjectID
 			if uid := getUserID(c); uid != "" {
@@ -171,19 +234,43 @@ func (h *Handler) UpdateTechnician(c *fiber.Ctx) error {
 	return c.SendStatus(fiber.StatusNoContent)
 }
 
+// DeleteTechnician moves a technician into the trash. Assignments on active
+// bookings block the delete unless mode=cascade unassigns the technician, or
+// mode=reassign&target=<technician> hands the work over after a conflict check.
 func (h *Handler) DeleteTechnician(c *fiber.Ctx) error {
 	id, err := asObjectID(c.Params("id"))
 	if err != nil {
 		return fiber.ErrBadRequest
 	}
-	res, err := h.DB.Collection(technicianCollection).DeleteOne(h.ctx(c), bson.M{"_id": id})
-	if err != nil {
-		return fiber.ErrInternalServerError
-	}
-	if res.DeletedCount == 0 {
-		return fiber.ErrNotFound
-	}
-	return c.SendStatus(fiber.StatusNoContent)
+	filter := activeBookings(bson.M{"technician_ids": id})
+	return h.deleteWithDependents(c, deletePlan{
+		kind:       trashKinds["technicians"],
+		id:         id,
+		dependents: []dependent{{"bookings", trashKinds["bookings"], filter}},
+		cascade: func() (bson.M, error) {
+			items, err := h.dependentBookings(c, filter)
+			if err != nil {
+				return nil, err
+			}
+			if err := h.moveBookings(c, items, swapTechnician(id, primitive.NilObjectID), "technician deleted", false); err != nil {
+				return nil, err
+			}
+			return bson.M{"unassigned": bson.M{"bookings": len(items)}}, nil
+		},
+		reassign: func(target primitive.ObjectID) (bson.M, error) {
+			if err := h.requireLive(c, trashKinds["technicians"], target); err != nil {
+				return nil, err
+			}
+			items, err := h.dependentBookings(c, filter)
+			if err != nil {
+				return nil, err
+			}
+			if err := h.moveBookings(c, items, swapTechnician(id, target), "technician deleted", true); err != nil {
+				return nil, err
+			}
+			return bson.M{"target": target, "reassigned": bson.M{"bookings": len(items)}}, nil
+		},
+	})
 }
 
 // ListTechnicianLogs returns audit logs related to the technician.
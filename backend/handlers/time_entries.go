@@ -0,0 +1,250 @@
+package handlers
+
+import (
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/tss-booking-system/backend/models"
+	"github.com/tss-booking-system/backend/services"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const timeEntryCollection = "time_entries"
+
+type timeEntryStartRequest struct {
+	TechnicianID string `json:"technician_id"`
+	BookingID    string `json:"booking_id"`
+	JobID        string `json:"job_id"`
+	Notes        string `json:"notes"`
+}
+
+// findTimeEntries returns entries matching filter, newest first.
+func (h *Handler) findTimeEntries(c *fiber.Ctx, filter bson.M) ([]models.TimeEntry, error) {
+	cur, err := h.DB.Collection(timeEntryCollection).Find(h.ctx(c), filter, options.Find().SetSort(bson.D{{Key: "start", Value: -1}}))
+	if err != nil {
+		return nil, err
+	}
+	defer cur.Close(h.ctx(c))
+	items := make([]models.TimeEntry, 0)
+	if err := cur.All(h.ctx(c), &items); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+// laborTotals sums the time entries matching filter.
+func (h *Handler) laborTotals(c *fiber.Ctx, filter bson.M) (services.LaborTotals, error) {
+	entries, err := h.findTimeEntries(c, filter)
+	if err != nil {
+		return services.LaborTotals{}, err
+	}
+	return services.SumTimeEntries(entries, h.now()), nil
+}
+
+// stopTimeEntry stops a running clock and audits it on the booking.
+func (h *Handler) stopTimeEntry(c *fiber.Ctx, e models.TimeEntry, at time.Time) (models.TimeEntry, error) {
+	e.End = &at
+	e.Active = false
+	e.Minutes = services.EntryMinutes(e, at)
+	e.UpdatedAt = at
+	res, err := h.DB.Collection(timeEntryCollection).UpdateOne(h.ctx(c), bson.M{"_id": e.ID, "active": true}, bson.M{
+		"$set":   bson.M{"end": e.End, "minutes": e.Minutes, "updated_at": at},
+		"$unset": bson.M{"active": ""},
+	})
+	if err != nil {
+		return e, fiber.ErrInternalServerError
+	}
+	if res.MatchedCount == 0 {
+		return e, fiber.NewError(fiber.StatusConflict, "time entry is already stopped")
+	}
+	_, _ = h.DB.Collection(auditCollection).InsertOne(h.ctx(c), models.AuditLog{
+		ID:        primitive.NewObjectID(),
+		Action:    "booking.clock_out",
+		Entity:    "booking",
+		EntityID:  e.BookingID,
+		UserID:    actorID(c),
+		Meta:      bson.M{"time_entry_id": e.ID.Hex(), "technician_id": e.TechnicianID.Hex(), "minutes": e.Minutes},
+		CreatedAt: at,
+	})
+	pushRealtime(models.RealtimeEvent{Type: "time_entry.stopped", Data: e})
+	return e, nil
+}
+
+// stopBookingClocks stops every running clock on a booking, e.g. when it is
+// closed or canceled.
+func (h *Handler) stopBookingClocks(c *fiber.Ctx, bookingID primitive.ObjectID) {
+	entries, err := h.findTimeEntries(c, bson.M{"booking_id": bookingID, "active": true})
+	if err != nil {
+		return
+	}
+	now := h.now()
+	for _, e := range entries {
+		_, _ = h.stopTimeEntry(c, e, now)
+	}
+}
+
+// ListTimeEntries returns labor entries filtered by technician_id, booking_id,
+// active=true and a from/to range on the clock-in time.
+func (h *Handler) ListTimeEntries(c *fiber.Ctx) error {
+	filter := bson.M{}
+	if v := c.Query("technician_id"); v != "" {
+		id, err := asObjectID(v)
+		if err != nil {
+			return fiber.NewError(fiber.StatusBadRequest, "invalid technician_id")
+		}
+		filter["technician_id"] = id
+	}
+	if v := c.Query("booking_id"); v != "" {
+		id, err := asObjectID(v)
+		if err != nil {
+			return fiber.NewError(fiber.StatusBadRequest, "invalid booking_id")
+		}
+		filter["booking_id"] = id
+	}
+	if c.Query("active") == "true" {
+		filter["active"] = true
+	}
+	rng := bson.M{}
+	if v := c.Query("from"); v != "" {
+		from, err := parseQueryTime(v)
+		if err != nil {
+			return fiber.NewError(fiber.StatusBadRequest, "invalid from")
+		}
+		rng["$gte"] = from
+	}
+	if v := c.Query("to"); v != "" {
+		to, err := parseQueryTime(v)
+		if err != nil {
+			return fiber.NewError(fiber.StatusBadRequest, "invalid to")
+		}
+		rng["$lt"] = to
+	}
+	if len(rng) > 0 {
+		filter["start"] = rng
+	}
+	items, err := h.findTimeEntries(c, filter)
+	if err != nil {
+		return fiber.ErrInternalServerError
+	}
+	now := h.now()
+	for i := range items {
+		if items[i].End == nil {
+			items[i].Minutes = services.EntryMinutes(items[i], now)
+		}
+	}
+	return c.JSON(items)
+}
+
+// StartTimeEntry clocks a technician on a booking (optionally a job line).
+// A technician can run only one clock at a time.
+func (h *Handler) StartTimeEntry(c *fiber.Ctx) error {
+	var req timeEntryStartRequest
+	if err := c.BodyParser(&req); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "invalid body")
+	}
+	techID, err := asObjectID(req.TechnicianID)
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "invalid technician_id")
+	}
+	bookingID, err := asObjectID(req.BookingID)
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "invalid booking_id")
+	}
+	b, err := h.loadBooking(c, bookingID)
+	if err != nil {
+		return err
+	}
+	if b.Status != models.BookingOpen && b.Status != models.BookingInProgress {
+		return fiber.NewError(fiber.StatusConflict, "booking is "+string(b.Status))
+	}
+	var jobID *primitive.ObjectID
+	if req.JobID != "" {
+		id, err := asObjectID(req.JobID)
+		if err != nil {
+			return fiber.NewError(fiber.StatusBadRequest, "invalid job_id")
+		}
+		found := false
+		for _, j := range b.Jobs {
+			if j.ID == id {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return fiber.NewError(fiber.StatusBadRequest, "job line not found on booking")
+		}
+		jobID = &id
+	}
+	if err := h.DB.Collection(technicianCollection).FindOne(h.ctx(c), notDeleted(bson.M{"_id": techID})).Err(); err != nil {
+		if err == mongo.ErrNoDocuments {
+			return fiber.NewError(fiber.StatusBadRequest, "technician not found")
+		}
+		return fiber.ErrInternalServerError
+	}
+
+	now := h.now()
+	entry := models.TimeEntry{
+		ID:           primitive.NewObjectID(),
+		TechnicianID: techID,
+		BookingID:    bookingID,
+		JobID:        jobID,
+		Start:        now,
+		Active:       true,
+		Notes:        req.Notes,
+		CreatedBy:    actorID(c),
+		CreatedAt:    now,
+		UpdatedAt:    now,
+	}
+	if _, err := h.DB.Collection(timeEntryCollection).InsertOne(h.ctx(c), entry); err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			var active models.TimeEntry
+			_ = h.DB.Collection(timeEntryCollection).FindOne(h.ctx(c), bson.M{"technician_id": techID, "active": true}).Decode(&active)
+			return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+				"error":  "technician is already clocked in",
+				"active": active,
+			})
+		}
+		return fiber.ErrInternalServerError
+	}
+	meta := bson.M{"time_entry_id": entry.ID.Hex(), "technician_id": techID.Hex()}
+	if jobID != nil {
+		meta["job_id"] = jobID.Hex()
+	}
+	_, _ = h.DB.Collection(auditCollection).InsertOne(h.ctx(c), models.AuditLog{
+		ID:        primitive.NewObjectID(),
+		Action:    "booking.clock_in",
+		Entity:    "booking",
+		EntityID:  bookingID,
+		UserID:    actorID(c),
+		Meta:      meta,
+		CreatedAt: now,
+	})
+	pushRealtime(models.RealtimeEvent{Type: "time_entry.started", Data: entry})
+	return c.Status(fiber.StatusCreated).JSON(entry)
+}
+
+// StopTimeEntry clocks a technician off.
+func (h *Handler) StopTimeEntry(c *fiber.Ctx) error {
+	id, err := asObjectID(c.Params("id"))
+	if err != nil {
+		return fiber.ErrBadRequest
+	}
+	var e models.TimeEntry
+	if err := h.DB.Collection(timeEntryCollection).FindOne(h.ctx(c), bson.M{"_id": id}).Decode(&e); err != nil {
+		if err == mongo.ErrNoDocuments {
+			return fiber.ErrNotFound
+		}
+		return fiber.ErrInternalServerError
+	}
+	if !e.Active {
+		return fiber.NewError(fiber.StatusConflict, "time entry is already stopped")
+	}
+	stopped, err := h.stopTimeEntry(c, e, h.now())
+	if err != nil {
+		return err
+	}
+	return c.JSON(stopped)
+}
@@ -0,0 +1,311 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/tss-booking-system/backend/models"
+	"github.com/tss-booking-system/backend/services"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// defaultTrashRetention is how long deleted records stay in the trash before
+// the TTL index on purge_at removes them for good.
+const defaultTrashRetention = 30 * 24 * time.Hour
+
+type trashKind struct {
+	collection string
+	entity     string
+}
+
+// trashKinds maps the record types used in trash URLs to their collection
+// and audit entity.
+var trashKinds = map[string]trashKind{
+	"bookings":    {bookingCollection, "booking"},
+	"companies":   {companyCollection, "company"},
+	"contacts":    {contactCollection, "contact"},
+	"vehicles":    {vehicleCollection, "vehicle"},
+	"technicians": {technicianCollection, "technician"},
+	"bays":        {bayCollection, "bay"},
+	"pm_programs": {pmProgramCollection, "pm_program"},
+}
+
+// stillReferenced reports whether a record that is live or kept in the trash
+// points at id.
+func (h *Handler) stillReferenced(c *fiber.Ctx, kind trashKind, id primitive.ObjectID) (bool, error) {
+	for _, ref := range services.ReferencesTo(kind.collection) {
+		n, err := h.DB.Collection(ref.Collection).CountDocuments(h.ctx(c), bson.M{ref.Field: id, "purge_at": nil}, options.Count().SetLimit(1))
+		if err != nil {
+			return false, err
+		}
+		if n > 0 {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// notDeleted narrows filter to records that are not in the trash. A null
+// deleted_at matches documents written before soft delete existed.
+func notDeleted(filter bson.M) bson.M {
+	filter["deleted_at"] = nil
+	return filter
+}
+
+// softDelete moves a record into the trash and writes the <entity>.deleted
+// audit entry with meta. It returns 404 when the record is missing or already
+// deleted. Records still referenced (see services.TrashReferences) are kept in the
+// trash until restored instead of being purged.
+func (h *Handler) softDelete(c *fiber.Ctx, kind trashKind, id primitive.ObjectID, meta bson.M) error {
+	now := h.now()
+	actor := actorID(c)
+	referenced, err := h.stillReferenced(c, kind, id)
+	if err != nil {
+		return fiber.ErrInternalServerError
+	}
+	set := bson.M{"deleted_at": now, "deleted_by": actor}
+	logMeta := bson.M{}
+	if referenced {
+		logMeta["kept"] = "referenced"
+	} else {
+		set["purge_at"] = now.Add(h.TrashRetention)
+		logMeta["purge_at"] = now.Add(h.TrashRetention)
+	}
+	res, err := h.DB.Collection(kind.collection).UpdateOne(h.ctx(c), notDeleted(bson.M{"_id": id}), bson.M{
+		"$set": set,
+		"$inc": bson.M{"version": 1},
+	})
+	if err != nil {
+		return fiber.ErrInternalServerError
+	}
+	if res.MatchedCount == 0 {
+		return fiber.ErrNotFound
+	}
+	for k, v := range meta {
+		logMeta[k] = v
+	}
+	_, _ = h.DB.Collection(auditCollection).InsertOne(h.ctx(c), models.AuditLog{
+		ID:        primitive.NewObjectID(),
+		Action:    kind.entity + ".deleted",
+		Entity:    kind.entity,
+		EntityID:  id,
+		UserID:    actor,
+		Meta:      logMeta,
+		CreatedAt: now,
+	})
+	return nil
+}
+
+// trashItem is one deleted record in the trash view. Record holds the whole
+// document as stored.
+type trashItem struct {
+	Type      string             `json:"type"`
+	ID        primitive.ObjectID `json:"id"`
+	Label     string             `json:"label"`
+	DeletedAt time.Time          `json:"deleted_at"`
+	DeletedBy primitive.ObjectID `json:"deleted_by"`
+	PurgeAt   *time.Time         `json:"purge_at,omitempty"`
+	Record    bson.M             `json:"record"`
+}
+
+// trashLabel picks a human readable name for a deleted record.
+func trashLabel(doc bson.M) string {
+	for _, key := range []string{"name", "number", "plate", "vin", "email"} {
+		if v, ok := doc[key].(string); ok && v != "" {
+			return v
+		}
+	}
+	return ""
+}
+
+func trashTime(v interface{}) time.Time {
+	if dt, ok := v.(primitive.DateTime); ok {
+		return dt.Time()
+	}
+	return time.Time{}
+}
+
+// ListTrash returns deleted records, newest first. ?type= limits the list to
+// one kind (bookings, companies, contacts, vehicles, technicians, bays,
+// pm_programs).
+func (h *Handler) ListTrash(c *fiber.Ctx) error {
+	types := make([]string, 0, len(trashKinds))
+	if v := c.Query("type"); v != "" {
+		if _, ok := trashKinds[v]; !ok {
+			return fiber.NewError(fiber.StatusBadRequest, "invalid type")
+		}
+		types = append(types, v)
+	} else {
+		for t := range trashKinds {
+			types = append(types, t)
+		}
+	}
+	opts := options.Find().SetSort(bson.D{{Key: "deleted_at", Value: -1}})
+	items := make([]trashItem, 0)
+	for _, t := range types {
+		cur, err := h.DB.Collection(trashKinds[t].collection).Find(h.ctx(c), bson.M{"deleted_at": bson.M{"$ne": nil}}, opts)
+		if err != nil {
+			return fiber.ErrInternalServerError
+		}
+		var docs []bson.M
+		err = cur.All(h.ctx(c), &docs)
+		cur.Close(h.ctx(c))
+		if err != nil {
+			return fiber.ErrInternalServerError
+		}
+		for _, doc := range docs {
+			item := trashItem{
+				Type:      t,
+				Label:     trashLabel(doc),
+				DeletedAt: trashTime(doc["deleted_at"]),
+				Record:    doc,
+			}
+			if at := trashTime(doc["purge_at"]); !at.IsZero() {
+				item.PurgeAt = &at
+			}
+			item.ID, _ = doc["_id"].(primitive.ObjectID)
+			item.DeletedBy, _ = doc["deleted_by"].(primitive.ObjectID)
+			items = append(items, item)
+		}
+	}
+	sort.SliceStable(items, func(i, j int) bool { return items[i].DeletedAt.After(items[j].DeletedAt) })
+	return c.JSON(items)
+}
+
+// RestoreFromTrash brings a deleted record back. A booking that still holds
+// its slot is checked against the current schedule first and gets the usual
+// 409 when the slot was taken in the meantime, or a 409 naming its bay, unit,
+// company or technician when that is still in the trash.
+func (h *Handler) RestoreFromTrash(c *fiber.Ctx) error {
+	kind, ok := trashKinds[c.Params("type")]
+	if !ok {
+		return fiber.ErrNotFound
+	}
+	id, err := asObjectID(c.Params("id"))
+	if err != nil {
+		return fiber.ErrBadRequest
+	}
+	filter := bson.M{"_id": id, "deleted_at": bson.M{"$ne": nil}}
+	var restored models.Booking
+	if kind.collection == bookingCollection {
+		if err := h.DB.Collection(bookingCollection).FindOne(h.ctx(c), filter).Decode(&restored); err != nil {
+			if errors.Is(err, mongo.ErrNoDocuments) {
+				return fiber.ErrNotFound
+			}
+			return fiber.ErrInternalServerError
+		}
+		if restored.Status == models.BookingOpen || restored.Status == models.BookingInProgress {
+			if err := h.checkBookingDependencies(c, restored); err != nil {
+				return err
+			}
+			unlock, err := h.lockSchedule(c, restored)
+			if err != nil {
+				return err
+			}
+			defer unlock()
+			if err := h.validateBookingConflicts(c, restored, nil); err != nil {
+				return h.conflictResponse(c, err)
+			}
+		}
+	}
+	res, err := h.DB.Collection(kind.collection).UpdateOne(h.ctx(c), filter, bson.M{
+		"$unset": bson.M{"deleted_at": "", "deleted_by": "", "purge_at": ""},
+		"$set":   bson.M{"updated_at": h.now()},
+		"$inc":   bson.M{"version": 1},
+	})
+	if err != nil {
+		return fiber.ErrInternalServerError
+	}
+	if res.MatchedCount == 0 {
+		return fiber.ErrNotFound
+	}
+	_, _ = h.DB.Collection(auditCollection).InsertOne(h.ctx(c), models.AuditLog{
+		ID:        primitive.NewObjectID(),
+		Action:    kind.entity + ".restored",
+		Entity:    kind.entity,
+		EntityID:  id,
+		UserID:    actorID(c),
+		Meta:      bson.M{},
+		CreatedAt: h.now(),
+	})
+	if kind.collection == bookingCollection {
+		restored.DeletedAt, restored.DeletedBy, restored.PurgeAt = nil, nil, nil
+		pushRealtime(models.RealtimeEvent{Type: "booking.restored", Data: restored})
+	}
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+// checkBookingDependencies refuses to bring back an active booking whose bay,
+// unit, company or technicians are in the trash; they must be restored first.
+func (h *Handler) checkBookingDependencies(c *fiber.Ctx, b models.Booking) error {
+	deps := []struct {
+		kind string
+		ids  []primitive.ObjectID
+	}{
+		{"bays", []primitive.ObjectID{b.BayID}},
+		{"vehicles", []primitive.ObjectID{b.VehicleID}},
+		{"companies", []primitive.ObjectID{b.CompanyID}},
+		{"technicians", b.TechnicianIDs},
+	}
+	for _, dep := range deps {
+		kind := trashKinds[dep.kind]
+		var trashed struct {
+			ID primitive.ObjectID `bson:"_id"`
+		}
+		err := h.DB.Collection(kind.collection).FindOne(h.ctx(c),
+			bson.M{"_id": bson.M{"$in": dep.ids}, "deleted_at": bson.M{"$ne": nil}},
+			options.FindOne().SetProjection(bson.M{"_id": 1}),
+		).Decode(&trashed)
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			continue
+		}
+		if err != nil {
+			return fiber.ErrInternalServerError
+		}
+		return fiber.NewError(fiber.StatusConflict, fmt.Sprintf("%s %s of this booking is in the trash; restore it first", kind.entity, trashed.ID.Hex()))
+	}
+	return nil
+}
+
+// PurgeFromTrash removes a deleted record permanently without waiting for
+// the retention period. Records history still points at are refused with 409.
+func (h *Handler) PurgeFromTrash(c *fiber.Ctx) error {
+	kind, ok := trashKinds[c.Params("type")]
+	if !ok {
+		return fiber.ErrNotFound
+	}
+	id, err := asObjectID(c.Params("id"))
+	if err != nil {
+		return fiber.ErrBadRequest
+	}
+	referenced, err := h.stillReferenced(c, kind, id)
+	if err != nil {
+		return fiber.ErrInternalServerError
+	}
+	if referenced {
+		return fiber.NewError(fiber.StatusConflict, "record is still referenced by bookings or units")
+	}
+	res, err := h.DB.Collection(kind.collection).DeleteOne(h.ctx(c), bson.M{"_id": id, "deleted_at": bson.M{"$ne": nil}})
+	if err != nil {
+		return fiber.ErrInternalServerError
+	}
+	if res.DeletedCount == 0 {
+		return fiber.ErrNotFound
+	}
+	_, _ = h.DB.Collection(auditCollection).InsertOne(h.ctx(c), models.AuditLog{
+		ID:        primitive.NewObjectID(),
+		Action:    kind.entity + ".purged",
+		Entity:    kind.entity,
+		EntityID:  id,
+		UserID:    actorID(c),
+		Meta:      bson.M{},
+		CreatedAt: h.now(),
+	})
+	return c.SendStatus(fiber.StatusNoContent)
+}
@@ -0,0 +1,206 @@
+package handlers
+
+import (
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/tss-booking-system/backend/models"
+	"github.com/tss-booking-system/backend/services"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// vehicleHistoryItem is one visit of a unit in its service history.
+// DurationMinutes runs from the actual (else planned) start to the end;
+// LaborMinutes sums the technicians' clocks.
+type vehicleHistoryItem struct {
+	models.Booking  `bson:",inline" json:",inline"`
+	BayName         string              `json:"bay_name,omitempty"`
+	Technicians     []fiber.Map         `json:"technicians"`
+	DurationMinutes int                 `json:"duration_minutes"`
+	LaborMinutes    int                 `json:"labor_minutes"`
+	Outcome         string              `json:"outcome"`
+	Corrections     []string            `json:"corrections,omitempty"`
+	ComebackOf      *primitive.ObjectID `json:"comeback_of,omitempty"`
+	ComebackDays    int                 `json:"comeback_days,omitempty"`
+}
+
+// bookingOutcome names how a visit ended, or that it has not yet.
+func bookingOutcome(status models.BookingStatus) string {
+	switch status {
+	case models.BookingClosed:
+		return "completed"
+	case models.BookingCanceled:
+		return "canceled"
+	case models.BookingInProgress:
+		return "in_progress"
+	}
+	return "scheduled"
+}
+
+// comebackWindow reads ?days= (default services.DefaultComebackDays).
+func comebackWindow(c *fiber.Ctx) (time.Duration, error) {
+	days := c.QueryInt("days", services.DefaultComebackDays)
+	if days <= 0 {
+		return 0, fiber.NewError(fiber.StatusBadRequest, "days must be positive")
+	}
+	return time.Duration(days) * 24 * time.Hour, nil
+}
+
+// GetVehicleHistory returns every booking of a unit, oldest first, with its
+// bay, technicians, duration and outcome. Visits that started within ?days=
+// (default 30) of a closed visit with a similar complaint are flagged with
+// comeback_of.
+func (h *Handler) GetVehicleHistory(c *fiber.Ctx) error {
+	id, err := asObjectID(c.Params("id"))
+	if err != nil {
+		return fiber.ErrBadRequest
+	}
+	window, err := comebackWindow(c)
+	if err != nil {
+		return err
+	}
+	var v models.Vehicle
+	if err := h.DB.Collection(vehicleCollection).FindOne(h.ctx(c), bson.M{"_id": id}).Decode(&v); err != nil {
+		return fiber.ErrNotFound
+	}
+	cur, err := h.DB.Collection(bookingCollection).Find(h.ctx(c), notDeleted(bson.M{"vehicle_id": id}), options.Find().SetSort(bson.D{{Key: "start", Value: 1}}))
+	if err != nil {
+		return fiber.ErrInternalServerError
+	}
+	defer cur.Close(h.ctx(c))
+	var bookings []models.Booking
+	if err := cur.All(h.ctx(c), &bookings); err != nil {
+		return fiber.ErrInternalServerError
+	}
+
+	bayIDs := map[primitive.ObjectID]struct{}{}
+	techIDs := map[primitive.ObjectID]struct{}{}
+	bookingIDs := make([]primitive.ObjectID, 0, len(bookings))
+	for _, b := range bookings {
+		bayIDs[b.BayID] = struct{}{}
+		for _, t := range b.TechnicianIDs {
+			techIDs[t] = struct{}{}
+		}
+		bookingIDs = append(bookingIDs, b.ID)
+	}
+	bayNames, err := h.namesByID(c, bayCollection, bayIDs)
+	if err != nil {
+		return err
+	}
+	techNames, err := h.namesByID(c, technicianCollection, techIDs)
+	if err != nil {
+		return err
+	}
+	labor := map[primitive.ObjectID]int{}
+	if len(bookingIDs) > 0 {
+		entries, err := h.findTimeEntries(c, bson.M{"booking_id": bson.M{"$in": bookingIDs}})
+		if err != nil {
+			return fiber.ErrInternalServerError
+		}
+		now := h.now()
+		for _, e := range entries {
+			labor[e.BookingID] += services.EntryMinutes(e, now)
+		}
+	}
+	comebacks := map[primitive.ObjectID]services.Comeback{}
+	for _, cb := range services.FindComebacks(bookings, window) {
+		comebacks[cb.BookingID] = cb
+	}
+
+	items := make([]vehicleHistoryItem, 0, len(bookings))
+	for _, b := range bookings {
+		item := vehicleHistoryItem{
+			Booking:      b,
+			BayName:      bayNames[b.BayID],
+			Technicians:  make([]fiber.Map, 0, len(b.TechnicianIDs)),
+			LaborMinutes: labor[b.ID],
+			Outcome:      bookingOutcome(b.Status),
+		}
+		for _, t := range b.TechnicianIDs {
+			item.Technicians = append(item.Technicians, fiber.Map{"id": t, "name": techNames[t]})
+		}
+		if b.End != nil {
+			start := b.Start
+			if b.ActualStart != nil {
+				start = *b.ActualStart
+			}
+			if d := b.End.Sub(start); d > 0 {
+				item.DurationMinutes = int(d.Minutes())
+			}
+		}
+		for _, j := range b.Jobs {
+			if j.Correction != "" {
+				item.Corrections = append(item.Corrections, j.Correction)
+			}
+		}
+		if cb, ok := comebacks[b.ID]; ok {
+			item.ComebackOf = &cb.PreviousID
+			item.ComebackDays = cb.Days
+		}
+		items = append(items, item)
+	}
+	return c.JSON(fiber.Map{
+		"vehicle":       v,
+		"comeback_days": int(window.Hours() / 24),
+		"comebacks":     len(comebacks),
+		"items":         items,
+	})
+}
+
+// namesByID loads the name field of the given records of a collection.
+func (h *Handler) namesByID(c *fiber.Ctx, collection string, ids map[primitive.ObjectID]struct{}) (map[primitive.ObjectID]string, error) {
+	names := map[primitive.ObjectID]string{}
+	if len(ids) == 0 {
+		return names, nil
+	}
+	list := make([]primitive.ObjectID, 0, len(ids))
+	for id := range ids {
+		list = append(list, id)
+	}
+	cur, err := h.DB.Collection(collection).Find(h.ctx(c), bson.M{"_id": bson.M{"$in": list}}, options.Find().SetProjection(bson.M{"name": 1}))
+	if err != nil {
+		return nil, fiber.ErrInternalServerError
+	}
+	defer cur.Close(h.ctx(c))
+	var docs []struct {
+		ID   primitive.ObjectID `bson:"_id"`
+		Name string             `bson:"name"`
+	}
+	if err := cur.All(h.ctx(c), &docs); err != nil {
+		return nil, fiber.ErrInternalServerError
+	}
+	for _, d := range docs {
+		names[d.ID] = d.Name
+	}
+	return names, nil
+}
+
+// recentComebacks finds comebacks that started within window before now, over
+// all units.
+func (h *Handler) recentComebacks(c *fiber.Ctx, window time.Duration) ([]services.Comeback, error) {
+	since := h.now().Add(-window)
+	// visits up to one window earlier can be what a recent visit came back from
+	cur, err := h.DB.Collection(bookingCollection).Find(h.ctx(c), notDeleted(bson.M{
+		"start":  bson.M{"$gte": since.Add(-window)},
+		"status": bson.M{"$ne": models.BookingCanceled},
+	}), options.Find().SetProjection(bson.M{
+		"vehicle_id": 1, "status": 1, "start": 1, "end": 1, "title": 1, "complaint": 1, "jobs.complaint": 1,
+	}))
+	if err != nil {
+		return nil, fiber.ErrInternalServerError
+	}
+	defer cur.Close(h.ctx(c))
+	var bookings []models.Booking
+	if err := cur.All(h.ctx(c), &bookings); err != nil {
+		return nil, fiber.ErrInternalServerError
+	}
+	var out []services.Comeback
+	for _, cb := range services.FindComebacks(bookings, window) {
+		if !cb.Start.Before(since) {
+			out = append(out, cb)
+		}
+	}
+	return out, nil
+}
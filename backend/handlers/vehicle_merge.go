@@ -0,0 +1,244 @@
+package handlers
+
+import (
+	"errors"
+	"regexp"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/tss-booking-system/backend/models"
+	"github.com/tss-booking-system/backend/services"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const errVINTaken = "another unit already has this VIN"
+
+type vehicleMergeRequest struct {
+	SourceIDs []string `json:"source_ids"`
+	DryRun    bool     `json:"dry_run"`
+	VIN       string   `json:"vin"`
+}
+
+// vehicleMergeSource is one unit merged into the target and how many bookings
+// move (or, on a dry run, would move) from it.
+type vehicleMergeSource struct {
+	ID       primitive.ObjectID `json:"id"`
+	VIN      string             `json:"vin"`
+	Plate    string             `json:"plate"`
+	Nickname string             `json:"nickname"`
+	Bookings int                `json:"bookings"`
+}
+
+// MergeVehicles repoints all bookings and PM program attachments of the
+// source units to the unit in the URL, fills the target's blank fields from
+// the sources and moves the sources to the trash. When the units carry
+// different VINs the body must pick one with vin, otherwise the answer is 409
+// with the VINs found. With dry_run (body or ?dry_run=true) nothing is
+// written and the response previews the merged unit.
+func (h *Handler) MergeVehicles(c *fiber.Ctx) error {
+	targetID, err := asObjectID(c.Params("id"))
+	if err != nil {
+		return fiber.ErrBadRequest
+	}
+	var req vehicleMergeRequest
+	if err := c.BodyParser(&req); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "invalid body")
+	}
+	dryRun := req.DryRun || c.QueryBool("dry_run")
+	ids, err := parseObjectIDs(req.SourceIDs)
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "invalid source_ids")
+	}
+	if ids, err = services.MergeSources(targetID, ids); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, err.Error())
+	}
+	var target models.Vehicle
+	if err := h.DB.Collection(vehicleCollection).FindOne(h.ctx(c), notDeleted(bson.M{"_id": targetID})).Decode(&target); err != nil {
+		return fiber.ErrNotFound
+	}
+	cur, err := h.DB.Collection(vehicleCollection).Find(h.ctx(c), notDeleted(bson.M{"_id": bson.M{"$in": ids}}))
+	if err != nil {
+		return fiber.ErrInternalServerError
+	}
+	var sources []models.Vehicle
+	if err := cur.All(h.ctx(c), &sources); err != nil {
+		return fiber.ErrInternalServerError
+	}
+	if len(sources) != len(ids) {
+		return fiber.NewError(fiber.StatusBadRequest, "source unit not found")
+	}
+	merged, err := services.MergeVehicleFields(target, sources, req.VIN)
+	if err != nil {
+		var conflict *services.VINConflictError
+		if errors.As(err, &conflict) {
+			return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+				"error": services.ErrVINConflict.Error(),
+				"vins":  conflict.VINs,
+			})
+		}
+		return fiber.NewError(fiber.StatusBadRequest, err.Error())
+	}
+	if owner, err := h.vinOwner(c, merged.VIN, append(ids, targetID)...); err != nil {
+		return err
+	} else if owner != nil {
+		return vinTakenResponse(c, owner)
+	}
+
+	programs, err := h.repointPMPrograms(c, "vehicle_ids", ids, targetID, "vehicle merged", dryRun)
+	if err != nil {
+		return err
+	}
+	result := make([]vehicleMergeSource, 0, len(sources))
+	moved := 0
+	for _, src := range sources {
+		item := vehicleMergeSource{ID: src.ID, VIN: src.VIN, Plate: src.Plate, Nickname: src.Nickname}
+		filter := notDeleted(bson.M{"vehicle_id": src.ID})
+		if dryRun {
+			count, err := h.DB.Collection(bookingCollection).CountDocuments(h.ctx(c), filter)
+			if err != nil {
+				return fiber.ErrInternalServerError
+			}
+			item.Bookings = int(count)
+		} else {
+			if item.Bookings, err = h.reassignField(c, trashKinds["bookings"], filter, "vehicle_id", src.ID, targetID, "vehicle merged"); err != nil {
+				return err
+			}
+			if err := h.softDelete(c, trashKinds["vehicles"], src.ID, bson.M{"merged_into": targetID}); err != nil {
+				return err
+			}
+		}
+		moved += item.Bookings
+		result = append(result, item)
+	}
+	if !dryRun {
+		now := h.now()
+		changes := vehicleChanges(target, merged)
+		if _, err := h.DB.Collection(vehicleCollection).UpdateByID(h.ctx(c), targetID, bson.M{
+			"$set": bson.M{
+				"type":         merged.Type,
+				"vin":          merged.VIN,
+				"plate":        merged.Plate,
+				"nickname":     merged.Nickname,
+				"make":         merged.Make,
+				"model":        merged.Model,
+				"year":         merged.Year,
+				"length_ft":    merged.LengthFt,
+				"vin_aliases":  merged.VINAliases,
+				"unit_aliases": merged.UnitAliases,
+				"updated_at":   now,
+			},
+			"$inc": bson.M{"version": 1},
+		}); err != nil {
+			return fiber.ErrInternalServerError
+		}
+		merged.Version = target.Version + 1
+		merged.UpdatedAt = now
+		_, _ = h.DB.Collection(auditCollection).InsertOne(h.ctx(c), models.AuditLog{
+			ID:        primitive.NewObjectID(),
+			Action:    "vehicle.merged",
+			Entity:    "vehicle",
+			EntityID:  targetID,
+			UserID:    actorID(c),
+			Meta:      bson.M{"sources": result, "moved": bson.M{"bookings": moved, "pm_programs": programs}, "changes": changes},
+			CreatedAt: now,
+		})
+		if moved > 0 {
+			pushRealtime(models.RealtimeEvent{Type: "booking.updated", Data: fiber.Map{"vehicle_id": targetID}})
+		}
+	}
+	return c.JSON(fiber.Map{
+		"dry_run": dryRun,
+		"vehicle": merged,
+		"sources": result,
+		"totals":  fiber.Map{"bookings": moved, "pm_programs": programs},
+	})
+}
+
+// vehicleChanges lists the fields that differ between two versions of a unit
+// in the audit from/to shape.
+func vehicleChanges(prev, next models.Vehicle) bson.M {
+	changes := bson.M{}
+	if prev.Type != next.Type {
+		changes["type"] = bson.M{"from": prev.Type, "to": next.Type}
+	}
+	if prev.VIN != next.VIN {
+		changes["vin"] = bson.M{"from": prev.VIN, "to": next.VIN}
+	}
+	if prev.Plate != next.Plate {
+		changes["plate"] = bson.M{"from": prev.Plate, "to": next.Plate}
+	}
+	if prev.Nickname != next.Nickname {
+		changes["nickname"] = bson.M{"from": prev.Nickname, "to": next.Nickname}
+	}
+	if prev.Make != next.Make {
+		changes["make"] = bson.M{"from": prev.Make, "to": next.Make}
+	}
+	if prev.Model != next.Model {
+		changes["model"] = bson.M{"from": prev.Model, "to": next.Model}
+	}
+	if prev.Year != next.Year {
+		changes["year"] = bson.M{"from": prev.Year, "to": next.Year}
+	}
+	if prev.LengthFt != next.LengthFt {
+		changes["length_ft"] = bson.M{"from": prev.LengthFt, "to": next.LengthFt}
+	}
+	return changes
+}
+
+// vinOwner returns the live unit other than exclude that already carries
+// vin, or nil. An empty VIN never has an owner.
+func (h *Handler) vinOwner(c *fiber.Ctx, vin string, exclude ...primitive.ObjectID) (*models.Vehicle, error) {
+	if vin == "" {
+		return nil, nil
+	}
+	filter := notDeleted(bson.M{"vin": bson.M{"$regex": "^" + regexp.QuoteMeta(vin) + "$", "$options": "i"}})
+	if len(exclude) > 0 {
+		filter["_id"] = bson.M{"$nin": exclude}
+	}
+	var existing models.Vehicle
+	if err := h.DB.Collection(vehicleCollection).FindOne(h.ctx(c), filter).Decode(&existing); err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, nil
+		}
+		return nil, fiber.ErrInternalServerError
+	}
+	return &existing, nil
+}
+
+// vinTakenResponse answers 409 naming the unit that already has the VIN.
+func vinTakenResponse(c *fiber.Ctx, existing *models.Vehicle) error {
+	return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+		"error":    errVINTaken,
+		"existing": existing,
+	})
+}
+
+// ListDuplicateVehicles groups live units that share a VIN, or a plate or unit
+// number within the same company, as merge candidates. ?company_id= limits the
+// scan to one company's fleet (VIN matches across companies are then missed).
+func (h *Handler) ListDuplicateVehicles(c *fiber.Ctx) error {
+	filter := notDeleted(bson.M{})
+	if cid := c.Query("company_id"); cid != "" {
+		id, err := asObjectID(cid)
+		if err != nil {
+			return fiber.ErrBadRequest
+		}
+		filter["company_id"] = id
+	}
+	cur, err := h.DB.Collection(vehicleCollection).Find(h.ctx(c), filter, options.Find().SetSort(bson.D{{Key: "created_at", Value: 1}}))
+	if err != nil {
+		return fiber.ErrInternalServerError
+	}
+	defer cur.Close(h.ctx(c))
+	var items []models.Vehicle
+	if err := cur.All(h.ctx(c), &items); err != nil {
+		return fiber.ErrInternalServerError
+	}
+	groups := services.FindDuplicateVehicles(items)
+	if groups == nil {
+		groups = []services.DuplicateGroup{}
+	}
+	return c.JSON(groups)
+}
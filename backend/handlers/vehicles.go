@@ -5,6 +5,7 @@ import (
 
 	"github.com/gofiber/fiber/v2"
 	"github.com/tss-booking-system/backend/models"
+	"github.com/tss-booking-system/backend/services"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
@@ -22,13 +23,45 @@ type vehicleRequest struct {
 	Make      string             `json:"make"`
 	Model     string             `json:"model"`
 	Year      int                `json:"year"`
+	LengthFt  int                `json:"length_ft"`
+	Version   *int               `json:"version"`
+	// AllowInvalidVIN lets an admin save a VIN that fails validation, e.g.
+	// from an old title or a non-US manufacturer.
+	AllowInvalidVIN bool `json:"allow_invalid_vin"`
+}
+
+// checkVIN normalises req.VIN and rejects it when it fails validation, unless
+// an admin set allow_invalid_vin. A valid VIN fills an empty make and year.
+// It reports whether an invalid VIN was let through.
+func (h *Handler) checkVIN(c *fiber.Ctx, req *vehicleRequest) (bool, error) {
+	req.VIN = services.NormalizeVIN(req.VIN)
+	if req.VIN == "" {
+		return false, nil
+	}
+	info, err := services.DecodeVIN(req.VIN, h.now())
+	if err != nil {
+		if !req.AllowInvalidVIN {
+			return false, fiber.NewError(fiber.StatusBadRequest, err.Error())
+		}
+		if getRole(c) != models.RoleAdmin {
+			return false, fiber.NewError(fiber.StatusForbidden, "only admins can save an invalid VIN")
+		}
+		return true, nil
+	}
+	if req.Make == "" {
+		req.Make = info.Make
+	}
+	if req.Year == 0 {
+		req.Year = info.ModelYear
+	}
+	return false, nil
 }
 
 func (h *Handler) ListVehicles(c *fiber.Ctx) error {
-	filter := bson.D{}
+	filter := bson.D{{Key: "deleted_at", Value: nil}}
 	if cid := c.Query("company_id"); cid != "" {
 		if id, err := asObjectID(cid); err == nil {
-			filter = bson.D{{Key: "company_id", Value: id}}
+			filter = append(filter, bson.E{Key: "company_id", Value: id})
 		} else {
 			return fiber.ErrBadRequest
 		}
@@ -117,6 +150,7 @@ func (h *Handler) GetVehicle(c *fiber.Ctx) error {
 	if err := h.DB.Collection(vehicleCollection).FindOne(h.ctx(c), bson.M{"_id": id}).Decode(&v); err != nil {
 		return fiber.ErrInternalServerError
 	}
+	setETag(c, v.Version)
 	return c.JSON(v)
 }
 
@@ -130,6 +164,18 @@ func (h *Handler) CreateVehicle(c *fiber.Ctx) error {
 	if err != nil {
 		return fiber.ErrBadRequest
 	}
+	if req.LengthFt < 0 {
+		return fiber.NewError(fiber.StatusBadRequest, "length_ft must not be negative")
+	}
+	overridden, err := h.checkVIN(c, &req)
+	if err != nil {
+		return err
+	}
+	if owner, err := h.vinOwner(c, req.VIN); err != nil {
+		return err
+	} else if owner != nil {
+		return vinTakenResponse(c, owner)
+	}
 
 	now := h.now()
 	item := models.Vehicle{
@@ -142,6 +188,7 @@ func (h *Handler) CreateVehicle(c *fiber.Ctx) error {
 		Make:      req.Make,
 		Model:     req.Model,
 		Year:      req.Year,
+		LengthFt:  req.LengthFt,
 		CreatedAt: now,
 		UpdatedAt: now,
 	}
@@ -156,13 +203,17 @@ func (h *Handler) CreateVehicle(c *fiber.Ctx) error {
 				actor = id
 			}
 		}
+		meta := bson.M{"plate": item.Plate, "vin": item.VIN, "make": item.Make, "model": item.Model, "year": item.Year}
+		if overridden {
+			meta["vin_override"] = true
+		}
 		_, _ = h.DB.Collection(auditCollection).InsertOne(h.ctx(c), models.AuditLog{
 			ID:        primitive.NewObjectID(),
 			Action:    "vehicle.created",
 			Entity:    "vehicle",
 			EntityID:  item.ID,
 			UserID:    actor,
-			Meta:      bson.M{"plate": item.Plate, "vin": item.VIN, "make": item.Make, "model": item.Model, "year": item.Year},
+			Meta:      meta,
 			CreatedAt: now,
 		})
 	}
@@ -182,6 +233,33 @@ func (h *Handler) UpdateVehicle(c *fiber.Ctx) error {
 	if err != nil {
 		return fiber.ErrBadRequest
 	}
+	if req.LengthFt < 0 {
+		return fiber.NewError(fiber.StatusBadRequest, "length_ft must not be negative")
+	}
+	version, checked, err := expectedVersion(c, req.Version)
+	if err != nil {
+		return err
+	}
+	var prev models.Vehicle
+	if err := h.DB.Collection(vehicleCollection).FindOne(h.ctx(c), notDeleted(bson.M{"_id": id})).Decode(&prev); err != nil {
+		if err == mongo.ErrNoDocuments {
+			return fiber.ErrNotFound
+		}
+		return fiber.ErrInternalServerError
+	}
+	overridden, err := h.checkVIN(c, &req)
+	if err != nil {
+		// a VIN saved before validation existed may be kept as it is
+		if req.VIN != services.NormalizeVIN(prev.VIN) {
+			return err
+		}
+		overridden = false
+	}
+	if owner, err := h.vinOwner(c, req.VIN, id); err != nil {
+		return err
+	} else if owner != nil {
+		return vinTakenResponse(c, owner)
+	}
 	update := bson.M{
 		"$set": bson.M{
 			"company_id": companyID,
@@ -192,76 +270,97 @@ func (h *Handler) UpdateVehicle(c *fiber.Ctx) error {
 			"make":       req.Make,
 			"model":      req.Model,
 			"year":       req.Year,
+			"length_ft":  req.LengthFt,
 			"updated_at": h.now(),
 		},
+		"$inc": bson.M{"version": 1},
 	}
-	res, err := h.DB.Collection(vehicleCollection).UpdateByID(h.ctx(c), id, update)
+	filter := notDeleted(bson.M{"_id": id})
+	if checked {
+		filter = versionFilter(id, version)
+	}
+	res, err := h.DB.Collection(vehicleCollection).UpdateOne(h.ctx(c), filter, update)
 	if err != nil {
 		return fiber.ErrInternalServerError
 	}
 	if res.MatchedCount == 0 {
+		if checked {
+			return h.conflictResponse(c, h.staleVersion(c, vehicleCollection, id, &models.Vehicle{}))
+		}
 		return fiber.ErrNotFound
 	}
+	if checked {
+		setETag(c, version+1)
+	}
 	// audit diffs
-	{
-		var prev models.Vehicle
-		if err := h.DB.Collection(vehicleCollection).FindOne(h.ctx(c), bson.M{"_id": id}).Decode(&prev); err == nil {
-			changes := bson.M{}
-			if prev.Plate != req.Plate {
-				changes["plate"] = bson.M{"from": prev.Plate, "to": req.Plate}
-			}
-			if prev.VIN != req.VIN {
-				changes["vin"] = bson.M{"from": prev.VIN, "to": req.VIN}
-			}
-			if prev.Nickname != req.Nickname {
-				changes["nickname"] = bson.M{"from": prev.Nickname, "to": req.Nickname}
-			}
-			if prev.Make != req.Make {
-				changes["make"] = bson.M{"from": prev.Make, "to": req.Make}
-			}
-			if prev.Model != req.Model {
-				changes["model"] = bson.M{"from": prev.Model, "to": req.Model}
-			}
-			if prev.Year != req.Year {
-				changes["year"] = bson.M{"from": prev.Year, "to": req.Year}
-			}
-			if len(changes) > 0 {
-				var actor primitive.ObjectID
-				if uid := getUserID(c); uid != "" {
-					if id2, err := primitive.ObjectIDFromHex(uid); err == nil {
-						actor = id2
-					}
-				}
-				_, _ = h.DB.Collection(auditCollection).InsertOne(h.ctx(c), models.AuditLog{
-					ID:        primitive.NewObjectID(),
-					Action:    "vehicle.updated",
-					Entity:    "vehicle",
-					EntityID:  id,
-					UserID:    actor,
-					Meta:      changes,
-					CreatedAt: h.now(),
-				})
-			}
-		} else if err != mongo.ErrNoDocuments {
-			return fiber.ErrInternalServerError
+	changes := bson.M{}
+	if prev.Plate != req.Plate {
+		changes["plate"] = bson.M{"from": prev.Plate, "to": req.Plate}
+	}
+	if prev.VIN != req.VIN {
+		changes["vin"] = bson.M{"from": prev.VIN, "to": req.VIN}
+	}
+	if prev.Nickname != req.Nickname {
+		changes["nickname"] = bson.M{"from": prev.Nickname, "to": req.Nickname}
+	}
+	if prev.Make != req.Make {
+		changes["make"] = bson.M{"from": prev.Make, "to": req.Make}
+	}
+	if prev.Model != req.Model {
+		changes["model"] = bson.M{"from": prev.Model, "to": req.Model}
+	}
+	if prev.Year != req.Year {
+		changes["year"] = bson.M{"from": prev.Year, "to": req.Year}
+	}
+	if prev.LengthFt != req.LengthFt {
+		changes["length_ft"] = bson.M{"from": prev.LengthFt, "to": req.LengthFt}
+	}
+	if len(changes) > 0 {
+		if overridden {
+			changes["vin_override"] = true
 		}
+		_, _ = h.DB.Collection(auditCollection).InsertOne(h.ctx(c), models.AuditLog{
+			ID:        primitive.NewObjectID(),
+			Action:    "vehicle.updated",
+			Entity:    "vehicle",
+			EntityID:  id,
+			UserID:    actorID(c),
+			Meta:      changes,
+			CreatedAt: h.now(),
+		})
 	}
 	return c.SendStatus(fiber.StatusNoContent)
 }
 
+// DeleteVehicle moves a unit into the trash. Active bookings block the delete
+// unless mode=cascade trashes them too, or mode=reassign&target=<unit> moves
+// them, along with booking history, to another unit.
 func (h *Handler) DeleteVehicle(c *fiber.Ctx) error {
 	id, err := asObjectID(c.Params("id"))
 	if err != nil {
 		return fiber.ErrBadRequest
 	}
-	res, err := h.DB.Collection(vehicleCollection).DeleteOne(h.ctx(c), bson.M{"_id": id})
-	if err != nil {
-		return fiber.ErrInternalServerError
+	deps := []dependent{
+		{"bookings", trashKinds["bookings"], activeBookings(bson.M{"vehicle_id": id})},
 	}
-	if res.DeletedCount == 0 {
-		return fiber.ErrNotFound
-	}
-	return c.SendStatus(fiber.StatusNoContent)
+	return h.deleteWithDependents(c, deletePlan{
+		kind:       trashKinds["vehicles"],
+		id:         id,
+		dependents: deps,
+		cascade: func() (bson.M, error) {
+			return h.cascadeDelete(c, trashKinds["vehicles"], id, deps...)
+		},
+		reassign: func(target primitive.ObjectID) (bson.M, error) {
+			if err := h.requireLive(c, trashKinds["vehicles"], target); err != nil {
+				return nil, err
+			}
+			n, err := h.reassignField(c, trashKinds["bookings"], notDeleted(bson.M{"vehicle_id": id}), "vehicle_id", id, target, "vehicle deleted")
+			if err != nil {
+				return nil, err
+			}
+			return bson.M{"target": target, "reassigned": bson.M{"bookings": n}}, nil
+		},
+	})
 }
 
 // ListVehicleLogs returns audit logs for a vehicle
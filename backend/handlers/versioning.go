@@ -0,0 +1,89 @@
+package handlers
+
+import (
+	"errors"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/tss-booking-system/backend/models"
+	"github.com/tss-booking-system/backend/services"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// staleVersionError is returned when an update was based on an outdated
+// version. conflictResponse renders it as a 409 with the current document.
+type staleVersionError struct {
+	Current interface{}
+}
+
+func (e *staleVersionError) Error() string {
+	return services.ErrStaleVersion.Error()
+}
+
+func (e *staleVersionError) Unwrap() error {
+	return services.ErrStaleVersion
+}
+
+// expectedVersion returns the version an edit is based on: the If-Match
+// header, else the version sent in the body. ok is false when the client
+// sent neither and the update should not be checked.
+func expectedVersion(c *fiber.Ctx, body *int) (int, bool, error) {
+	v, ok, err := services.ParseIfMatch(c.Get(fiber.HeaderIfMatch))
+	if err != nil {
+		return 0, false, fiber.NewError(fiber.StatusBadRequest, err.Error())
+	}
+	if ok {
+		return v, true, nil
+	}
+	if body != nil {
+		return *body, true, nil
+	}
+	return 0, false, nil
+}
+
+// versionFilter matches id at the given version unless it is in the trash.
+// Documents written before versioning have no version field and count as
+// version 0.
+func versionFilter(id primitive.ObjectID, version int) bson.M {
+	if version == 0 {
+		return notDeleted(bson.M{"_id": id, "$or": []bson.M{
+			{"version": 0},
+			{"version": bson.M{"$exists": false}},
+		}})
+	}
+	return notDeleted(bson.M{"_id": id, "version": version})
+}
+
+// staleVersion loads the current document into out and wraps it in a
+// staleVersionError, or returns 404 when the document is gone or deleted.
+func (h *Handler) staleVersion(c *fiber.Ctx, collection string, id primitive.ObjectID, out interface{}) error {
+	if err := h.DB.Collection(collection).FindOne(h.ctx(c), notDeleted(bson.M{"_id": id})).Decode(out); err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return fiber.ErrNotFound
+		}
+		return fiber.ErrInternalServerError
+	}
+	return &staleVersionError{Current: out}
+}
+
+// checkBookingVersions returns the stale version error of the first of items
+// saved since it was loaded, so writes spanning several bookings can check
+// them all before the first one.
+func (h *Handler) checkBookingVersions(c *fiber.Ctx, items ...models.Booking) error {
+	for _, b := range items {
+		n, err := h.DB.Collection(bookingCollection).CountDocuments(h.ctx(c), versionFilter(b.ID, b.Version))
+		if err != nil {
+			return fiber.ErrInternalServerError
+		}
+		if n == 0 {
+			return h.staleVersion(c, bookingCollection, b.ID, &models.Booking{})
+		}
+	}
+	return nil
+}
+
+// setETag exposes the document version for If-Match on the next update.
+func setETag(c *fiber.Ctx, version int) {
+	c.Set(fiber.HeaderETag, services.FormatETag(version))
+}
@@ -0,0 +1,245 @@
+package handlers
+
+import (
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/tss-booking-system/backend/models"
+	"github.com/tss-booking-system/backend/services"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+type waitingListOrderRequest struct {
+	IDs []string `json:"ids"`
+}
+
+type promoteRequest struct {
+	BayID         string     `json:"bay_id"`
+	Start         time.Time  `json:"start"`
+	End           *time.Time `json:"end"`
+	TechnicianIDs *[]string  `json:"technician_ids"`
+}
+
+// trackQueue stamps queued_at when b enters the WaitingList bay and clears the
+// queue fields when it leaves. prev is nil for new bookings.
+func (h *Handler) trackQueue(c *fiber.Ctx, prev *models.Booking, b *models.Booking) {
+	wlID, ok := h.findWaitingListBayID(c)
+	if ok && b.BayID == wlID {
+		if prev == nil || prev.BayID != wlID {
+			now := h.now()
+			b.QueuedAt = &now
+		}
+		return
+	}
+	b.QueuedAt = nil
+	b.QueueOrder = 0
+}
+
+// ReorderWaitingList stores the manual drag order of the waiting list. ids
+// lists waiting bookings top to bottom; bookings not listed keep no manual
+// order and follow the listed ones within their priority.
+func (h *Handler) ReorderWaitingList(c *fiber.Ctx) error {
+	var req waitingListOrderRequest
+	if err := c.BodyParser(&req); err != nil {
+		return fiber.ErrBadRequest
+	}
+	ids, err := parseObjectIDs(req.IDs)
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "invalid ids")
+	}
+	wlID, ok := h.findWaitingListBayID(c)
+	if !ok {
+		return fiber.NewError(fiber.StatusBadRequest, "waiting list bay is not configured")
+	}
+	items, err := h.waitingQueue(c, wlID)
+	if err != nil {
+		return fiber.ErrInternalServerError
+	}
+	order := map[primitive.ObjectID]int{}
+	for i, id := range ids {
+		order[id] = i + 1
+	}
+	for id := range order {
+		found := false
+		for _, b := range items {
+			if b.ID == id {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return fiber.NewError(fiber.StatusBadRequest, "booking "+id.Hex()+" is not on the waiting list")
+		}
+	}
+
+	now := h.now()
+	for i := range items {
+		next := order[items[i].ID]
+		if next == items[i].QueueOrder {
+			continue
+		}
+		if _, err := h.DB.Collection(bookingCollection).UpdateByID(h.ctx(c), items[i].ID, bson.M{
+			"$set": bson.M{"queue_order": next, "updated_at": now},
+			"$inc": bson.M{"version": 1},
+		}); err != nil {
+			return fiber.ErrInternalServerError
+		}
+		_, _ = h.DB.Collection(auditCollection).InsertOne(h.ctx(c), models.AuditLog{
+			ID:        primitive.NewObjectID(),
+			Action:    "booking.queue_reordered",
+			Entity:    "booking",
+			EntityID:  items[i].ID,
+			UserID:    actorID(c),
+			Meta:      bson.M{"queue_order": bson.M{"from": items[i].QueueOrder, "to": next}},
+			CreatedAt: now,
+		})
+		items[i].QueueOrder = next
+		items[i].UpdatedAt = now
+		items[i].Version++
+	}
+	services.SortWaitingQueue(items, now)
+	h.flagOverdue(items)
+	pushRealtime(models.RealtimeEvent{Type: "waitinglist.reordered", Data: items})
+	return c.JSON(items)
+}
+
+// PromoteBooking moves a waiting booking into a bay at the given time after
+// running the usual conflict and working time validation.
+func (h *Handler) PromoteBooking(c *fiber.Ctx) error {
+	id, err := asObjectID(c.Params("id"))
+	if err != nil {
+		return fiber.ErrBadRequest
+	}
+	var req promoteRequest
+	if err := c.BodyParser(&req); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "invalid body")
+	}
+	bayID, err := asObjectID(req.BayID)
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "invalid bay_id")
+	}
+	if req.Start.IsZero() {
+		return fiber.NewError(fiber.StatusBadRequest, "start is required")
+	}
+	if req.End != nil && !req.End.After(req.Start) {
+		return fiber.NewError(fiber.StatusBadRequest, "end must be after start")
+	}
+
+	var existing models.Booking
+	if err := h.DB.Collection(bookingCollection).FindOne(h.ctx(c), notDeleted(bson.M{"_id": id})).Decode(&existing); err != nil {
+		if err == mongo.ErrNoDocuments {
+			return fiber.ErrNotFound
+		}
+		return fiber.ErrInternalServerError
+	}
+	wlID, ok := h.findWaitingListBayID(c)
+	if !ok || existing.BayID != wlID {
+		return fiber.NewError(fiber.StatusConflict, "booking is not on the waiting list")
+	}
+	if existing.Status != models.BookingOpen {
+		return fiber.NewError(fiber.StatusConflict, "only open bookings can be promoted")
+	}
+	if bayID == wlID {
+		return fiber.NewError(fiber.StatusBadRequest, "choose a bay other than the waiting list")
+	}
+	if _, err := h.loadBay(c, bayID); err != nil {
+		return err
+	}
+
+	// Position is taken before the booking leaves the queue
+	queue, err := h.waitingQueue(c, wlID)
+	if err != nil {
+		return fiber.ErrInternalServerError
+	}
+	position := 0
+	for _, q := range queue {
+		if q.ID == existing.ID {
+			position = q.QueuePosition
+		}
+	}
+
+	now := h.now()
+	promoted := existing
+	promoted.BayID = bayID
+	promoted.Start = req.Start.In(h.TZ)
+	promoted.End = req.End
+	if req.TechnicianIDs != nil {
+		techIDs, err := parseObjectIDs(*req.TechnicianIDs)
+		if err != nil {
+			return fiber.NewError(fiber.StatusBadRequest, "invalid technician_ids")
+		}
+		promoted.TechnicianIDs = techIDs
+	}
+	promoted.UpdatedAt = now
+	h.trackQueue(c, &existing, &promoted)
+	unlock, err := h.lockSchedule(c, promoted)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	if err := h.validateBookingSchedule(c, &promoted, nil); err != nil {
+		return h.conflictResponse(c, err)
+	}
+
+	update := bson.M{
+		"$set": bson.M{
+			"bay_id":         promoted.BayID,
+			"start":          promoted.Start,
+			"end":            promoted.End,
+			"technician_ids": promoted.TechnicianIDs,
+			"updated_at":     now,
+		},
+		"$unset": bson.M{"queue_order": "", "queued_at": ""},
+		"$inc":   bson.M{"version": 1},
+	}
+	res, err := h.DB.Collection(bookingCollection).UpdateOne(h.ctx(c), versionFilter(promoted.ID, existing.Version), update)
+	if err != nil {
+		return fiber.ErrInternalServerError
+	}
+	if res.MatchedCount == 0 {
+		return h.conflictResponse(c, h.staleVersion(c, bookingCollection, promoted.ID, &models.Booking{}))
+	}
+	promoted.Version = existing.Version + 1
+	unlock()
+	timeInQueue := int(now.Sub(services.QueuedSince(existing)) / time.Minute)
+	meta := bookingChanges(existing, promoted)
+	meta["queue_position"] = position
+	meta["time_in_queue"] = timeInQueue
+	meta["priority"] = existing.Priority
+	_, _ = h.DB.Collection(auditCollection).InsertOne(h.ctx(c), models.AuditLog{
+		ID:        primitive.NewObjectID(),
+		Action:    "booking.promoted",
+		Entity:    "booking",
+		EntityID:  promoted.ID,
+		UserID:    actorID(c),
+		Meta:      meta,
+		CreatedAt: now,
+	})
+	pushRealtime(models.RealtimeEvent{Type: "booking.promoted", Data: promoted})
+	h.notifyBookingUpdated(c, existing, promoted)
+
+	promoted.QueuePosition = position
+	promoted.TimeInQueue = timeInQueue
+	return c.JSON(promoted)
+}
+
+// waitingQueue returns the active waiting list bookings in queue order.
+func (h *Handler) waitingQueue(c *fiber.Ctx, wlID primitive.ObjectID) ([]models.Booking, error) {
+	cur, err := h.DB.Collection(bookingCollection).Find(h.ctx(c), bson.M{
+		"bay_id":     wlID,
+		"status":     bson.M{"$in": []models.BookingStatus{models.BookingOpen, models.BookingInProgress}},
+		"deleted_at": nil,
+	})
+	if err != nil {
+		return nil, err
+	}
+	defer cur.Close(h.ctx(c))
+	var items []models.Booking
+	if err := cur.All(h.ctx(c), &items); err != nil {
+		return nil, err
+	}
+	services.SortWaitingQueue(items, h.now())
+	return items, nil
+}
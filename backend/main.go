@@ -37,6 +37,18 @@ func main() {
 	if err := seed.EnsureBayIndex(context.Background(), database.DB); err != nil {
 		log.Printf("ensure bay index: %v", err)
 	}
+	if err := seed.EnsureTimeEntryIndexes(context.Background(), database.DB); err != nil {
+		log.Printf("ensure time entry indexes: %v", err)
+	}
+	if err := seed.EnsureIdempotencyIndexes(context.Background(), database.DB); err != nil {
+		log.Printf("ensure idempotency indexes: %v", err)
+	}
+	if err := seed.EnsureScheduleLockIndexes(context.Background(), database.DB); err != nil {
+		log.Printf("ensure schedule lock indexes: %v", err)
+	}
+	if err := seed.EnsureTrashIndexes(context.Background(), database.DB); err != nil {
+		log.Printf("ensure trash indexes: %v", err)
+	}
 	if err := seed.SeedBaysIfEmpty(context.Background(), database.DB, time.Now().In(cfg.Timezone)); err != nil {
 		log.Printf("seed bays: %v", err)
 	}
@@ -71,6 +83,7 @@ func main() {
 
 	app := fiber.New()
 	h := handlers.NewHandler(database.DB, jwtSvc, tgSvc, cfg.Timezone)
+	h.TrashRetention = time.Duration(cfg.TrashRetentionDays) * 24 * time.Hour
 	routes.Register(app, h)
 
 	go func() {
@@ -22,6 +22,15 @@ const (
 	BookingCanceled   BookingStatus = "canceled"
 )
 
+// BookingPriority orders the waiting list; an empty priority means normal.
+type BookingPriority string
+
+const (
+	PriorityUrgent BookingPriority = "urgent"
+	PriorityNormal BookingPriority = "normal"
+	PriorityLow    BookingPriority = "low"
+)
+
 type User struct {
 	ID           primitive.ObjectID `bson:"_id,omitempty" json:"id"`
 	Email        string             `bson:"email" json:"email"`
@@ -32,24 +41,34 @@ type User struct {
 	UpdatedAt    time.Time          `bson:"updated_at" json:"updated_at"`
 }
 
+// Company is a customer. Aliases keeps the names of companies merged into
+// it so imports still resolve them.
 type Company struct {
-	ID        primitive.ObjectID `bson:"_id,omitempty" json:"id"`
-	Name      string             `bson:"name" json:"name"`
-	Contact   string             `bson:"contact" json:"contact"`
-	Phone     string             `bson:"phone" json:"phone"`
-	CreatedAt time.Time          `bson:"created_at" json:"created_at"`
-	UpdatedAt time.Time          `bson:"updated_at" json:"updated_at"`
+	ID        primitive.ObjectID  `bson:"_id,omitempty" json:"id"`
+	Name      string              `bson:"name" json:"name"`
+	Contact   string              `bson:"contact" json:"contact"`
+	Phone     string              `bson:"phone" json:"phone"`
+	Aliases   []string            `bson:"aliases,omitempty" json:"aliases,omitempty"`
+	Version   int                 `bson:"version" json:"version"`
+	CreatedAt time.Time           `bson:"created_at" json:"created_at"`
+	UpdatedAt time.Time           `bson:"updated_at" json:"updated_at"`
+	DeletedAt *time.Time          `bson:"deleted_at,omitempty" json:"deleted_at,omitempty"`
+	DeletedBy *primitive.ObjectID `bson:"deleted_by,omitempty" json:"deleted_by,omitempty"`
+	PurgeAt   *time.Time          `bson:"purge_at,omitempty" json:"purge_at,omitempty"`
 }
 
 // Contact belongs to a company and stores contact person details
 type Contact struct {
-	ID        primitive.ObjectID `bson:"_id,omitempty" json:"id"`
-	CompanyID primitive.ObjectID `bson:"company_id" json:"company_id"`
-	Name      string             `bson:"name" json:"name"`
-	Phone     string             `bson:"phone" json:"phone"`
-	Email     string             `bson:"email,omitempty" json:"email,omitempty"`
-	CreatedAt time.Time          `bson:"created_at" json:"created_at"`
-	UpdatedAt time.Time          `bson:"updated_at" json:"updated_at"`
+	ID        primitive.ObjectID  `bson:"_id,omitempty" json:"id"`
+	CompanyID primitive.ObjectID  `bson:"company_id" json:"company_id"`
+	Name      string              `bson:"name" json:"name"`
+	Phone     string              `bson:"phone" json:"phone"`
+	Email     string              `bson:"email,omitempty" json:"email,omitempty"`
+	CreatedAt time.Time           `bson:"created_at" json:"created_at"`
+	UpdatedAt time.Time           `bson:"updated_at" json:"updated_at"`
+	DeletedAt *time.Time          `bson:"deleted_at,omitempty" json:"deleted_at,omitempty"`
+	DeletedBy *primitive.ObjectID `bson:"deleted_by,omitempty" json:"deleted_by,omitempty"`
+	PurgeAt   *time.Time          `bson:"purge_at,omitempty" json:"purge_at,omitempty"`
 }
 
 type VehicleType string
@@ -59,56 +78,238 @@ const (
 	VehicleTrailer VehicleType = "trailer"
 )
 
+// Vehicle is a customer unit. Odometer (miles) and EngineHours hold the
+// latest meter reading, taken at ReadingAt; the full history lives in the
+// meter_readings collection.
 type Vehicle struct {
-	ID        primitive.ObjectID `bson:"_id,omitempty" json:"id"`
-	CompanyID primitive.ObjectID `bson:"company_id" json:"company_id"`
-	Type      VehicleType        `bson:"type" json:"type"`
-	VIN       string             `bson:"vin" json:"vin"`
-	Plate     string             `bson:"plate" json:"plate"`
-	Nickname  string             `bson:"nickname,omitempty" json:"nickname,omitempty"`
-	Make      string             `bson:"make" json:"make"`
-	Model     string             `bson:"model" json:"model"`
-	Year      int                `bson:"year" json:"year"`
-	CreatedAt time.Time          `bson:"created_at" json:"created_at"`
-	UpdatedAt time.Time          `bson:"updated_at" json:"updated_at"`
+	ID          primitive.ObjectID  `bson:"_id,omitempty" json:"id"`
+	CompanyID   primitive.ObjectID  `bson:"company_id" json:"company_id"`
+	Type        VehicleType         `bson:"type" json:"type"`
+	VIN         string              `bson:"vin" json:"vin"`
+	Plate       string              `bson:"plate" json:"plate"`
+	Nickname    string              `bson:"nickname,omitempty" json:"nickname,omitempty"`
+	Make        string              `bson:"make" json:"make"`
+	Model       string              `bson:"model" json:"model"`
+	Year        int                 `bson:"year" json:"year"`
+	LengthFt    int                 `bson:"length_ft,omitempty" json:"length_ft,omitempty"`
+	VINAliases  []string            `bson:"vin_aliases,omitempty" json:"vin_aliases,omitempty"`
+	UnitAliases []string            `bson:"unit_aliases,omitempty" json:"unit_aliases,omitempty"`
+	Odometer    int                 `bson:"odometer,omitempty" json:"odometer,omitempty"`
+	EngineHours float64             `bson:"engine_hours,omitempty" json:"engine_hours,omitempty"`
+	ReadingAt   *time.Time          `bson:"reading_at,omitempty" json:"reading_at,omitempty"`
+	Version     int                 `bson:"version" json:"version"`
+	CreatedAt   time.Time           `bson:"created_at" json:"created_at"`
+	UpdatedAt   time.Time           `bson:"updated_at" json:"updated_at"`
+	DeletedAt   *time.Time          `bson:"deleted_at,omitempty" json:"deleted_at,omitempty"`
+	DeletedBy   *primitive.ObjectID `bson:"deleted_by,omitempty" json:"deleted_by,omitempty"`
+	PurgeAt     *time.Time          `bson:"purge_at,omitempty" json:"purge_at,omitempty"`
+}
+
+// MeterReading is one odometer (miles) and/or engine hours reading of a unit,
+// taken when a booking is created or started, or entered by hand. Override
+// marks a reading an admin accepted although it is lower than the one before
+// (e.g. after a cluster replacement).
+type MeterReading struct {
+	ID          primitive.ObjectID  `bson:"_id,omitempty" json:"id"`
+	VehicleID   primitive.ObjectID  `bson:"vehicle_id" json:"vehicle_id"`
+	BookingID   *primitive.ObjectID `bson:"booking_id,omitempty" json:"booking_id,omitempty"`
+	Odometer    int                 `bson:"odometer,omitempty" json:"odometer,omitempty"`
+	EngineHours float64             `bson:"engine_hours,omitempty" json:"engine_hours,omitempty"`
+	Source      string              `bson:"source" json:"source"`
+	Override    bool                `bson:"override,omitempty" json:"override,omitempty"`
+	RecordedBy  primitive.ObjectID  `bson:"recorded_by" json:"recorded_by"`
+	RecordedAt  time.Time           `bson:"recorded_at" json:"recorded_at"`
 }
 
+// Technician is a shop technician. Without shifts a technician is
+// considered available whenever the shop is open.
 type Technician struct {
-	ID        primitive.ObjectID `bson:"_id,omitempty" json:"id"`
-	Name      string             `bson:"name" json:"name"`
-	Skills    []string           `bson:"skills" json:"skills"`
-	Phone     string             `bson:"phone" json:"phone"`
-	Email     string             `bson:"email" json:"email"`
-	CreatedAt time.Time          `bson:"created_at" json:"created_at"`
-	UpdatedAt time.Time          `bson:"updated_at" json:"updated_at"`
+	ID        primitive.ObjectID  `bson:"_id,omitempty" json:"id"`
+	Name      string              `bson:"name" json:"name"`
+	Skills    []string            `bson:"skills" json:"skills"`
+	Phone     string              `bson:"phone" json:"phone"`
+	Email     string              `bson:"email" json:"email"`
+	Shifts    []Shift             `bson:"shifts,omitempty" json:"shifts,omitempty"`
+	Version   int                 `bson:"version" json:"version"`
+	CreatedAt time.Time           `bson:"created_at" json:"created_at"`
+	UpdatedAt time.Time           `bson:"updated_at" json:"updated_at"`
+	DeletedAt *time.Time          `bson:"deleted_at,omitempty" json:"deleted_at,omitempty"`
+	DeletedBy *primitive.ObjectID `bson:"deleted_by,omitempty" json:"deleted_by,omitempty"`
+	PurgeAt   *time.Time          `bson:"purge_at,omitempty" json:"purge_at,omitempty"`
+}
+
+// Shift is a weekly working period in shop local time ("HH:MM"). A shift
+// whose end is not after its start runs past midnight.
+type Shift struct {
+	Weekday time.Weekday `bson:"weekday" json:"weekday"`
+	Start   string       `bson:"start" json:"start"`
+	End     string       `bson:"end" json:"end"`
 }
 
+// TimeOff is a period when a technician is unavailable (vacation, sick
+// leave, training).
+type TimeOff struct {
+	ID           primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	TechnicianID primitive.ObjectID `bson:"technician_id" json:"technician_id"`
+	Start        time.Time          `bson:"start" json:"start"`
+	End          time.Time          `bson:"end" json:"end"`
+	Kind         string             `bson:"kind,omitempty" json:"kind,omitempty"`
+	Reason       string             `bson:"reason,omitempty" json:"reason,omitempty"`
+	CreatedBy    primitive.ObjectID `bson:"created_by" json:"created_by"`
+	CreatedAt    time.Time          `bson:"created_at" json:"created_at"`
+}
+
+// Bay is a service position. Capacity is how many units it holds at once
+// (0 means one); DefaultDuration is in minutes. Capabilities are tags such as
+// alignment, body, lift, inside or outside; MaxLengthFt limits the vehicle
+// length (0 means no limit).
 type Bay struct {
+	ID              primitive.ObjectID  `bson:"_id,omitempty" json:"id"`
+	Key             string              `bson:"key" json:"key"`
+	Name            string              `bson:"name" json:"name"`
+	DefaultDuration int                 `bson:"default_duration,omitempty" json:"default_duration,omitempty"`
+	Capacity        int                 `bson:"capacity,omitempty" json:"capacity,omitempty"`
+	Capabilities    []string            `bson:"capabilities,omitempty" json:"capabilities,omitempty"`
+	MaxLengthFt     int                 `bson:"max_length_ft,omitempty" json:"max_length_ft,omitempty"`
+	Version         int                 `bson:"version" json:"version"`
+	CreatedAt       time.Time           `bson:"created_at" json:"created_at"`
+	UpdatedAt       time.Time           `bson:"updated_at" json:"updated_at"`
+	DeletedAt       *time.Time          `bson:"deleted_at,omitempty" json:"deleted_at,omitempty"`
+	DeletedBy       *primitive.ObjectID `bson:"deleted_by,omitempty" json:"deleted_by,omitempty"`
+	PurgeAt         *time.Time          `bson:"purge_at,omitempty" json:"purge_at,omitempty"`
+}
+
+type RecurrenceFrequency string
+
+const (
+	RecurDaily   RecurrenceFrequency = "daily"
+	RecurWeekly  RecurrenceFrequency = "weekly"
+	RecurMonthly RecurrenceFrequency = "monthly"
+)
+
+// RecurrenceRule is an RRULE-style description of a repeating booking.
+// Either Until or Count must be set; Exceptions are skipped occurrence dates.
+type RecurrenceRule struct {
+	Frequency  RecurrenceFrequency `bson:"frequency" json:"frequency"`
+	Interval   int                 `bson:"interval" json:"interval"`
+	Until      *time.Time          `bson:"until,omitempty" json:"until,omitempty"`
+	Count      int                 `bson:"count,omitempty" json:"count,omitempty"`
+	Exceptions []time.Time         `bson:"exceptions,omitempty" json:"exceptions,omitempty"`
+}
+
+// BookingSeries groups the bookings generated from one recurrence rule.
+type BookingSeries struct {
 	ID        primitive.ObjectID `bson:"_id,omitempty" json:"id"`
-	Key       string             `bson:"key" json:"key"`
-	Name      string             `bson:"name" json:"name"`
+	Rule      RecurrenceRule     `bson:"rule" json:"rule"`
+	Start     time.Time          `bson:"start" json:"start"`
+	CreatedBy primitive.ObjectID `bson:"created_by" json:"created_by"`
 	CreatedAt time.Time          `bson:"created_at" json:"created_at"`
 	UpdatedAt time.Time          `bson:"updated_at" json:"updated_at"`
 }
 
+type JobLineStatus string
+
+const (
+	JobPending    JobLineStatus = "pending"
+	JobInProgress JobLineStatus = "in_progress"
+	JobCompleted  JobLineStatus = "completed"
+	JobCanceled   JobLineStatus = "canceled"
+)
+
+// JobLine is one service task inside a booking (3C: complaint, cause,
+// correction). Hours are decimal hours.
+type JobLine struct {
+	ID             primitive.ObjectID   `bson:"_id" json:"id"`
+	Title          string               `bson:"title" json:"title"`
+	Complaint      string               `bson:"complaint,omitempty" json:"complaint,omitempty"`
+	Cause          string               `bson:"cause,omitempty" json:"cause,omitempty"`
+	Correction     string               `bson:"correction,omitempty" json:"correction,omitempty"`
+	TechnicianIDs  []primitive.ObjectID `bson:"technician_ids" json:"technician_ids"`
+	EstimatedHours float64              `bson:"estimated_hours" json:"estimated_hours"`
+	ActualHours    float64              `bson:"actual_hours" json:"actual_hours"`
+	Status         JobLineStatus        `bson:"status" json:"status"`
+	CreatedAt      time.Time            `bson:"created_at" json:"created_at"`
+	UpdatedAt      time.Time            `bson:"updated_at" json:"updated_at"`
+}
+
+// TimeEntry is a technician's labor clock on a booking (optionally a job
+// line). Active is only stored while the clock runs so a unique partial index
+// allows one running clock per technician. Minutes is set when it stops.
+type TimeEntry struct {
+	ID           primitive.ObjectID  `bson:"_id,omitempty" json:"id"`
+	TechnicianID primitive.ObjectID  `bson:"technician_id" json:"technician_id"`
+	BookingID    primitive.ObjectID  `bson:"booking_id" json:"booking_id"`
+	JobID        *primitive.ObjectID `bson:"job_id,omitempty" json:"job_id,omitempty"`
+	Start        time.Time           `bson:"start" json:"start"`
+	End          *time.Time          `bson:"end,omitempty" json:"end,omitempty"`
+	Active       bool                `bson:"active,omitempty" json:"active"`
+	Minutes      int                 `bson:"minutes" json:"minutes"`
+	Notes        string              `bson:"notes,omitempty" json:"notes,omitempty"`
+	CreatedBy    primitive.ObjectID  `bson:"created_by" json:"created_by"`
+	CreatedAt    time.Time           `bson:"created_at" json:"created_at"`
+	UpdatedAt    time.Time           `bson:"updated_at" json:"updated_at"`
+}
+
+// IdempotencyRecord remembers the response to a create request sent with an
+// Idempotency-Key header. ID scopes the key to the user and endpoint;
+// Completed is false while the first request is still running. Records are
+// removed by a TTL index on ExpiresAt.
+type IdempotencyRecord struct {
+	ID          string    `bson:"_id"`
+	Fingerprint string    `bson:"fingerprint"`
+	Completed   bool      `bson:"completed"`
+	StatusCode  int       `bson:"status_code,omitempty"`
+	ContentType string    `bson:"content_type,omitempty"`
+	Body        []byte    `bson:"body,omitempty"`
+	CreatedAt   time.Time `bson:"created_at"`
+	ExpiresAt   time.Time `bson:"expires_at"`
+}
+
+// Booking is a unit's visit to a bay. EstimatedDuration, DefaultDuration (on
+// Bay) and TimeInQueue are in minutes. Overdue, Warnings, QueuePosition and
+// TimeInQueue are computed and never stored. QueueOrder and QueuedAt are only
+// used while the booking waits in the WaitingList bay. Version is bumped on
+// every write; updates may require the version they were based on (If-Match).
+// DeletedAt marks a booking in the trash until it is purged at PurgeAt.
+// Odometer and EngineHours are the unit's meters at check-in.
 type Booking struct {
-	ID               primitive.ObjectID   `bson:"_id,omitempty" json:"id"`
-	Number           string               `bson:"number" json:"number"`
-	Title            string               `bson:"title,omitempty" json:"title,omitempty"`
-	Complaint        string               `bson:"complaint,omitempty" json:"complaint,omitempty"`
-	Description      string               `bson:"description" json:"description"`
-	VehicleID        primitive.ObjectID   `bson:"vehicle_id" json:"vehicle_id"`
-	FullbayServiceID string               `bson:"fullbay_service_id,omitempty" json:"fullbay_service_id,omitempty"`
-	BayID            primitive.ObjectID   `bson:"bay_id" json:"bay_id"`
-	TechnicianIDs    []primitive.ObjectID `bson:"technician_ids" json:"technician_ids"`
-	CompanyID        primitive.ObjectID   `bson:"company_id" json:"company_id"`
-	Start            time.Time            `bson:"start" json:"start"`
-	End              *time.Time           `bson:"end,omitempty" json:"end,omitempty"`
-	Status           BookingStatus        `bson:"status" json:"status"`
-	Notes            string               `bson:"notes" json:"notes"`
-	CreatedBy        primitive.ObjectID   `bson:"created_by" json:"created_by"`
-	CreatedAt        time.Time            `bson:"created_at" json:"created_at"`
-	UpdatedAt        time.Time            `bson:"updated_at" json:"updated_at"`
+	ID                   primitive.ObjectID   `bson:"_id,omitempty" json:"id"`
+	Number               string               `bson:"number" json:"number"`
+	Title                string               `bson:"title,omitempty" json:"title,omitempty"`
+	Complaint            string               `bson:"complaint,omitempty" json:"complaint,omitempty"`
+	Description          string               `bson:"description" json:"description"`
+	VehicleID            primitive.ObjectID   `bson:"vehicle_id" json:"vehicle_id"`
+	FullbayServiceID     string               `bson:"fullbay_service_id,omitempty" json:"fullbay_service_id,omitempty"`
+	BayID                primitive.ObjectID   `bson:"bay_id" json:"bay_id"`
+	TechnicianIDs        []primitive.ObjectID `bson:"technician_ids" json:"technician_ids"`
+	CompanyID            primitive.ObjectID   `bson:"company_id" json:"company_id"`
+	Start                time.Time            `bson:"start" json:"start"`
+	End                  *time.Time           `bson:"end,omitempty" json:"end,omitempty"`
+	Status               BookingStatus        `bson:"status" json:"status"`
+	ActualStart          *time.Time           `bson:"actual_start,omitempty" json:"actual_start,omitempty"`
+	JobType              string               `bson:"job_type,omitempty" json:"job_type,omitempty"`
+	EstimatedDuration    int                  `bson:"estimated_duration,omitempty" json:"estimated_duration,omitempty"`
+	RequiredCapabilities []string             `bson:"required_capabilities,omitempty" json:"required_capabilities,omitempty"`
+	Odometer             int                  `bson:"odometer,omitempty" json:"odometer,omitempty"`
+	EngineHours          float64              `bson:"engine_hours,omitempty" json:"engine_hours,omitempty"`
+	Overdue              bool                 `bson:"-" json:"overdue,omitempty"`
+	Warnings             []string             `bson:"-" json:"warnings,omitempty"`
+	Notes                string               `bson:"notes" json:"notes"`
+	Priority             BookingPriority      `bson:"priority,omitempty" json:"priority,omitempty"`
+	QueueOrder           int                  `bson:"queue_order,omitempty" json:"queue_order,omitempty"`
+	QueuedAt             *time.Time           `bson:"queued_at,omitempty" json:"queued_at,omitempty"`
+	QueuePosition        int                  `bson:"-" json:"queue_position,omitempty"`
+	TimeInQueue          int                  `bson:"-" json:"time_in_queue,omitempty"`
+	Jobs                 []JobLine            `bson:"jobs,omitempty" json:"jobs,omitempty"`
+	SeriesID             *primitive.ObjectID  `bson:"series_id,omitempty" json:"series_id,omitempty"`
+	Occurrence           int                  `bson:"occurrence,omitempty" json:"occurrence,omitempty"`
+	Version              int                  `bson:"version" json:"version"`
+	CreatedBy            primitive.ObjectID   `bson:"created_by" json:"created_by"`
+	CreatedAt            time.Time            `bson:"created_at" json:"created_at"`
+	UpdatedAt            time.Time            `bson:"updated_at" json:"updated_at"`
+	DeletedAt            *time.Time           `bson:"deleted_at,omitempty" json:"deleted_at,omitempty"`
+	DeletedBy            *primitive.ObjectID  `bson:"deleted_by,omitempty" json:"deleted_by,omitempty"`
+	PurgeAt              *time.Time           `bson:"purge_at,omitempty" json:"purge_at,omitempty"`
 }
 
 type AuditLog struct {
@@ -121,14 +322,90 @@ type AuditLog struct {
 	CreatedAt time.Time              `bson:"created_at" json:"created_at"`
 }
 
+// JobType is a configurable kind of work (e.g. PM, DOT inspection) with
+// defaults applied to new bookings.
+type JobType struct {
+	Key               string   `bson:"key" json:"key"`
+	Name              string   `bson:"name" json:"name"`
+	EstimatedDuration int      `bson:"estimated_duration" json:"estimated_duration"`
+	Skills            []string `bson:"skills,omitempty" json:"skills,omitempty"`
+	Capabilities      []string `bson:"capabilities,omitempty" json:"capabilities,omitempty"`
+}
+
 type Settings struct {
 	ID               string    `bson:"_id,omitempty" json:"id"`
 	TelegramToken    string    `bson:"telegram_token" json:"telegram_token"`
 	TelegramChat     string    `bson:"telegram_chat" json:"telegram_chat"`
 	TelegramTemplate string    `bson:"telegram_template" json:"telegram_template"`
+	JobTypes         []JobType `bson:"job_types,omitempty" json:"job_types,omitempty"`
 	UpdatedAt        time.Time `bson:"updated_at" json:"updated_at"`
 }
 
+type CalendarMode string
+
+const (
+	CalendarOff    CalendarMode = "off"
+	CalendarWarn   CalendarMode = "warn"
+	CalendarReject CalendarMode = "reject"
+)
+
+// OpeningHours are the working hours of one weekday as "HH:MM" in shop time.
+type OpeningHours struct {
+	Weekday time.Weekday `bson:"weekday" json:"weekday"`
+	Open    string       `bson:"open" json:"open"`
+	Close   string       `bson:"close" json:"close"`
+	Closed  bool         `bson:"closed" json:"closed"`
+}
+
+// Holiday is a whole day the shop is closed; Date is "YYYY-MM-DD".
+type Holiday struct {
+	Date string `bson:"date" json:"date"`
+	Name string `bson:"name" json:"name"`
+}
+
+// ShopCalendar is stored in the settings collection under _id "calendar".
+// Mode decides whether bookings outside working time are rejected or only
+// produce warnings.
+type ShopCalendar struct {
+	ID          string         `bson:"_id,omitempty" json:"id"`
+	Mode        CalendarMode   `bson:"mode" json:"mode"`
+	WeeklyHours []OpeningHours `bson:"weekly_hours" json:"weekly_hours"`
+	Holidays    []Holiday      `bson:"holidays" json:"holidays"`
+	UpdatedAt   time.Time      `bson:"updated_at" json:"updated_at"`
+}
+
+// BayBlackout blocks a bay for maintenance or calibration.
+type BayBlackout struct {
+	ID        primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	BayID     primitive.ObjectID `bson:"bay_id" json:"bay_id"`
+	Start     time.Time          `bson:"start" json:"start"`
+	End       time.Time          `bson:"end" json:"end"`
+	Reason    string             `bson:"reason" json:"reason"`
+	CreatedBy primitive.ObjectID `bson:"created_by" json:"created_by"`
+	CreatedAt time.Time          `bson:"created_at" json:"created_at"`
+}
+
+// PMProgram is a preventive maintenance schedule such as a 90 day PM or the
+// annual DOT inspection. It applies to the units in VehicleIDs and to every
+// unit of the companies in CompanyIDs; a closed booking with JobType counts
+// as the service. IntervalMiles counts from the odometer read on that
+// booking. At least one of IntervalDays and IntervalMiles is set.
+type PMProgram struct {
+	ID            primitive.ObjectID   `bson:"_id,omitempty" json:"id"`
+	Name          string               `bson:"name" json:"name"`
+	JobType       string               `bson:"job_type" json:"job_type"`
+	IntervalDays  int                  `bson:"interval_days,omitempty" json:"interval_days,omitempty"`
+	IntervalMiles int                  `bson:"interval_miles,omitempty" json:"interval_miles,omitempty"`
+	VehicleIDs    []primitive.ObjectID `bson:"vehicle_ids" json:"vehicle_ids"`
+	CompanyIDs    []primitive.ObjectID `bson:"company_ids" json:"company_ids"`
+	Version       int                  `bson:"version" json:"version"`
+	CreatedAt     time.Time            `bson:"created_at" json:"created_at"`
+	UpdatedAt     time.Time            `bson:"updated_at" json:"updated_at"`
+	DeletedAt     *time.Time           `bson:"deleted_at,omitempty" json:"deleted_at,omitempty"`
+	DeletedBy     *primitive.ObjectID  `bson:"deleted_by,omitempty" json:"deleted_by,omitempty"`
+	PurgeAt       *time.Time           `bson:"purge_at,omitempty" json:"purge_at,omitempty"`
+}
+
 type RealtimeEvent struct {
 	Type string      `json:"type"`
 	Data interface{} `json:"data"`
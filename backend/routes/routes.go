@@ -30,8 +30,9 @@ func Register(app *fiber.App, h *handlers.Handler) {
 	))
 	app.Use(cors.New(cors.Config{
 		AllowOrigins:     "https://bookings.tsstruckservice.com,http://bookings.tsstruckservice.com,http://167.71.168.200,http://localhost:5190,http://localhost:5173,http://127.0.0.1:5173,http://localhost:3000",
-		AllowHeaders:     "Authorization,Content-Type",
-		AllowMethods:     "GET,POST,PUT,DELETE,OPTIONS",
+		AllowHeaders:     "Authorization,Content-Type,Idempotency-Key,If-Match",
+		ExposeHeaders:    "Idempotent-Replayed,ETag",
+		AllowMethods:     "GET,POST,PUT,PATCH,DELETE,OPTIONS",
 		AllowCredentials: true,
 	}))
 
@@ -47,42 +48,60 @@ func Register(app *fiber.App, h *handlers.Handler) {
 	app.Post("/debug/seed-admin", h.SeedAdmin)
 
 	api := app.Group("/api", h.AuthMiddleware(models.RoleAdmin, models.RoleOffice))
+	// Create endpoints replay the stored response for retried Idempotency-Keys
+	idem := h.Idempotency()
 
 	api.Get("/dashboard/summary", h.DashboardSummary)
 
 	api.Get("/technicians", h.ListTechnicians)
+	api.Get("/technicians/availability", h.GetTechnicianAvailability)
+	api.Get("/technicians/suggestions", h.SuggestTechnicians)
 	api.Get("/technicians/:id", h.GetOneTechnician)
-	api.Post("/technicians", h.AuthMiddleware(models.RoleAdmin, models.RoleOffice), h.CreateTechnician)
+	api.Post("/technicians", h.AuthMiddleware(models.RoleAdmin, models.RoleOffice), idem, h.CreateTechnician)
 	api.Put("/technicians/:id", h.AuthMiddleware(models.RoleAdmin, models.RoleOffice), h.UpdateTechnician)
 	api.Delete("/technicians/:id", h.AuthMiddleware(models.RoleAdmin, models.RoleOffice), h.DeleteTechnician)
 	api.Get("/technicians/:id/logs", h.ListTechnicianLogs)
+	api.Get("/technicians/:id/time-off", h.ListTechnicianTimeOff)
+	api.Post("/technicians/:id/time-off", h.AuthMiddleware(models.RoleAdmin, models.RoleOffice), idem, h.CreateTechnicianTimeOff)
+	api.Delete("/technicians/:id/time-off/:timeOffId", h.AuthMiddleware(models.RoleAdmin, models.RoleOffice), h.DeleteTechnicianTimeOff)
 
 	api.Get("/bays", h.ListBays)
 	api.Get("/bays/occupancy", h.ListBayOccupancy)
+	api.Get("/bays/availability", h.GetBayAvailability)
 	api.Get("/bays/:id", h.GetBay)
 	// Bays: only Admin can create/edit/delete
-	api.Post("/bays", h.AuthMiddleware(models.RoleAdmin), h.CreateBay)
+	api.Post("/bays", h.AuthMiddleware(models.RoleAdmin), idem, h.CreateBay)
 	api.Put("/bays/:id", h.AuthMiddleware(models.RoleAdmin), h.UpdateBay)
 	api.Delete("/bays/:id", h.AuthMiddleware(models.RoleAdmin), h.DeleteBay)
 	api.Get("/bays/:id/logs", h.ListBayLogs)
+	api.Get("/bays/:id/blackouts", h.ListBayBlackouts)
+	api.Post("/bays/:id/blackouts", h.AuthMiddleware(models.RoleAdmin), idem, h.CreateBayBlackout)
+	api.Delete("/bays/:id/blackouts/:blackoutId", h.AuthMiddleware(models.RoleAdmin), h.DeleteBayBlackout)
 
 	api.Get("/companies", h.ListCompanies)
+	api.Get("/companies/duplicates", h.ListDuplicateCompanies)
 	api.Get("/companies/:id", h.GetCompany)
-	api.Post("/companies", h.AuthMiddleware(models.RoleAdmin, models.RoleOffice), h.CreateCompany)
+	api.Post("/companies", h.AuthMiddleware(models.RoleAdmin, models.RoleOffice), idem, h.CreateCompany)
 	api.Put("/companies/:id", h.AuthMiddleware(models.RoleAdmin, models.RoleOffice), h.UpdateCompany)
 	api.Delete("/companies/:id", h.AuthMiddleware(models.RoleAdmin, models.RoleOffice), h.DeleteCompany)
 	api.Get("/companies/:id/logs", h.ListCompanyLogs)
+	api.Post("/companies/:id/merge", h.AuthMiddleware(models.RoleAdmin, models.RoleOffice), idem, h.MergeCompanies)
 	// company contacts
 	api.Get("/companies/:id/contacts", h.ListCompanyContacts)
-	api.Post("/companies/:id/contacts", h.AuthMiddleware(models.RoleAdmin, models.RoleOffice), h.CreateCompanyContact)
+	api.Post("/companies/:id/contacts", h.AuthMiddleware(models.RoleAdmin, models.RoleOffice), idem, h.CreateCompanyContact)
 	api.Put("/contacts/:id", h.AuthMiddleware(models.RoleAdmin, models.RoleOffice), h.UpdateContact)
 	api.Delete("/contacts/:id", h.AuthMiddleware(models.RoleAdmin, models.RoleOffice), h.DeleteContact)
 
 	api.Get("/vehicles", h.ListVehicles)
+	api.Get("/vehicles/duplicates", h.ListDuplicateVehicles)
 	api.Get("/vehicles/:id", h.GetVehicle)
-	api.Post("/vehicles", h.AuthMiddleware(models.RoleAdmin, models.RoleOffice), h.CreateVehicle)
+	api.Post("/vehicles", h.AuthMiddleware(models.RoleAdmin, models.RoleOffice), idem, h.CreateVehicle)
 	api.Put("/vehicles/:id", h.AuthMiddleware(models.RoleAdmin, models.RoleOffice), h.UpdateVehicle)
 	api.Delete("/vehicles/:id", h.AuthMiddleware(models.RoleAdmin, models.RoleOffice), h.DeleteVehicle)
+	api.Post("/vehicles/:id/merge", h.AuthMiddleware(models.RoleAdmin, models.RoleOffice), idem, h.MergeVehicles)
+	api.Get("/vehicles/:id/history", h.GetVehicleHistory)
+	api.Get("/vehicles/:id/readings", h.ListVehicleReadings)
+	api.Post("/vehicles/:id/readings", h.AuthMiddleware(models.RoleAdmin, models.RoleOffice), idem, h.CreateVehicleReading)
 	api.Get("/vehicles/:id/logs", h.ListVehicleLogs)
 
 	api.Get("/bookings", h.ListBookings)
@@ -90,18 +109,50 @@ func Register(app *fiber.App, h *handlers.Handler) {
 	// Side panels on calendar view
 	api.Get("/bookings/ready", h.ReadyBookings)
 	api.Get("/bookings/waitinglist", h.WaitingListBookings)
+	api.Put("/bookings/waitinglist/order", h.AuthMiddleware(models.RoleAdmin, models.RoleOffice), h.ReorderWaitingList)
 	api.Get("/bookings/:id", h.GetBooking)
 	// Bookings: only Admin can delete; others allowed for Office
-	api.Post("/bookings", h.AuthMiddleware(models.RoleAdmin, models.RoleOffice), h.CreateBooking)
+	api.Post("/bookings", h.AuthMiddleware(models.RoleAdmin, models.RoleOffice), idem, h.CreateBooking)
 	api.Put("/bookings/:id", h.AuthMiddleware(models.RoleAdmin, models.RoleOffice), h.UpdateBooking)
+	api.Patch("/bookings/:id", h.AuthMiddleware(models.RoleAdmin, models.RoleOffice), h.PatchBooking)
 	api.Put("/bookings/:id/cancel", h.AuthMiddleware(models.RoleAdmin, models.RoleOffice), h.CancelBooking)
 	api.Put("/bookings/:id/close", h.AuthMiddleware(models.RoleAdmin, models.RoleOffice), h.CloseBooking)
+	api.Put("/bookings/:id/start", h.AuthMiddleware(models.RoleAdmin, models.RoleOffice), h.StartBooking)
+	api.Put("/bookings/:id/reopen", h.AuthMiddleware(models.RoleAdmin), h.ReopenBooking)
+	api.Post("/bookings/:id/promote", h.AuthMiddleware(models.RoleAdmin, models.RoleOffice), idem, h.PromoteBooking)
 	api.Delete("/bookings/:id", h.AuthMiddleware(models.RoleAdmin), h.DeleteBooking)
 	api.Get("/bookings/:id/logs", h.ListBookingLogs)
+	api.Get("/bookings/:id/series", h.GetBookingSeries)
+	api.Get("/bookings/:id/suggested-technicians", h.SuggestTechniciansForBooking)
+	api.Get("/bookings/:id/jobs", h.ListBookingJobs)
+	api.Post("/bookings/:id/jobs", h.AuthMiddleware(models.RoleAdmin, models.RoleOffice), idem, h.CreateBookingJob)
+	api.Put("/bookings/:id/jobs/:jobId", h.AuthMiddleware(models.RoleAdmin, models.RoleOffice), h.UpdateBookingJob)
+	api.Delete("/bookings/:id/jobs/:jobId", h.AuthMiddleware(models.RoleAdmin, models.RoleOffice), h.DeleteBookingJob)
+
+	// Labor time tracking
+	api.Get("/time-entries", h.ListTimeEntries)
+	api.Post("/time-entries/start", h.AuthMiddleware(models.RoleAdmin, models.RoleOffice), idem, h.StartTimeEntry)
+	api.Post("/time-entries/:id/stop", h.AuthMiddleware(models.RoleAdmin, models.RoleOffice), h.StopTimeEntry)
+
+	// Preventive maintenance programs and due units
+	api.Get("/pm/programs", h.ListPMPrograms)
+	api.Post("/pm/programs", h.AuthMiddleware(models.RoleAdmin, models.RoleOffice), idem, h.CreatePMProgram)
+	api.Put("/pm/programs/:id", h.AuthMiddleware(models.RoleAdmin, models.RoleOffice), h.UpdatePMProgram)
+	api.Delete("/pm/programs/:id", h.AuthMiddleware(models.RoleAdmin, models.RoleOffice), h.DeletePMProgram)
+	api.Get("/pm/due", h.ListPMDue)
+	api.Post("/pm/due/bookings", h.AuthMiddleware(models.RoleAdmin, models.RoleOffice), idem, h.CreatePMDueBookings)
 
 	api.Get("/settings/telegram", h.AuthMiddleware(models.RoleAdmin), h.GetTelegramSettings)
 	api.Put("/settings/telegram", h.AuthMiddleware(models.RoleAdmin), h.SaveTelegramSettings)
 	api.Get("/settings/telegram/preview", h.AuthMiddleware(models.RoleAdmin), h.PreviewTelegramTemplate)
+	api.Get("/settings/job-types", h.GetJobTypes)
+	api.Put("/settings/job-types", h.AuthMiddleware(models.RoleAdmin), h.SaveJobTypes)
+	api.Get("/settings/calendar", h.GetShopCalendar)
+	api.Put("/settings/calendar", h.AuthMiddleware(models.RoleAdmin), h.SaveShopCalendar)
 	// Global logs (admin only)
 	api.Get("/logs", h.AuthMiddleware(models.RoleAdmin), h.ListAllLogs)
+	// Trash: soft-deleted records (admin only)
+	api.Get("/trash", h.AuthMiddleware(models.RoleAdmin), h.ListTrash)
+	api.Post("/trash/:type/:id/restore", h.AuthMiddleware(models.RoleAdmin), h.RestoreFromTrash)
+	api.Delete("/trash/:type/:id", h.AuthMiddleware(models.RoleAdmin), h.PurgeFromTrash)
 }
@@ -2,6 +2,7 @@ package seed
 
 import (
 	"context"
+	"strings"
 	"time"
 
 	"go.mongodb.org/mongo-driver/bson"
@@ -37,6 +38,19 @@ var bayNames = []string{
 	"OB-5",
 }
 
+// bayCapabilities returns the capability tags seeded for a bay name.
+func bayCapabilities(name string) []string {
+	switch {
+	case name == "Body-Shop":
+		return []string{"body", "inside"}
+	case name == "Alignment-Rack":
+		return []string{"alignment", "inside"}
+	case strings.HasPrefix(name, "OB-"):
+		return []string{"outside"}
+	}
+	return []string{"inside"}
+}
+
 const bayCollection = "bays"
 
 // EnsureBayIndex creates a unique index on bays.key
@@ -58,18 +72,20 @@ func SeedBaysIfEmpty(ctx context.Context, db *mongo.Database, now time.Time) err
 		return nil
 	}
 	type bayDoc struct {
-		Key       string    `bson:"key"`
-		Name      string    `bson:"name"`
-		CreatedAt time.Time `bson:"created_at"`
-		UpdatedAt time.Time `bson:"updated_at"`
+		Key          string    `bson:"key"`
+		Name         string    `bson:"name"`
+		Capabilities []string  `bson:"capabilities"`
+		CreatedAt    time.Time `bson:"created_at"`
+		UpdatedAt    time.Time `bson:"updated_at"`
 	}
 	var docs []interface{}
 	for _, n := range bayNames {
 		docs = append(docs, bayDoc{
-			Key:       n,
-			Name:      n,
-			CreatedAt: now,
-			UpdatedAt: now,
+			Key:          n,
+			Name:         n,
+			Capabilities: bayCapabilities(n),
+			CreatedAt:    now,
+			UpdatedAt:    now,
 		})
 	}
 	_, err = db.Collection(bayCollection).InsertMany(ctx, docs)
@@ -10,6 +10,7 @@ import (
 	"strings"
 	"time"
 
+	"github.com/tss-booking-system/backend/services"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
@@ -61,17 +62,50 @@ func seedCompaniesAndUnits(ctx context.Context, db *mongo.Database, reader *csv.
 		return ""
 	}
 
-	// Cache company name -> id
+	// Cache company name -> id; only the exact name (ignoring case and outer
+	// spaces) matches, look-alike names stay separate until merged by hand
+	companyKey := func(name string) string { return strings.ToUpper(strings.TrimSpace(name)) }
 	companyCache := map[string]primitive.ObjectID{}
-	// Preload existing companies into cache
-	cur, _ := db.Collection(companyCollection).Find(ctx, bson.D{}, options.Find().SetProjection(bson.M{"name": 1}))
+	// Preload live companies into cache; names merged into a company resolve to it
+	cur, _ := db.Collection(companyCollection).Find(ctx, bson.M{"deleted_at": nil}, options.Find().SetProjection(bson.M{"name": 1, "aliases": 1}))
 	for cur != nil && cur.Next(ctx) {
 		var m struct {
-			ID   primitive.ObjectID `bson:"_id"`
-			Name string             `bson:"name"`
+			ID      primitive.ObjectID `bson:"_id"`
+			Name    string             `bson:"name"`
+			Aliases []string           `bson:"aliases"`
 		}
 		if err := cur.Decode(&m); err == nil {
-			companyCache[strings.ToUpper(strings.TrimSpace(m.Name))] = m.ID
+			for _, alias := range m.Aliases {
+				companyCache[companyKey(alias)] = m.ID
+			}
+			companyCache[companyKey(m.Name)] = m.ID
+		}
+	}
+	if cur != nil {
+		cur.Close(ctx)
+	}
+
+	// VINs and unit numbers of units merged away resolve to the unit they were
+	// merged into; rows naming them are already imported
+	vinAliases := map[string]bool{}
+	unitAliases := map[string]bool{}
+	cur, _ = db.Collection(vehicleCollection).Find(ctx, bson.M{"$or": []bson.M{
+		{"vin_aliases.0": bson.M{"$exists": true}},
+		{"unit_aliases.0": bson.M{"$exists": true}},
+	}}, options.Find().SetProjection(bson.M{"company_id": 1, "vin_aliases": 1, "unit_aliases": 1}))
+	for cur != nil && cur.Next(ctx) {
+		var v struct {
+			CompanyID   primitive.ObjectID `bson:"company_id"`
+			VINAliases  []string           `bson:"vin_aliases"`
+			UnitAliases []string           `bson:"unit_aliases"`
+		}
+		if err := cur.Decode(&v); err == nil {
+			for _, alias := range v.VINAliases {
+				vinAliases[alias] = true
+			}
+			for _, alias := range v.UnitAliases {
+				unitAliases[v.CompanyID.Hex()+":"+alias] = true
+			}
 		}
 	}
 	if cur != nil {
@@ -93,15 +127,16 @@ func seedCompaniesAndUnits(ctx context.Context, db *mongo.Database, reader *csv.
 		}
 		customer := get(rec, "customer")
 		unitNum := get(rec, "unit #")
-		vin := get(rec, "vin")
+		vin := services.NormalizeVIN(get(rec, "vin"))
 		yearStr := get(rec, "year")
 		makeModel := get(rec, "make / model")
-		if customer == "" && vin == "" && unitNum == "" {
+		// a row without VIN and unit number cannot be matched to a unit
+		if vin == "" && unitNum == "" {
 			continue
 		}
 
 		// resolve company
-		normName := strings.ToUpper(strings.TrimSpace(customer))
+		normName := companyKey(customer)
 		if normName == "" {
 			normName = "UNKNOWN"
 		}
@@ -123,15 +158,28 @@ func seedCompaniesAndUnits(ctx context.Context, db *mongo.Database, reader *csv.
 			}
 		}
 
+		if vin != "" && vinAliases[vin] || vin == "" && unitAliases[companyID.Hex()+":"+unitNum] {
+			continue
+		}
+
 		// parse year
 		year := 0
 		if y, err := strconv.Atoi(strings.TrimSpace(yearStr)); err == nil {
 			year = y
 		}
-		// split make/model
+		// split make/model; a decodable VIN names the make, which may span
+		// several words ("Western Star 4900"), and fills a missing year
 		make := ""
 		model := ""
-		if mm := strings.TrimSpace(makeModel); mm != "" {
+		info, vinErr := services.DecodeVIN(vin, now)
+		if vinErr == nil && year == 0 {
+			year = info.ModelYear
+		}
+		mm := strings.TrimSpace(makeModel)
+		if vinErr == nil && info.Make != "" && (mm == "" || strings.HasPrefix(strings.ToUpper(mm+" "), strings.ToUpper(info.Make+" "))) {
+			make = info.Make
+			model = strings.TrimSpace(mm[min(len(info.Make), len(mm)):])
+		} else if mm != "" {
 			parts := strings.Fields(mm)
 			if len(parts) > 0 {
 				if len(parts) == 1 {
@@ -149,7 +197,8 @@ func seedCompaniesAndUnits(ctx context.Context, db *mongo.Database, reader *csv.
 			vtype = "trailer"
 		}
 
-		// upsert by VIN if present, otherwise insert by (company_id, nickname, plate)
+		// upsert by VIN if present, otherwise by (company_id, unit #); trashed
+		// units match too so a re-import does not bring back merged duplicates
 		filter := bson.M{}
 		if vin != "" {
 			filter["vin"] = vin
@@ -157,7 +206,6 @@ func seedCompaniesAndUnits(ctx context.Context, db *mongo.Database, reader *csv.
 			filter = bson.M{
 				"company_id": companyID,
 				"nickname":   unitNum,
-				"plate":      "",
 			}
 		}
 		update := bson.M{
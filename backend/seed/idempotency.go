@@ -0,0 +1,20 @@
+package seed
+
+import (
+	"context"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const idempotencyCollection = "idempotency_keys"
+
+// EnsureIdempotencyIndexes expires stored idempotency keys at expires_at.
+func EnsureIdempotencyIndexes(ctx context.Context, db *mongo.Database) error {
+	_, err := db.Collection(idempotencyCollection).Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "expires_at", Value: 1}},
+		Options: options.Index().SetExpireAfterSeconds(0).SetName("ttl_expires_at"),
+	})
+	return err
+}
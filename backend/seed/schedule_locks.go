@@ -0,0 +1,22 @@
+package seed
+
+import (
+	"context"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const scheduleLockCollection = "schedule_locks"
+
+// EnsureScheduleLockIndexes purges schedule leases left behind by crashed
+// requests. Expired leases are also taken over on acquire, so the TTL
+// monitor's delay does not matter.
+func EnsureScheduleLockIndexes(ctx context.Context, db *mongo.Database) error {
+	_, err := db.Collection(scheduleLockCollection).Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "expires_at", Value: 1}},
+		Options: options.Index().SetExpireAfterSeconds(0).SetName("ttl_expires_at"),
+	})
+	return err
+}
@@ -0,0 +1,34 @@
+package seed
+
+import (
+	"context"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const timeEntryCollection = "time_entries"
+
+// EnsureTimeEntryIndexes allows a single running clock per technician and
+// speeds up per-booking and per-technician totals.
+func EnsureTimeEntryIndexes(ctx context.Context, db *mongo.Database) error {
+	_, err := db.Collection(timeEntryCollection).Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{
+			Keys: bson.D{{Key: "technician_id", Value: 1}},
+			Options: options.Index().
+				SetUnique(true).
+				SetPartialFilterExpression(bson.M{"active": true}).
+				SetName("uniq_active_clock_per_technician"),
+		},
+		{
+			Keys:    bson.D{{Key: "booking_id", Value: 1}, {Key: "start", Value: 1}},
+			Options: options.Index().SetName("booking_start"),
+		},
+		{
+			Keys:    bson.D{{Key: "technician_id", Value: 1}, {Key: "start", Value: 1}},
+			Options: options.Index().SetName("technician_start"),
+		},
+	})
+	return err
+}
@@ -0,0 +1,67 @@
+package seed
+
+import (
+	"context"
+
+	"github.com/tss-booking-system/backend/services"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// trashCollections hold records that are soft-deleted into the trash.
+var trashCollections = []string{"bookings", "companies", "contacts", "vehicles", "technicians", "bays", "pm_programs"}
+
+// EnsureTrashIndexes purges soft-deleted records once their purge_at passes.
+// Live records have no purge_at and are never touched by the TTL monitor.
+// Trashed records that are still referenced lose their purge_at first.
+func EnsureTrashIndexes(ctx context.Context, db *mongo.Database) error {
+	if err := keepReferencedTrash(ctx, db); err != nil {
+		return err
+	}
+	for _, name := range trashCollections {
+		if _, err := db.Collection(name).Indexes().CreateOne(ctx, mongo.IndexModel{
+			Keys:    bson.D{{Key: "purge_at", Value: 1}},
+			Options: options.Index().SetExpireAfterSeconds(0).SetName("ttl_purge_at"),
+		}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// keepReferencedTrash clears purge_at on trashed records that live or kept
+// records still point at (see services.TrashReferences), so history never
+// loses its vehicle or company.
+func keepReferencedTrash(ctx context.Context, db *mongo.Database) error {
+	for _, name := range services.ReferencedCollections() {
+		cur, err := db.Collection(name).Find(ctx, bson.M{"purge_at": bson.M{"$ne": nil}}, options.Find().SetProjection(bson.M{"_id": 1}))
+		if err != nil {
+			return err
+		}
+		var docs []struct {
+			ID primitive.ObjectID `bson:"_id"`
+		}
+		err = cur.All(ctx, &docs)
+		cur.Close(ctx)
+		if err != nil {
+			return err
+		}
+		for _, doc := range docs {
+			for _, ref := range services.ReferencesTo(name) {
+				n, err := db.Collection(ref.Collection).CountDocuments(ctx, bson.M{ref.Field: doc.ID, "purge_at": nil}, options.Count().SetLimit(1))
+				if err != nil {
+					return err
+				}
+				if n > 0 {
+					if _, err := db.Collection(name).UpdateByID(ctx, doc.ID, bson.M{"$unset": bson.M{"purge_at": ""}}); err != nil {
+						return err
+					}
+					break
+				}
+			}
+		}
+	}
+	return nil
+}
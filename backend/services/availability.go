@@ -0,0 +1,158 @@
+package services
+
+import (
+	"sort"
+	"time"
+
+	"github.com/tss-booking-system/backend/models"
+)
+
+// Window is a half-open time range [Start, End).
+type Window struct {
+	Start time.Time `json:"start"`
+	End   time.Time `json:"end"`
+}
+
+// Duration returns the length of the window.
+func (w Window) Duration() time.Duration {
+	return w.End.Sub(w.Start)
+}
+
+// BusyWindows converts active bookings into occupied windows that last until
+// HeldUntil, as known at now, so they match what conflict checks refuse.
+func BusyWindows(bookings []models.Booking, now time.Time) []Window {
+	out := make([]Window, 0, len(bookings))
+	for _, b := range bookings {
+		if !isActive(b) {
+			continue
+		}
+		out = append(out, Window{Start: b.Start, End: HeldUntil(b, now)})
+	}
+	return out
+}
+
+// SaturatedWindows returns the times when at least capacity active bookings
+// overlap, i.e. when a bay of that capacity cannot take another booking.
+func SaturatedWindows(bookings []models.Booking, capacity int, now time.Time) []Window {
+	if capacity <= 1 {
+		return MergeWindows(BusyWindows(bookings, now))
+	}
+	type event struct {
+		at    time.Time
+		delta int
+	}
+	var events []event
+	for _, w := range BusyWindows(bookings, now) {
+		events = append(events, event{w.Start, 1}, event{w.End, -1})
+	}
+	sort.Slice(events, func(i, j int) bool {
+		if events[i].at.Equal(events[j].at) {
+			return events[i].delta < events[j].delta
+		}
+		return events[i].at.Before(events[j].at)
+	})
+	var out []Window
+	current := 0
+	var start time.Time
+	for _, e := range events {
+		current += e.delta
+		if e.delta > 0 && current == capacity {
+			start = e.at
+		}
+		if e.delta < 0 && current == capacity-1 && e.at.After(start) {
+			out = append(out, Window{Start: start, End: e.at})
+		}
+	}
+	return MergeWindows(out)
+}
+
+// FreeWindows returns the gaps inside [from, to) that are not covered by any
+// busy window and are at least minDuration long, in chronological order.
+func FreeWindows(from, to time.Time, busy []Window, minDuration time.Duration) []Window {
+	sorted := make([]Window, len(busy))
+	copy(sorted, busy)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Start.Before(sorted[j].Start) })
+
+	var out []Window
+	cursor := from
+	for _, w := range sorted {
+		if !w.End.After(cursor) {
+			continue
+		}
+		if !w.Start.Before(to) {
+			break
+		}
+		if w.Start.After(cursor) {
+			out = appendWindow(out, Window{Start: cursor, End: w.Start}, minDuration)
+		}
+		cursor = w.End
+	}
+	if cursor.Before(to) {
+		out = appendWindow(out, Window{Start: cursor, End: to}, minDuration)
+	}
+	return out
+}
+
+// IntersectWindows returns the parts of a that are also covered by b.
+// Both inputs must be sorted and non-overlapping.
+func IntersectWindows(a, b []Window) []Window {
+	var out []Window
+	i, j := 0, 0
+	for i < len(a) && j < len(b) {
+		start := a[i].Start
+		if b[j].Start.After(start) {
+			start = b[j].Start
+		}
+		end := a[i].End
+		if b[j].End.Before(end) {
+			end = b[j].End
+		}
+		if start.Before(end) {
+			out = append(out, Window{Start: start, End: end})
+		}
+		if a[i].End.Before(b[j].End) {
+			i++
+		} else {
+			j++
+		}
+	}
+	return out
+}
+
+// MergeWindows sorts windows and joins the ones that overlap or touch.
+func MergeWindows(windows []Window) []Window {
+	if len(windows) == 0 {
+		return nil
+	}
+	sorted := make([]Window, len(windows))
+	copy(sorted, windows)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Start.Before(sorted[j].Start) })
+	out := []Window{sorted[0]}
+	for _, w := range sorted[1:] {
+		last := &out[len(out)-1]
+		if !w.Start.After(last.End) {
+			if w.End.After(last.End) {
+				last.End = w.End
+			}
+			continue
+		}
+		out = append(out, w)
+	}
+	return out
+}
+
+// FilterWindows drops windows shorter than minDuration.
+func FilterWindows(windows []Window, minDuration time.Duration) []Window {
+	var out []Window
+	for _, w := range windows {
+		out = appendWindow(out, w, minDuration)
+	}
+	return out
+}
+
+func appendWindow(out []Window, w Window, minDuration time.Duration) []Window {
+	if w.Duration() >= minDuration && w.Duration() > 0 {
+		out = append(out, w)
+	}
+	return out
+}
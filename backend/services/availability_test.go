@@ -0,0 +1,87 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"github.com/tss-booking-system/backend/models"
+)
+
+func TestFreeWindows(t *testing.T) {
+	base := time.Date(2026, 3, 2, 8, 0, 0, 0, time.UTC)
+	at := func(h int) time.Time { return base.Add(time.Duration(h) * time.Hour) }
+	busy := []Window{
+		{Start: at(3), End: at(5)},
+		{Start: at(1), End: at(2)},
+		{Start: at(4), End: at(6)},
+	}
+	got := FreeWindows(at(0), at(9), busy, 90*time.Minute)
+	// 0-1 is too short, 2-3 too short, 6-9 fits
+	if len(got) != 1 || !got[0].Start.Equal(at(6)) || !got[0].End.Equal(at(9)) {
+		t.Fatalf("unexpected free windows: %v", got)
+	}
+
+	all := FreeWindows(at(0), at(9), busy, 0)
+	if len(all) != 3 {
+		t.Fatalf("expected 3 gaps without a minimum duration, got %v", all)
+	}
+}
+
+func TestIntersectWindows(t *testing.T) {
+	base := time.Date(2026, 3, 2, 8, 0, 0, 0, time.UTC)
+	at := func(h int) time.Time { return base.Add(time.Duration(h) * time.Hour) }
+	a := []Window{{Start: at(0), End: at(4)}, {Start: at(6), End: at(10)}}
+	b := MergeWindows([]Window{{Start: at(3), End: at(7)}, {Start: at(2), End: at(3)}})
+	got := IntersectWindows(a, b)
+	if len(got) != 2 || !got[0].Start.Equal(at(2)) || !got[0].End.Equal(at(4)) || !got[1].Start.Equal(at(6)) || !got[1].End.Equal(at(7)) {
+		t.Fatalf("unexpected intersection: %v", got)
+	}
+}
+
+func TestSaturatedWindows(t *testing.T) {
+	base := time.Date(2026, 3, 2, 8, 0, 0, 0, time.UTC)
+	at := func(h int) time.Time { return base.Add(time.Duration(h) * time.Hour) }
+	booking := func(from, to int) models.Booking {
+		end := at(to)
+		return models.Booking{Start: at(from), End: &end, Status: models.BookingOpen}
+	}
+	bookings := []models.Booking{booking(0, 4), booking(2, 6), booking(3, 5), booking(6, 8)}
+
+	got := SaturatedWindows(bookings, 2, base)
+	// two or more units between 2 and 5
+	if len(got) != 1 || !got[0].Start.Equal(at(2)) || !got[0].End.Equal(at(5)) {
+		t.Fatalf("unexpected saturated windows at capacity 2: %v", got)
+	}
+	got = SaturatedWindows(bookings, 3, base)
+	if len(got) != 1 || !got[0].Start.Equal(at(3)) || !got[0].End.Equal(at(4)) {
+		t.Fatalf("unexpected saturated windows at capacity 3: %v", got)
+	}
+	if got := SaturatedWindows(bookings, 4, base); len(got) != 0 {
+		t.Fatalf("expected no saturation at capacity 4, got %v", got)
+	}
+}
+
+func TestBusyWindowsHoldOverdueBooking(t *testing.T) {
+	base := time.Date(2026, 3, 2, 8, 0, 0, 0, time.UTC)
+	started := base.Add(30 * time.Minute)
+	now := base.Add(5 * time.Hour)
+	// open-ended, 2h estimate, started late and still in the bay at now
+	overdue := models.Booking{Start: base, ActualStart: &started, Status: models.BookingInProgress, EstimatedDuration: 120}
+
+	busy := BusyWindows([]models.Booking{overdue}, now)
+	if len(busy) != 1 || !busy[0].End.Equal(now.Add(OverrunGrace)) {
+		t.Fatalf("overdue booking should be busy until now plus grace, got %v", busy)
+	}
+	free := FreeWindows(base, base.Add(8*time.Hour), busy, time.Hour)
+	if len(free) != 1 || !free[0].Start.Equal(now.Add(OverrunGrace)) {
+		t.Fatalf("slot before the overdue booking leaves should not be offered, got %v", free)
+	}
+	plannedEnd := now.Add(time.Hour)
+	planned := models.Booking{Start: now, End: &plannedEnd, Status: models.BookingOpen}
+	if !overlaps(planned, overdue, now) {
+		t.Fatal("conflict check should agree that the slot is taken")
+	}
+	if got := SaturatedWindows([]models.Booking{overdue}, 1, now); len(got) != 1 || !got[0].End.Equal(now.Add(OverrunGrace)) {
+		t.Fatalf("unexpected saturated windows: %v", got)
+	}
+}
@@ -0,0 +1,67 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/tss-booking-system/backend/models"
+)
+
+var (
+	ErrBayMissingCapability = errors.New("bay cannot do this work")
+	ErrVehicleTooLong       = errors.New("vehicle is too long for this bay")
+)
+
+// Well-known bay capability tags. Bays may carry any other tag as well.
+const (
+	CapabilityAlignment = "alignment"
+	CapabilityBody      = "body"
+	CapabilityLift      = "lift"
+	CapabilityInside    = "inside"
+	CapabilityOutside   = "outside"
+)
+
+// NormalizeCapabilities lowercases and trims tags, dropping blanks and
+// duplicates while keeping the original order.
+func NormalizeCapabilities(tags []string) []string {
+	var out []string
+	seen := map[string]bool{}
+	for _, t := range tags {
+		t = strings.ToLower(strings.TrimSpace(t))
+		if t == "" || seen[t] {
+			continue
+		}
+		seen[t] = true
+		out = append(out, t)
+	}
+	return out
+}
+
+// MissingCapabilities returns the required tags the bay does not have.
+func MissingCapabilities(bay models.Bay, required []string) []string {
+	has := map[string]bool{}
+	for _, t := range NormalizeCapabilities(bay.Capabilities) {
+		has[t] = true
+	}
+	var missing []string
+	for _, t := range NormalizeCapabilities(required) {
+		if !has[t] {
+			missing = append(missing, t)
+		}
+	}
+	return missing
+}
+
+// CheckBayCompatibility verifies that the bay has every required capability
+// and can fit a vehicle of the given length. Zero lengths are unknown and
+// never rejected.
+func CheckBayCompatibility(bay models.Bay, required []string, vehicleLengthFt int) error {
+	if missing := MissingCapabilities(bay, required); len(missing) > 0 {
+		return fmt.Errorf("%w: %s lacks %s", ErrBayMissingCapability, bay.Name, strings.Join(missing, ", "))
+	}
+	if bay.MaxLengthFt > 0 && vehicleLengthFt > bay.MaxLengthFt {
+		return fmt.Errorf("%w: %d ft vehicle, %s fits up to %d ft", ErrVehicleTooLong, vehicleLengthFt, bay.Name, bay.MaxLengthFt)
+	}
+	return nil
+}
@@ -0,0 +1,34 @@
+package services
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/tss-booking-system/backend/models"
+)
+
+func TestNormalizeCapabilities(t *testing.T) {
+	got := NormalizeCapabilities([]string{" Alignment", "lift", "", "LIFT", "body "})
+	want := []string{"alignment", "lift", "body"}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("expected %v, got %v", want, got)
+	}
+}
+
+func TestCheckBayCompatibility(t *testing.T) {
+	rack := models.Bay{Name: "Alignment-Rack", Capabilities: []string{"alignment", "Inside"}, MaxLengthFt: 40}
+
+	if err := CheckBayCompatibility(rack, []string{"ALIGNMENT", "inside"}, 35); err != nil {
+		t.Fatalf("expected compatible bay, got %v", err)
+	}
+	if err := CheckBayCompatibility(rack, []string{"alignment", "body"}, 0); !errors.Is(err, ErrBayMissingCapability) {
+		t.Fatalf("expected missing capability, got %v", err)
+	}
+	if err := CheckBayCompatibility(rack, nil, 53); !errors.Is(err, ErrVehicleTooLong) {
+		t.Fatalf("expected vehicle too long, got %v", err)
+	}
+	if err := CheckBayCompatibility(models.Bay{Name: "Yard"}, nil, 75); err != nil {
+		t.Fatalf("bays without a length limit take any vehicle, got %v", err)
+	}
+}
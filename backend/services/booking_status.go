@@ -0,0 +1,81 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/tss-booking-system/backend/models"
+)
+
+var (
+	ErrIllegalTransition = errors.New("illegal booking status transition")
+	ErrAdminOnlyReopen   = errors.New("only admins can reopen bookings")
+	ErrInitialStatus     = errors.New("new bookings must be open")
+)
+
+// bookingTransitions lists the status changes anyone may perform.
+var bookingTransitions = map[models.BookingStatus][]models.BookingStatus{
+	models.BookingOpen:       {models.BookingInProgress, models.BookingCanceled},
+	models.BookingInProgress: {models.BookingClosed},
+}
+
+// reopenTransitions lists the status changes reserved for admins.
+var reopenTransitions = map[models.BookingStatus][]models.BookingStatus{
+	models.BookingClosed:   {models.BookingOpen},
+	models.BookingCanceled: {models.BookingOpen},
+}
+
+// StatusTransitionError describes a rejected status change.
+type StatusTransitionError struct {
+	From models.BookingStatus
+	To   models.BookingStatus
+	Err  error
+}
+
+func (e *StatusTransitionError) Error() string {
+	if e.Err == ErrAdminOnlyReopen {
+		return fmt.Sprintf("%s: %s -> %s", e.Err.Error(), e.From, e.To)
+	}
+	return fmt.Sprintf("cannot change booking status from %s to %s", e.From, e.To)
+}
+
+func (e *StatusTransitionError) Unwrap() error {
+	return e.Err
+}
+
+func containsStatus(list []models.BookingStatus, s models.BookingStatus) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+// ValidateStatusTransition checks a booking status change against the
+// transition table: open -> in_progress -> closed, open -> canceled, and
+// closed/canceled -> open for admins only. Keeping the same status is allowed.
+func ValidateStatusTransition(from, to models.BookingStatus, role models.UserRole) error {
+	if from == to {
+		return nil
+	}
+	if containsStatus(bookingTransitions[from], to) {
+		return nil
+	}
+	if containsStatus(reopenTransitions[from], to) {
+		if role == models.RoleAdmin {
+			return nil
+		}
+		return &StatusTransitionError{From: from, To: to, Err: ErrAdminOnlyReopen}
+	}
+	return &StatusTransitionError{From: from, To: to, Err: ErrIllegalTransition}
+}
+
+// ValidateInitialStatus checks the status of a new booking. Bookings start
+// open; any other status has to be reached through the transition table.
+func ValidateInitialStatus(s models.BookingStatus) error {
+	if s != "" && s != models.BookingOpen {
+		return ErrInitialStatus
+	}
+	return nil
+}
@@ -0,0 +1,51 @@
+package services
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/tss-booking-system/backend/models"
+)
+
+func TestValidateStatusTransition(t *testing.T) {
+	cases := []struct {
+		from, to models.BookingStatus
+		role     models.UserRole
+		want     error
+	}{
+		{models.BookingOpen, models.BookingInProgress, models.RoleOffice, nil},
+		{models.BookingInProgress, models.BookingClosed, models.RoleOffice, nil},
+		{models.BookingOpen, models.BookingCanceled, models.RoleOffice, nil},
+		{models.BookingOpen, models.BookingOpen, models.RoleOffice, nil},
+		{models.BookingCanceled, models.BookingInProgress, models.RoleAdmin, ErrIllegalTransition},
+		{models.BookingInProgress, models.BookingOpen, models.RoleAdmin, ErrIllegalTransition},
+		{models.BookingClosed, models.BookingOpen, models.RoleOffice, ErrAdminOnlyReopen},
+		{models.BookingClosed, models.BookingOpen, models.RoleAdmin, nil},
+		{models.BookingCanceled, models.BookingOpen, models.RoleAdmin, nil},
+	}
+	for _, tc := range cases {
+		err := ValidateStatusTransition(tc.from, tc.to, tc.role)
+		if tc.want == nil {
+			if err != nil {
+				t.Fatalf("%s -> %s (%s): expected allowed, got %v", tc.from, tc.to, tc.role, err)
+			}
+			continue
+		}
+		if !errors.Is(err, tc.want) {
+			t.Fatalf("%s -> %s (%s): expected %v, got %v", tc.from, tc.to, tc.role, tc.want, err)
+		}
+	}
+}
+
+func TestValidateInitialStatus(t *testing.T) {
+	for _, s := range []models.BookingStatus{"", models.BookingOpen} {
+		if err := ValidateInitialStatus(s); err != nil {
+			t.Fatalf("%q: expected allowed, got %v", s, err)
+		}
+	}
+	for _, s := range []models.BookingStatus{models.BookingInProgress, models.BookingClosed, models.BookingCanceled, "done"} {
+		if err := ValidateInitialStatus(s); !errors.Is(err, ErrInitialStatus) {
+			t.Fatalf("%q: expected %v, got %v", s, ErrInitialStatus, err)
+		}
+	}
+}
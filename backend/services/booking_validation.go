@@ -2,51 +2,258 @@ package services
 
 import (
 	"errors"
+	"sort"
 	"time"
 
 	"github.com/tss-booking-system/backend/models"
+	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
 var (
-	ErrBayBusy = errors.New("bay is already booked in this timeframe")
+	ErrBayBusy        = errors.New("bay is already booked in this timeframe")
+	ErrTechnicianBusy = errors.New("technician is already assigned in this timeframe")
 )
 
-func overlaps(newStart time.Time, newEnd *time.Time, otherStart time.Time, otherEnd *time.Time) bool {
-	openEnded := time.Now().Add(365 * 24 * time.Hour)
+// TechnicianConflict describes one technician that is already assigned to an
+// overlapping booking.
+type TechnicianConflict struct {
+	TechnicianID   primitive.ObjectID `json:"technician_id"`
+	TechnicianName string             `json:"technician_name,omitempty"`
+	BookingID      primitive.ObjectID `json:"booking_id"`
+	BookingNumber  string             `json:"booking_number"`
+}
+
+// TechnicianConflictError is returned when one or more technicians collide
+// with other bookings. It unwraps to ErrTechnicianBusy.
+type TechnicianConflictError struct {
+	Conflicts []TechnicianConflict
+}
+
+func (e *TechnicianConflictError) Error() string {
+	return ErrTechnicianBusy.Error()
+}
 
-	effectiveNewEnd := openEnded
-	if newEnd != nil {
-		effectiveNewEnd = *newEnd
+func (e *TechnicianConflictError) Unwrap() error {
+	return ErrTechnicianBusy
+}
+
+// DefaultEstimatedDuration applies to open-ended bookings without an estimate.
+const DefaultEstimatedDuration = 2 * time.Hour
+
+// EstimatedDuration returns the booking's estimate, falling back to
+// DefaultEstimatedDuration.
+func EstimatedDuration(b models.Booking) time.Duration {
+	if b.EstimatedDuration > 0 {
+		return time.Duration(b.EstimatedDuration) * time.Minute
 	}
+	return DefaultEstimatedDuration
+}
 
-	effectiveOtherEnd := openEnded
-	if otherEnd != nil {
-		effectiveOtherEnd = *otherEnd
+// EffectiveEnd returns the end used for scheduling: the explicit end when set,
+// otherwise start plus the estimated duration.
+func EffectiveEnd(b models.Booking) time.Time {
+	if b.End != nil {
+		return *b.End
 	}
+	return b.Start.Add(EstimatedDuration(b))
+}
 
-	if !newStart.Before(effectiveOtherEnd) {
+// IsOverdue reports whether an open/in_progress booking has run past its
+// expected finish: the explicit end, or the (actual) start plus the estimate.
+func IsOverdue(b models.Booking, now time.Time) bool {
+	if !isActive(b) {
 		return false
 	}
-	if !otherStart.Before(effectiveNewEnd) {
+	return now.After(expectedFinish(b))
+}
+
+// expectedFinish is the explicit end, or the (actual) start plus the estimate.
+func expectedFinish(b models.Booking) time.Time {
+	if b.End == nil && b.ActualStart != nil {
+		return b.ActualStart.Add(EstimatedDuration(b))
+	}
+	return EffectiveEnd(b)
+}
+
+// OverrunGrace is how much longer an in-progress booking that has run past
+// its estimate is assumed to need.
+const OverrunGrace = 15 * time.Minute
+
+// HeldUntil returns when b frees its bay and technicians, as known at now.
+// An in-progress booking without an end is expected to finish its estimate
+// after the actual start; once it has run past that it is still in the bay,
+// so it holds it until OverrunGrace after now. Occupancy
+// and conflict checks both use this rule.
+func HeldUntil(b models.Booking, now time.Time) time.Time {
+	if b.End != nil || b.Status != models.BookingInProgress {
+		return EffectiveEnd(b)
+	}
+	end := expectedFinish(b)
+	if now.After(end) {
+		return now.Add(OverrunGrace)
+	}
+	return end
+}
+
+// OccupiesAt reports whether b holds its bay at t, as known at now.
+func OccupiesAt(b models.Booking, t, now time.Time) bool {
+	if !isActive(b) || b.Start.After(t) {
+		return false
+	}
+	return HeldUntil(b, now).After(t)
+}
+
+func overlaps(a, b models.Booking, now time.Time) bool {
+	if !a.Start.Before(HeldUntil(b, now)) {
+		return false
+	}
+	if !b.Start.Before(HeldUntil(a, now)) {
 		return false
 	}
 	return true
 }
 
-// ValidateBookingConflict checks time overlaps for the same bay.
-// Capacity is no longer used; any overlap is considered a conflict.
-func ValidateBookingConflict(newBooking models.Booking, existing []models.Booking, _ int) error {
-	conflicts := 0
+// isActive reports whether a booking still blocks bays and technicians.
+func isActive(b models.Booking) bool {
+	return b.Status != models.BookingCanceled && b.Status != models.BookingClosed
+}
+
+// BayCapacity returns how many bookings the bay can hold at once. Bays
+// without a capacity hold a single unit.
+func BayCapacity(bay models.Bay) int {
+	if bay.Capacity < 1 {
+		return 1
+	}
+	return bay.Capacity
+}
+
+// ValidateBookingConflict checks time overlaps for the same bay, as known at
+// now. Up to capacity bookings may overlap at any moment; capacity below 1
+// means 1.
+func ValidateBookingConflict(newBooking models.Booking, existing []models.Booking, capacity int, now time.Time) error {
+	if capacity < 1 {
+		capacity = 1
+	}
+	var same []models.Booking
 	for _, b := range existing {
-		if b.Status == models.BookingCanceled || b.Status == models.BookingClosed {
+		if isActive(b) && b.BayID == newBooking.BayID && overlaps(newBooking, b, now) {
+			same = append(same, b)
+		}
+	}
+	if len(same) < capacity {
+		return nil
+	}
+	if maxConcurrent(same, newBooking.Start, HeldUntil(newBooking, now), now)+1 > capacity {
+		return ErrBayBusy
+	}
+	return nil
+}
+
+// ScheduleState is what the conflict check of one booking reads from the
+// store: the bookings of its bay and of its technicians, the blackouts of its
+// bay and the bay capacity.
+type ScheduleState struct {
+	BayBookings        []models.Booking
+	TechnicianBookings []models.Booking
+	Blackouts          []models.BayBlackout
+	Capacity           int
+}
+
+// ValidateSchedule checks b against state and the bookings planned earlier in
+// the same request, as known at now. b itself and the ignored bookings (the
+// ones the request moves) are left out of state.
+func ValidateSchedule(b models.Booking, state ScheduleState, planned []models.Booking, now time.Time, ignore ...primitive.ObjectID) error {
+	skip := map[primitive.ObjectID]bool{b.ID: true}
+	for _, id := range ignore {
+		skip[id] = true
+	}
+	without := func(items []models.Booking) []models.Booking {
+		out := make([]models.Booking, 0, len(items)+len(planned))
+		for _, e := range items {
+			if !skip[e.ID] {
+				out = append(out, e)
+			}
+		}
+		return append(out, planned...)
+	}
+	if err := ValidateBookingConflict(b, without(state.BayBookings), state.Capacity, now); err != nil {
+		return err
+	}
+	if err := ValidateBlackouts(b, state.Blackouts); err != nil {
+		return err
+	}
+	return ValidateTechnicianConflict(b, without(state.TechnicianBookings), now)
+}
+
+// maxConcurrent returns the highest number of bookings overlapping at any
+// moment inside [from, to).
+func maxConcurrent(bookings []models.Booking, from, to, now time.Time) int {
+	type event struct {
+		at    time.Time
+		delta int
+	}
+	events := make([]event, 0, 2*len(bookings))
+	for _, b := range bookings {
+		start, end := b.Start, HeldUntil(b, now)
+		if start.Before(from) {
+			start = from
+		}
+		if end.After(to) {
+			end = to
+		}
+		if start.Before(end) {
+			events = append(events, event{start, 1}, event{end, -1})
+		}
+	}
+	// ends sort before starts at the same instant: back-to-back is not an overlap
+	sort.Slice(events, func(i, j int) bool {
+		if events[i].at.Equal(events[j].at) {
+			return events[i].delta < events[j].delta
+		}
+		return events[i].at.Before(events[j].at)
+	})
+	current, peak := 0, 0
+	for _, e := range events {
+		current += e.delta
+		if current > peak {
+			peak = current
+		}
+	}
+	return peak
+}
+
+// ValidateTechnicianConflict checks that none of the technicians assigned to
+// newBooking is assigned to another overlapping open/in_progress booking, as
+// known at now. Bookings with the same ID as newBooking are ignored so updates
+// can pass the full list. On conflict a *TechnicianConflictError is returned.
+func ValidateTechnicianConflict(newBooking models.Booking, existing []models.Booking, now time.Time) error {
+	if len(newBooking.TechnicianIDs) == 0 {
+		return nil
+	}
+	assigned := map[primitive.ObjectID]bool{}
+	for _, t := range newBooking.TechnicianIDs {
+		assigned[t] = true
+	}
+	var conflicts []TechnicianConflict
+	for _, b := range existing {
+		if !isActive(b) || b.ID == newBooking.ID {
+			continue
+		}
+		if !overlaps(newBooking, b, now) {
 			continue
 		}
-		if b.BayID == newBooking.BayID && overlaps(newBooking.Start, newBooking.End, b.Start, b.End) {
-			conflicts++
-			if conflicts >= 1 {
-				return ErrBayBusy
+		for _, t := range b.TechnicianIDs {
+			if assigned[t] {
+				conflicts = append(conflicts, TechnicianConflict{
+					TechnicianID:  t,
+					BookingID:     b.ID,
+					BookingNumber: b.Number,
+				})
 			}
 		}
 	}
+	if len(conflicts) > 0 {
+		return &TechnicianConflictError{Conflicts: conflicts}
+	}
 	return nil
 }
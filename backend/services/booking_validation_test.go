@@ -1,6 +1,7 @@
 package services
 
 import (
+	"errors"
 	"testing"
 	"time"
 
@@ -26,7 +27,7 @@ func TestValidateBookingConflictBay(t *testing.T) {
 		Status: models.BookingOpen,
 	}
 
-	err := ValidateBookingConflict(newBooking, []models.Booking{existing}, 1)
+	err := ValidateBookingConflict(newBooking, []models.Booking{existing}, 1, now)
 	if err != ErrBayBusy {
 		t.Fatalf("expected bay conflict, got %v", err)
 	}
@@ -41,12 +42,135 @@ func TestValidateBookingConflictCapacity(t *testing.T) {
 	}
 	newBooking := models.Booking{BayID: bayID, Start: now.Add(10 * time.Minute), End: nil, Status: models.BookingOpen}
 
-	if err := ValidateBookingConflict(newBooking, existing, 3); err != nil {
+	if err := ValidateBookingConflict(newBooking, existing, 3, now); err != nil {
 		t.Fatalf("expected allowed because capacity=3, got %v", err)
 	}
 
 	existing = append(existing, models.Booking{BayID: bayID, Start: now.Add(2 * time.Minute), End: nil, Status: models.BookingOpen})
-	if err := ValidateBookingConflict(newBooking, existing, 3); err != ErrBayBusy {
+	if err := ValidateBookingConflict(newBooking, existing, 3, now); err != ErrBayBusy {
 		t.Fatalf("expected conflict at capacity=3, got %v", err)
 	}
 }
+
+func TestValidateTechnicianConflict(t *testing.T) {
+	now := time.Now()
+	later := now.Add(2 * time.Hour)
+	techA := primitive.NewObjectID()
+	techB := primitive.NewObjectID()
+	existing := models.Booking{
+		ID:            primitive.NewObjectID(),
+		Number:        "000042",
+		BayID:         primitive.NewObjectID(),
+		TechnicianIDs: []primitive.ObjectID{techA},
+		Start:         now,
+		End:           &later,
+		Status:        models.BookingInProgress,
+	}
+
+	end := now.Add(3 * time.Hour)
+	newBooking := models.Booking{
+		ID:            primitive.NewObjectID(),
+		BayID:         primitive.NewObjectID(),
+		TechnicianIDs: []primitive.ObjectID{techA, techB},
+		Start:         now.Add(time.Hour),
+		End:           &end,
+		Status:        models.BookingOpen,
+	}
+
+	err := ValidateTechnicianConflict(newBooking, []models.Booking{existing}, now)
+	var techErr *TechnicianConflictError
+	if !errors.As(err, &techErr) {
+		t.Fatalf("expected technician conflict, got %v", err)
+	}
+	if !errors.Is(err, ErrTechnicianBusy) {
+		t.Fatalf("expected error to unwrap to ErrTechnicianBusy")
+	}
+	if len(techErr.Conflicts) != 1 || techErr.Conflicts[0].TechnicianID != techA || techErr.Conflicts[0].BookingNumber != "000042" {
+		t.Fatalf("unexpected conflicts: %+v", techErr.Conflicts)
+	}
+
+	// same booking (update) and closed bookings never conflict
+	existing.ID = newBooking.ID
+	if err := ValidateTechnicianConflict(newBooking, []models.Booking{existing}, now); err != nil {
+		t.Fatalf("expected no conflict with itself, got %v", err)
+	}
+	existing.ID = primitive.NewObjectID()
+	existing.Status = models.BookingClosed
+	if err := ValidateTechnicianConflict(newBooking, []models.Booking{existing}, now); err != nil {
+		t.Fatalf("expected closed booking to be ignored, got %v", err)
+	}
+}
+
+func TestValidateBookingConflictOpenEndedUsesEstimate(t *testing.T) {
+	now := time.Now()
+	bayID := primitive.NewObjectID()
+	existing := models.Booking{BayID: bayID, Start: now, EstimatedDuration: 60, Status: models.BookingOpen}
+
+	later := models.Booking{BayID: bayID, Start: now.Add(3 * time.Hour), Status: models.BookingOpen}
+	if err := ValidateBookingConflict(later, []models.Booking{existing}, 1, now); err != nil {
+		t.Fatalf("expected open-ended booking to end after its estimate, got %v", err)
+	}
+
+	inside := models.Booking{BayID: bayID, Start: now.Add(30 * time.Minute), Status: models.BookingOpen}
+	if err := ValidateBookingConflict(inside, []models.Booking{existing}, 1, now); err != ErrBayBusy {
+		t.Fatalf("expected conflict within estimate, got %v", err)
+	}
+
+	// without an estimate the default duration applies
+	existing.EstimatedDuration = 0
+	if got := EffectiveEnd(existing); !got.Equal(now.Add(DefaultEstimatedDuration)) {
+		t.Fatalf("expected default estimate, got %v", got)
+	}
+}
+
+func TestIsOverdue(t *testing.T) {
+	now := time.Now()
+	started := now.Add(-3 * time.Hour)
+	b := models.Booking{Start: now.Add(-4 * time.Hour), ActualStart: &started, EstimatedDuration: 120, Status: models.BookingInProgress}
+	if !IsOverdue(b, now) {
+		t.Fatalf("expected booking past its estimate to be overdue")
+	}
+	if !OccupiesAt(b, now, now) {
+		t.Fatalf("expected overdue in-progress booking to keep its bay")
+	}
+
+	b.EstimatedDuration = 240
+	if IsOverdue(b, now) {
+		t.Fatalf("expected booking within its estimate not to be overdue")
+	}
+
+	b.EstimatedDuration = 60
+	b.Status = models.BookingClosed
+	if IsOverdue(b, now) || OccupiesAt(b, now, now) {
+		t.Fatalf("expected closed booking to be neither overdue nor occupying")
+	}
+}
+
+func TestOverdueBookingHoldsBay(t *testing.T) {
+	now := time.Date(2026, 3, 10, 14, 0, 0, 0, time.UTC)
+	bayID := primitive.NewObjectID()
+	started := now.Add(-3 * time.Hour)
+	overdue := models.Booking{ID: primitive.NewObjectID(), BayID: bayID, Start: started, ActualStart: &started, EstimatedDuration: 60, Status: models.BookingInProgress}
+
+	// occupancy and conflict checks agree: the unit is still in the bay
+	walkIn := models.Booking{BayID: bayID, Start: now, EstimatedDuration: 60, Status: models.BookingOpen}
+	if !OccupiesAt(overdue, now, now) {
+		t.Fatalf("expected the overdue booking to occupy its bay now")
+	}
+	if err := ValidateBookingConflict(walkIn, []models.Booking{overdue}, 1, now); err != ErrBayBusy {
+		t.Fatalf("expected a booking starting now to conflict, got %v", err)
+	}
+
+	later := now.Add(OverrunGrace)
+	if OccupiesAt(overdue, later, now) {
+		t.Fatalf("expected the bay to be free after the grace period")
+	}
+	walkIn.Start = later
+	if err := ValidateBookingConflict(walkIn, []models.Booking{overdue}, 1, now); err != nil {
+		t.Fatalf("expected a booking after the grace period to fit, got %v", err)
+	}
+
+	if got := HeldUntil(overdue, started); !got.Equal(EffectiveEnd(overdue)) {
+		t.Fatalf("expected the estimate to apply before the booking is overdue, got %v", got)
+	}
+}
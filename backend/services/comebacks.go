@@ -0,0 +1,127 @@
+package services
+
+import (
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/tss-booking-system/backend/models"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// DefaultComebackDays is how soon after a closed visit a unit returning with
+// a similar complaint counts as a comeback.
+const DefaultComebackDays = 30
+
+// comebackSimilarity is the share of complaint words two visits must have in
+// common to count as the same problem.
+const comebackSimilarity = 0.3
+
+// complaintStopWords are words that say nothing about the problem itself.
+var complaintStopWords = map[string]bool{
+	"THE": true, "AND": true, "FOR": true, "WITH": true, "NOT": true, "FROM": true,
+	"UNIT": true, "TRUCK": true, "TRAILER": true, "CHECK": true, "PLEASE": true,
+	"CUSTOMER": true, "STATES": true, "SAYS": true, "NEEDS": true, "NEED": true,
+	"HAS": true, "WAS": true, "ARE": true, "WHEN": true, "THAT": true, "THIS": true,
+}
+
+// complaintWords returns the meaningful words of a booking's complaint,
+// including those of its job lines; the title stands in when no complaint was
+// written.
+func complaintWords(b models.Booking) map[string]bool {
+	parts := []string{b.Complaint}
+	for _, j := range b.Jobs {
+		parts = append(parts, j.Complaint)
+	}
+	text := strings.Join(parts, " ")
+	if strings.TrimSpace(text) == "" {
+		text = b.Title
+	}
+	words := map[string]bool{}
+	for _, w := range strings.FieldsFunc(strings.ToUpper(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	}) {
+		if len(w) >= 3 && !complaintStopWords[w] {
+			words[w] = true
+		}
+	}
+	return words
+}
+
+// ComplaintSimilarity is the Jaccard similarity (0..1) of the complaint words
+// of two bookings.
+func ComplaintSimilarity(a, b models.Booking) float64 {
+	wa, wb := complaintWords(a), complaintWords(b)
+	if len(wa) == 0 || len(wb) == 0 {
+		return 0
+	}
+	shared := 0
+	for w := range wa {
+		if wb[w] {
+			shared++
+		}
+	}
+	return float64(shared) / float64(len(wa)+len(wb)-shared)
+}
+
+// Comeback is a booking for a unit that returned with a similar complaint
+// within the comeback window after Previous was closed.
+type Comeback struct {
+	BookingID  primitive.ObjectID `json:"booking_id"`
+	PreviousID primitive.ObjectID `json:"previous_id"`
+	VehicleID  primitive.ObjectID `json:"vehicle_id"`
+	Start      time.Time          `json:"start"`
+	Days       int                `json:"days"`
+	Similarity float64            `json:"similarity"`
+}
+
+// bookingFinished is when a closed booking was done: its end, else its start.
+func bookingFinished(b models.Booking) time.Time {
+	if b.End != nil {
+		return *b.End
+	}
+	return b.Start
+}
+
+// FindComebacks flags bookings that started within window after a closed
+// booking of the same unit with a similar complaint. Each comeback points at
+// the most recent such visit. Canceled bookings are ignored; the input may
+// hold several units in any order.
+func FindComebacks(bookings []models.Booking, window time.Duration) []Comeback {
+	byVehicle := map[primitive.ObjectID][]models.Booking{}
+	for _, b := range bookings {
+		if b.Status != models.BookingCanceled && !b.VehicleID.IsZero() {
+			byVehicle[b.VehicleID] = append(byVehicle[b.VehicleID], b)
+		}
+	}
+	var out []Comeback
+	for vehicleID, visits := range byVehicle {
+		sort.SliceStable(visits, func(i, j int) bool { return visits[i].Start.Before(visits[j].Start) })
+		for i, b := range visits {
+			for k := i - 1; k >= 0; k-- {
+				prev := visits[k]
+				if prev.Status != models.BookingClosed {
+					continue
+				}
+				gap := b.Start.Sub(bookingFinished(prev))
+				if gap < 0 || gap > window {
+					continue
+				}
+				if sim := ComplaintSimilarity(prev, b); sim >= comebackSimilarity {
+					out = append(out, Comeback{
+						BookingID:  b.ID,
+						PreviousID: prev.ID,
+						VehicleID:  vehicleID,
+						Start:      b.Start,
+						Days:       int(gap.Hours() / 24),
+						Similarity: sim,
+					})
+					break
+				}
+			}
+		}
+	}
+	sort.SliceStable(out, func(i, j int) bool { return out[i].Start.Before(out[j].Start) })
+	return out
+}
@@ -0,0 +1,51 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"github.com/tss-booking-system/backend/models"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestComplaintSimilarity(t *testing.T) {
+	a := models.Booking{Complaint: "Customer states check engine light on, low power"}
+	b := models.Booking{Jobs: []models.JobLine{{Complaint: "check engine light is ON again"}}}
+	if sim := ComplaintSimilarity(a, b); sim < comebackSimilarity {
+		t.Fatalf("expected similar complaints, got %.2f", sim)
+	}
+	c := models.Booking{Title: "Trailer PM service"}
+	if sim := ComplaintSimilarity(a, c); sim != 0 {
+		t.Fatalf("expected unrelated complaints, got %.2f", sim)
+	}
+	if sim := ComplaintSimilarity(a, models.Booking{}); sim != 0 {
+		t.Fatalf("an empty complaint matches nothing, got %.2f", sim)
+	}
+}
+
+func TestFindComebacks(t *testing.T) {
+	unit, other := primitive.NewObjectID(), primitive.NewObjectID()
+	day := func(d int) time.Time { return time.Date(2026, 3, 1, 8, 0, 0, 0, time.UTC).AddDate(0, 0, d) }
+	end := func(d int) *time.Time { e := day(d).Add(4 * time.Hour); return &e }
+	first := models.Booking{ID: primitive.NewObjectID(), VehicleID: unit, Status: models.BookingClosed, Start: day(0), End: end(0), Complaint: "Air leak at brake chamber"}
+	pm := models.Booking{ID: primitive.NewObjectID(), VehicleID: unit, Status: models.BookingClosed, Start: day(5), End: end(5), Complaint: "PM service"}
+	again := models.Booking{ID: primitive.NewObjectID(), VehicleID: unit, Status: models.BookingOpen, Start: day(12), Complaint: "brake chamber air leak again"}
+	late := models.Booking{ID: primitive.NewObjectID(), VehicleID: unit, Status: models.BookingOpen, Start: day(80), Complaint: "PM service"}
+	canceled := models.Booking{ID: primitive.NewObjectID(), VehicleID: unit, Status: models.BookingCanceled, Start: day(3), Complaint: "air leak brake chamber"}
+	otherUnit := models.Booking{ID: primitive.NewObjectID(), VehicleID: other, Status: models.BookingOpen, Start: day(2), Complaint: "air leak brake chamber"}
+
+	got := FindComebacks([]models.Booking{late, again, otherUnit, canceled, pm, first}, 30*24*time.Hour)
+	if len(got) != 1 {
+		t.Fatalf("expected 1 comeback, got %+v", got)
+	}
+	cb := got[0]
+	if cb.BookingID != again.ID || cb.PreviousID != first.ID || cb.VehicleID != unit || cb.Days != 11 {
+		t.Fatalf("unexpected comeback %+v", cb)
+	}
+
+	// a visit that is still open is not a repair that came back
+	first.Status = models.BookingInProgress
+	if got := FindComebacks([]models.Booking{first, again}, 30*24*time.Hour); len(got) != 0 {
+		t.Fatalf("expected no comeback after an open visit, got %+v", got)
+	}
+}
@@ -0,0 +1,64 @@
+package services
+
+import (
+	"errors"
+	"strings"
+	"unicode"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+var (
+	ErrMergeNoSources = errors.New("source_ids must list at least one record")
+	ErrMergeIntoSelf  = errors.New("a record cannot be merged into itself")
+)
+
+// companySuffixes are legal-form words that do not tell companies apart.
+var companySuffixes = map[string]bool{
+	"LLC": true, "INC": true, "CORP": true, "CORPORATION": true, "CO": true,
+	"COMPANY": true, "LTD": true, "LP": true, "LLP": true, "PC": true,
+}
+
+// CompanyNameKey normalises a company name for duplicate detection: case,
+// punctuation, extra spaces and trailing legal forms are ignored, so
+// "ABC Trucking LLC" and "ABC TRUCKING, L.L.C." share a key.
+func CompanyNameKey(name string) string {
+	var sb strings.Builder
+	for _, r := range strings.ToUpper(name) {
+		switch {
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+			sb.WriteRune(r)
+		case r == '&':
+			sb.WriteString(" AND ")
+		case r == '.' || r == '\'':
+			// "L.L.C." and "O'Neil" keep their letters together
+		default:
+			sb.WriteRune(' ')
+		}
+	}
+	words := strings.Fields(sb.String())
+	for len(words) > 1 && companySuffixes[words[len(words)-1]] {
+		words = words[:len(words)-1]
+	}
+	return strings.Join(words, " ")
+}
+
+// MergeSources validates the records to merge into target and drops
+// repeated ids.
+func MergeSources(target primitive.ObjectID, sources []primitive.ObjectID) ([]primitive.ObjectID, error) {
+	out := make([]primitive.ObjectID, 0, len(sources))
+	seen := map[primitive.ObjectID]bool{}
+	for _, id := range sources {
+		if id == target {
+			return nil, ErrMergeIntoSelf
+		}
+		if !seen[id] {
+			seen[id] = true
+			out = append(out, id)
+		}
+	}
+	if len(out) == 0 {
+		return nil, ErrMergeNoSources
+	}
+	return out, nil
+}
@@ -0,0 +1,41 @@
+package services
+
+import (
+	"errors"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestCompanyNameKey(t *testing.T) {
+	key := CompanyNameKey("ABC Trucking LLC")
+	for _, name := range []string{"ABC TRUCKING, LLC", "  abc trucking l.l.c. ", "ABC Trucking, Inc."} {
+		if got := CompanyNameKey(name); got != key {
+			t.Fatalf("CompanyNameKey(%q) = %q, want %q", name, got, key)
+		}
+	}
+	if CompanyNameKey("ABC Transport LLC") == key {
+		t.Fatal("different companies must not share a key")
+	}
+	if got := CompanyNameKey("LLC"); got != "LLC" {
+		t.Fatalf("a bare legal form is kept as the name, got %q", got)
+	}
+	if got := CompanyNameKey("Smith & Sons Co"); got != "SMITH AND SONS" {
+		t.Fatalf("unexpected key %q", got)
+	}
+}
+
+func TestMergeSources(t *testing.T) {
+	target, a, b := primitive.NewObjectID(), primitive.NewObjectID(), primitive.NewObjectID()
+
+	got, err := MergeSources(target, []primitive.ObjectID{a, b, a})
+	if err != nil || len(got) != 2 || got[0] != a || got[1] != b {
+		t.Fatalf("expected [a b], got %v, %v", got, err)
+	}
+	if _, err := MergeSources(target, []primitive.ObjectID{a, target}); !errors.Is(err, ErrMergeIntoSelf) {
+		t.Fatalf("expected ErrMergeIntoSelf, got %v", err)
+	}
+	if _, err := MergeSources(target, nil); !errors.Is(err, ErrMergeNoSources) {
+		t.Fatalf("expected ErrMergeNoSources, got %v", err)
+	}
+}
@@ -0,0 +1,111 @@
+package services
+
+import (
+	"errors"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+var (
+	ErrHasDependents         = errors.New("record is still referenced; delete with mode=cascade or mode=reassign")
+	ErrInvalidDeleteMode     = errors.New("mode must be cascade or reassign")
+	ErrInvalidReassignTarget = errors.New("target must be another existing record")
+)
+
+// DeleteMode says what happens to the records that reference a deleted one.
+type DeleteMode string
+
+const (
+	// DeleteRestrict refuses the delete while dependents exist.
+	DeleteRestrict DeleteMode = ""
+	// DeleteCascade deletes (or, for technicians, unassigns) the dependents.
+	DeleteCascade DeleteMode = "cascade"
+	// DeleteReassign points the dependents at another record.
+	DeleteReassign DeleteMode = "reassign"
+)
+
+// ParseDeleteMode reads the mode query parameter of a delete request.
+func ParseDeleteMode(v string) (DeleteMode, error) {
+	switch m := DeleteMode(strings.ToLower(strings.TrimSpace(v))); m {
+	case DeleteRestrict, DeleteCascade, DeleteReassign:
+		return m, nil
+	}
+	return "", ErrInvalidDeleteMode
+}
+
+// Dependents counts the live records of each kind that reference a record.
+type Dependents map[string]int64
+
+// Total returns the number of dependents of all kinds.
+func (d Dependents) Total() int64 {
+	var n int64
+	for _, v := range d {
+		n += v
+	}
+	return n
+}
+
+// ReplaceTechnician swaps from for to in ids without creating duplicates.
+// A zero to removes from.
+func ReplaceTechnician(ids []primitive.ObjectID, from, to primitive.ObjectID) []primitive.ObjectID {
+	out := make([]primitive.ObjectID, 0, len(ids))
+	seen := map[primitive.ObjectID]bool{}
+	for _, id := range ids {
+		if id == from {
+			id = to
+		}
+		if id.IsZero() || seen[id] {
+			continue
+		}
+		seen[id] = true
+		out = append(out, id)
+	}
+	return out
+}
+
+// TrashReference is a field of Collection pointing at records of Target.
+type TrashReference struct {
+	Target     string
+	Collection string
+	Field      string
+}
+
+// TrashReferences lists what still points at a record after it is deleted:
+// closed bookings keep their vehicle, company, bay and technicians as history,
+// and units keep their company. A trashed record referenced by something that
+// is never purged stays in the trash without purge_at, so the TTL monitor
+// does not remove what history points to. Vehicles come before companies so a
+// sweep in this order keeps the company of a unit it has just kept.
+var TrashReferences = []TrashReference{
+	{Target: "vehicles", Collection: "bookings", Field: "vehicle_id"},
+	{Target: "companies", Collection: "bookings", Field: "company_id"},
+	{Target: "companies", Collection: "vehicles", Field: "company_id"},
+	{Target: "bays", Collection: "bookings", Field: "bay_id"},
+	{Target: "technicians", Collection: "bookings", Field: "technician_ids"},
+}
+
+// ReferencesTo returns the entries of TrashReferences pointing at target.
+func ReferencesTo(target string) []TrashReference {
+	var out []TrashReference
+	for _, ref := range TrashReferences {
+		if ref.Target == target {
+			out = append(out, ref)
+		}
+	}
+	return out
+}
+
+// ReferencedCollections returns the targets of TrashReferences once each, in
+// table order.
+func ReferencedCollections() []string {
+	var out []string
+	seen := map[string]bool{}
+	for _, ref := range TrashReferences {
+		if !seen[ref.Target] {
+			seen[ref.Target] = true
+			out = append(out, ref.Target)
+		}
+	}
+	return out
+}
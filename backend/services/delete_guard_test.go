@@ -0,0 +1,57 @@
+package services
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestParseDeleteMode(t *testing.T) {
+	for in, want := range map[string]DeleteMode{"": DeleteRestrict, "cascade": DeleteCascade, " Reassign ": DeleteReassign} {
+		got, err := ParseDeleteMode(in)
+		if err != nil || got != want {
+			t.Fatalf("ParseDeleteMode(%q) = %q, %v; want %q", in, got, err, want)
+		}
+	}
+	if _, err := ParseDeleteMode("force"); !errors.Is(err, ErrInvalidDeleteMode) {
+		t.Fatalf("expected invalid mode, got %v", err)
+	}
+}
+
+func TestDependentsTotal(t *testing.T) {
+	if n := (Dependents{"contacts": 2, "vehicles": 0, "bookings": 3}).Total(); n != 5 {
+		t.Fatalf("expected 5 dependents, got %d", n)
+	}
+}
+
+func TestReplaceTechnician(t *testing.T) {
+	a, b, c := primitive.NewObjectID(), primitive.NewObjectID(), primitive.NewObjectID()
+
+	if got := ReplaceTechnician([]primitive.ObjectID{a, b}, a, c); !reflect.DeepEqual(got, []primitive.ObjectID{c, b}) {
+		t.Fatalf("expected a replaced by c in place, got %v", got)
+	}
+	if got := ReplaceTechnician([]primitive.ObjectID{a, b}, a, b); !reflect.DeepEqual(got, []primitive.ObjectID{b}) {
+		t.Fatalf("expected no duplicate when target is already assigned, got %v", got)
+	}
+	if got := ReplaceTechnician([]primitive.ObjectID{a, b}, b, primitive.NilObjectID); !reflect.DeepEqual(got, []primitive.ObjectID{a}) {
+		t.Fatalf("expected b removed, got %v", got)
+	}
+	if got := ReplaceTechnician(nil, a, c); got == nil || len(got) != 0 {
+		t.Fatalf("expected an empty non-nil list, got %#v", got)
+	}
+}
+
+func TestTrashReferences(t *testing.T) {
+	if got := ReferencesTo("companies"); len(got) != 2 || got[0].Collection != "bookings" || got[1].Collection != "vehicles" {
+		t.Fatalf("unexpected references to companies: %v", got)
+	}
+	if got := ReferencesTo("contacts"); len(got) != 0 {
+		t.Fatalf("expected nothing to keep contacts, got %v", got)
+	}
+	want := []string{"vehicles", "companies", "bays", "technicians"}
+	if got := ReferencedCollections(); !reflect.DeepEqual(got, want) {
+		t.Fatalf("expected %v, got %v", want, got)
+	}
+}
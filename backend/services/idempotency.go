@@ -0,0 +1,39 @@
+package services
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"time"
+)
+
+var ErrInvalidIdempotencyKey = errors.New("Idempotency-Key must be 1 to 255 printable ASCII characters")
+
+// IdempotencyWindow is how long a stored response is replayed for retries.
+const IdempotencyWindow = 24 * time.Hour
+
+// ValidateIdempotencyKey accepts client generated keys such as UUIDs.
+func ValidateIdempotencyKey(key string) error {
+	if key == "" || len(key) > 255 {
+		return ErrInvalidIdempotencyKey
+	}
+	for i := 0; i < len(key); i++ {
+		if key[i] < 0x21 || key[i] > 0x7e {
+			return ErrInvalidIdempotencyKey
+		}
+	}
+	return nil
+}
+
+// IdempotencyScope derives the stored record id so the same key sent by
+// another user or to another endpoint never replays a foreign response.
+func IdempotencyScope(userID, method, path, key string) string {
+	sum := sha256.Sum256([]byte(userID + "\x00" + method + "\x00" + path + "\x00" + key))
+	return hex.EncodeToString(sum[:])
+}
+
+// RequestFingerprint identifies the request body a key was first used with.
+func RequestFingerprint(body []byte) string {
+	sum := sha256.Sum256(body)
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,41 @@
+package services
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestValidateIdempotencyKey(t *testing.T) {
+	valid := []string{"3f0c6a2e-8d4b-4f7e-9a51-2b7c1d9e0f11", "retry-1"}
+	for _, k := range valid {
+		if err := ValidateIdempotencyKey(k); err != nil {
+			t.Fatalf("expected %q to be valid, got %v", k, err)
+		}
+	}
+	invalid := []string{"", "has space", "tab\tkey", strings.Repeat("k", 256), "ключ"}
+	for _, k := range invalid {
+		if err := ValidateIdempotencyKey(k); err == nil {
+			t.Fatalf("expected %q to be rejected", k)
+		}
+	}
+}
+
+func TestIdempotencyScope(t *testing.T) {
+	base := IdempotencyScope("u1", "POST", "/api/bookings", "k1")
+	if base != IdempotencyScope("u1", "POST", "/api/bookings", "k1") {
+		t.Fatal("expected a stable scope for the same request")
+	}
+	others := []string{
+		IdempotencyScope("u2", "POST", "/api/bookings", "k1"),
+		IdempotencyScope("u1", "POST", "/api/vehicles", "k1"),
+		IdempotencyScope("u1", "POST", "/api/bookings", "k2"),
+	}
+	for _, o := range others {
+		if o == base {
+			t.Fatal("expected user, path and key to change the scope")
+		}
+	}
+	if RequestFingerprint([]byte(`{"a":1}`)) == RequestFingerprint([]byte(`{"a":2}`)) {
+		t.Fatal("expected different bodies to have different fingerprints")
+	}
+}
@@ -0,0 +1,49 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/tss-booking-system/backend/models"
+)
+
+var ErrInvalidJobLine = errors.New("invalid job line")
+
+// ValidateJobLine checks the title, hours and status of a job line.
+func ValidateJobLine(j models.JobLine) error {
+	if strings.TrimSpace(j.Title) == "" {
+		return fmt.Errorf("%w: title is required", ErrInvalidJobLine)
+	}
+	if j.EstimatedHours < 0 || j.ActualHours < 0 {
+		return fmt.Errorf("%w: hours must not be negative", ErrInvalidJobLine)
+	}
+	switch j.Status {
+	case models.JobPending, models.JobInProgress, models.JobCompleted, models.JobCanceled:
+	default:
+		return fmt.Errorf("%w: unknown status %q", ErrInvalidJobLine, j.Status)
+	}
+	return nil
+}
+
+// JobHours sums estimated and actual hours of all non-canceled job lines.
+func JobHours(jobs []models.JobLine) (estimated, actual float64) {
+	for _, j := range jobs {
+		if j.Status == models.JobCanceled {
+			continue
+		}
+		estimated += j.EstimatedHours
+		actual += j.ActualHours
+	}
+	return estimated, actual
+}
+
+// FormatJobLines renders job lines as "Title [status] est/act h" entries
+// joined by sep, for CSV cells and notifications.
+func FormatJobLines(jobs []models.JobLine, sep string) string {
+	parts := make([]string, 0, len(jobs))
+	for _, j := range jobs {
+		parts = append(parts, fmt.Sprintf("%s [%s] %.1f/%.1fh", j.Title, j.Status, j.EstimatedHours, j.ActualHours))
+	}
+	return strings.Join(parts, sep)
+}
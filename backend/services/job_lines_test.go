@@ -0,0 +1,40 @@
+package services
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/tss-booking-system/backend/models"
+)
+
+func TestValidateJobLine(t *testing.T) {
+	ok := models.JobLine{Title: "Brakes", EstimatedHours: 2, Status: models.JobPending}
+	if err := ValidateJobLine(ok); err != nil {
+		t.Fatalf("expected valid job line, got %v", err)
+	}
+	bad := []models.JobLine{
+		{Title: " ", Status: models.JobPending},
+		{Title: "PM", EstimatedHours: -1, Status: models.JobPending},
+		{Title: "DOT", Status: "done"},
+	}
+	for _, j := range bad {
+		if err := ValidateJobLine(j); !errors.Is(err, ErrInvalidJobLine) {
+			t.Fatalf("expected ErrInvalidJobLine for %+v, got %v", j, err)
+		}
+	}
+}
+
+func TestJobHoursSkipsCanceled(t *testing.T) {
+	jobs := []models.JobLine{
+		{Title: "Brakes", EstimatedHours: 2, ActualHours: 2.5, Status: models.JobCompleted},
+		{Title: "PM", EstimatedHours: 1.5, Status: models.JobPending},
+		{Title: "DOT", EstimatedHours: 1, ActualHours: 0.5, Status: models.JobCanceled},
+	}
+	est, act := JobHours(jobs)
+	if est != 3.5 || act != 2.5 {
+		t.Fatalf("expected 3.5/2.5 hours, got %v/%v", est, act)
+	}
+	if got := FormatJobLines(jobs[:1], "; "); got != "Brakes [completed] 2.0/2.5h" {
+		t.Fatalf("unexpected format %q", got)
+	}
+}
@@ -0,0 +1,46 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+)
+
+var (
+	ErrReadingRequired = errors.New("odometer or engine_hours is required")
+	ErrReadingNegative = errors.New("meter readings must not be negative")
+	ErrMeterRollback   = errors.New("reading is lower than the previous one")
+)
+
+// MeterRollbackError reports a reading below the unit's last one.
+type MeterRollbackError struct {
+	Meter    string
+	Previous float64
+	Reading  float64
+}
+
+func (e *MeterRollbackError) Error() string {
+	return fmt.Sprintf("%s %g is lower than the previous reading %g", e.Meter, e.Reading, e.Previous)
+}
+
+func (e *MeterRollbackError) Unwrap() error {
+	return ErrMeterRollback
+}
+
+// CheckMeterReading validates a new odometer and/or engine hours reading
+// against the last known values. Zero means the meter was not read. A meter
+// that goes backwards gives a *MeterRollbackError.
+func CheckMeterReading(lastOdometer int, lastHours float64, odometer int, hours float64) error {
+	if odometer < 0 || hours < 0 {
+		return ErrReadingNegative
+	}
+	if odometer == 0 && hours == 0 {
+		return ErrReadingRequired
+	}
+	if odometer > 0 && odometer < lastOdometer {
+		return &MeterRollbackError{Meter: "odometer", Previous: float64(lastOdometer), Reading: float64(odometer)}
+	}
+	if hours > 0 && hours < lastHours {
+		return &MeterRollbackError{Meter: "engine_hours", Previous: lastHours, Reading: hours}
+	}
+	return nil
+}
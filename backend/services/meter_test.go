@@ -0,0 +1,38 @@
+package services
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestCheckMeterReading(t *testing.T) {
+	cases := []struct {
+		name      string
+		lastOdo   int
+		lastHours float64
+		odo       int
+		hours     float64
+		want      error
+	}{
+		{"first reading", 0, 0, 120000, 0, nil},
+		{"higher odometer", 120000, 4000, 121500, 0, nil},
+		{"same odometer", 120000, 4000, 120000, 0, nil},
+		{"hours only", 120000, 4000, 0, 4012.5, nil},
+		{"nothing read", 120000, 4000, 0, 0, ErrReadingRequired},
+		{"negative", 0, 0, -1, 0, ErrReadingNegative},
+		{"odometer rollback", 120000, 4000, 12000, 0, ErrMeterRollback},
+		{"hours rollback", 120000, 4000, 121000, 3999, ErrMeterRollback},
+	}
+	for _, tc := range cases {
+		err := CheckMeterReading(tc.lastOdo, tc.lastHours, tc.odo, tc.hours)
+		if !errors.Is(err, tc.want) {
+			t.Errorf("%s: got %v, want %v", tc.name, err, tc.want)
+		}
+	}
+
+	var rollback *MeterRollbackError
+	err := CheckMeterReading(120000, 4000, 12000, 0)
+	if !errors.As(err, &rollback) || rollback.Meter != "odometer" || rollback.Previous != 120000 {
+		t.Fatalf("expected an odometer rollback, got %v", err)
+	}
+}
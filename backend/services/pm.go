@@ -0,0 +1,163 @@
+package services
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/tss-booking-system/backend/models"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+var (
+	ErrPMNameRequired    = errors.New("name is required")
+	ErrPMJobTypeRequired = errors.New("job_type is required")
+	ErrPMNoInterval      = errors.New("set interval_days and/or interval_miles")
+	ErrPMNegative        = errors.New("intervals must not be negative")
+	ErrInvalidWithin     = errors.New("within must be a number of days such as 30d, 4w or 72h")
+)
+
+// ValidatePMProgram checks the fields a PM program needs.
+func ValidatePMProgram(p models.PMProgram) error {
+	switch {
+	case strings.TrimSpace(p.Name) == "":
+		return ErrPMNameRequired
+	case strings.TrimSpace(p.JobType) == "":
+		return ErrPMJobTypeRequired
+	case p.IntervalDays < 0 || p.IntervalMiles < 0:
+		return ErrPMNegative
+	case p.IntervalDays == 0 && p.IntervalMiles == 0:
+		return ErrPMNoInterval
+	}
+	return nil
+}
+
+// ParseWithin reads a look-ahead such as "30d", "4w", "72h" or a bare number
+// of days. An empty value gives def.
+func ParseWithin(v string, def time.Duration) (time.Duration, error) {
+	v = strings.TrimSpace(strings.ToLower(v))
+	if v == "" {
+		return def, nil
+	}
+	unit := 24 * time.Hour
+	switch {
+	case strings.HasSuffix(v, "d"):
+		v = strings.TrimSuffix(v, "d")
+	case strings.HasSuffix(v, "w"):
+		v, unit = strings.TrimSuffix(v, "w"), 7*24*time.Hour
+	case strings.HasSuffix(v, "h"):
+		v, unit = strings.TrimSuffix(v, "h"), time.Hour
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil || n < 0 {
+		return 0, ErrInvalidWithin
+	}
+	return time.Duration(n) * unit, nil
+}
+
+// ApplicablePMPrograms returns the programs that apply to v. A program
+// attached to the unit itself replaces a company program with the same job
+// type, so a unit can run a shorter PM interval than the rest of its fleet.
+func ApplicablePMPrograms(programs []models.PMProgram, v models.Vehicle) []models.PMProgram {
+	direct := map[string]bool{}
+	for _, p := range programs {
+		if containsID(p.VehicleIDs, v.ID) {
+			direct[p.JobType] = true
+		}
+	}
+	var out []models.PMProgram
+	for _, p := range programs {
+		switch {
+		case containsID(p.VehicleIDs, v.ID):
+			out = append(out, p)
+		case !direct[p.JobType] && !v.CompanyID.IsZero() && containsID(p.CompanyIDs, v.CompanyID):
+			out = append(out, p)
+		}
+	}
+	return out
+}
+
+// PMWindow is how far ahead the due list looks, in time and in miles.
+type PMWindow struct {
+	Within time.Duration
+	Miles  int
+}
+
+// PMState is where a unit stands on one PM program. DueAt is nil when the
+// program only counts miles; DueOdometer and MilesLeft are nil when it only
+// counts days or the odometer is unknown. A unit without a completed service
+// is due now, and so is one whose odometer at its last service is unknown on
+// a program that counts miles, since the miles run since then cannot be told.
+type PMState struct {
+	LastDone     *time.Time `json:"last_done,omitempty"`
+	LastOdometer int        `json:"last_odometer,omitempty"`
+	DueAt        *time.Time `json:"due_at,omitempty"`
+	DaysLeft     int        `json:"days_left"`
+	DueOdometer  *int       `json:"due_odometer,omitempty"`
+	MilesLeft    *int       `json:"miles_left,omitempty"`
+	Overdue      bool       `json:"overdue"`
+	Due          bool       `json:"due"`
+}
+
+// PMDueState works out when p is next due for a unit last serviced at
+// lastDone (nil if never) with lastOdometer miles on it, now that it shows
+// odometer miles (0 if unknown), and whether either falls within the window.
+func PMDueState(p models.PMProgram, lastDone *time.Time, lastOdometer, odometer int, now time.Time, window PMWindow) PMState {
+	st := PMState{LastDone: lastDone, LastOdometer: lastOdometer}
+	if p.IntervalDays > 0 {
+		due := now
+		if lastDone != nil {
+			due = lastDone.AddDate(0, 0, p.IntervalDays)
+		}
+		st.DueAt = &due
+		st.DaysLeft = int(due.Sub(now).Hours() / 24)
+		st.Overdue = due.Before(now)
+		st.Due = !due.After(now.Add(window.Within))
+	}
+	if p.IntervalMiles > 0 {
+		switch {
+		case lastDone == nil:
+			st.Due = true
+			if odometer > 0 {
+				st.DueOdometer, st.MilesLeft = intPtr(odometer), intPtr(0)
+			}
+		case lastOdometer > 0 && odometer > 0:
+			due := lastOdometer + p.IntervalMiles
+			left := due - odometer
+			st.DueOdometer, st.MilesLeft = &due, &left
+			st.Overdue = st.Overdue || left < 0
+			st.Due = st.Due || left <= window.Miles
+		case lastOdometer == 0:
+			st.Due = true
+		}
+	}
+	return st
+}
+
+// PMDueBefore orders due states soonest first: by due date where both have
+// one, dated states before miles-only ones, then by miles left.
+func PMDueBefore(a, b PMState) bool {
+	switch {
+	case a.DueAt != nil && b.DueAt != nil:
+		return a.DueAt.Before(*b.DueAt)
+	case a.DueAt != nil || b.DueAt != nil:
+		return a.DueAt != nil
+	case a.MilesLeft != nil && b.MilesLeft != nil:
+		return *a.MilesLeft < *b.MilesLeft
+	}
+	return a.MilesLeft != nil
+}
+
+func intPtr(n int) *int {
+	return &n
+}
+
+func containsID(ids []primitive.ObjectID, id primitive.ObjectID) bool {
+	for _, x := range ids {
+		if x == id {
+			return true
+		}
+	}
+	return false
+}
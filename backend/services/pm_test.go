@@ -0,0 +1,145 @@
+package services
+
+import (
+	"errors"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/tss-booking-system/backend/models"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestValidatePMProgram(t *testing.T) {
+	ok := models.PMProgram{Name: "PM A", JobType: "pm", IntervalDays: 90}
+	if err := ValidatePMProgram(ok); err != nil {
+		t.Fatal(err)
+	}
+	miles := models.PMProgram{Name: "Oil", JobType: "pm", IntervalMiles: 25000}
+	if err := ValidatePMProgram(miles); err != nil {
+		t.Fatal(err)
+	}
+	cases := []struct {
+		p    models.PMProgram
+		want error
+	}{
+		{models.PMProgram{JobType: "pm", IntervalDays: 90}, ErrPMNameRequired},
+		{models.PMProgram{Name: "PM A", IntervalDays: 90}, ErrPMJobTypeRequired},
+		{models.PMProgram{Name: "PM A", JobType: "pm"}, ErrPMNoInterval},
+		{models.PMProgram{Name: "PM A", JobType: "pm", IntervalDays: -1, IntervalMiles: 100}, ErrPMNegative},
+	}
+	for _, tc := range cases {
+		if err := ValidatePMProgram(tc.p); !errors.Is(err, tc.want) {
+			t.Fatalf("ValidatePMProgram(%+v) = %v, want %v", tc.p, err, tc.want)
+		}
+	}
+}
+
+func TestParseWithin(t *testing.T) {
+	day := 24 * time.Hour
+	cases := map[string]time.Duration{"": 30 * day, "30d": 30 * day, "2W": 14 * day, "72h": 72 * time.Hour, "10": 10 * day, "0d": 0}
+	for in, want := range cases {
+		got, err := ParseWithin(in, 30*day)
+		if err != nil || got != want {
+			t.Fatalf("ParseWithin(%q) = %v, %v; want %v", in, got, err, want)
+		}
+	}
+	for _, in := range []string{"soon", "-5d", "3m"} {
+		if _, err := ParseWithin(in, day); !errors.Is(err, ErrInvalidWithin) {
+			t.Fatalf("ParseWithin(%q) should fail, got %v", in, err)
+		}
+	}
+}
+
+func TestApplicablePMPrograms(t *testing.T) {
+	fleet := primitive.NewObjectID()
+	unit := models.Vehicle{ID: primitive.NewObjectID(), CompanyID: fleet}
+	otherUnit := models.Vehicle{ID: primitive.NewObjectID(), CompanyID: fleet}
+	fleetPM := models.PMProgram{ID: primitive.NewObjectID(), JobType: "pm", IntervalDays: 90, CompanyIDs: []primitive.ObjectID{fleet}}
+	fleetDOT := models.PMProgram{ID: primitive.NewObjectID(), JobType: "dot", IntervalDays: 365, CompanyIDs: []primitive.ObjectID{fleet}}
+	unitPM := models.PMProgram{ID: primitive.NewObjectID(), JobType: "pm", IntervalDays: 45, VehicleIDs: []primitive.ObjectID{unit.ID}}
+	programs := []models.PMProgram{fleetPM, fleetDOT, unitPM}
+
+	got := ApplicablePMPrograms(programs, unit)
+	if len(got) != 2 || got[0].ID != fleetDOT.ID || got[1].ID != unitPM.ID {
+		t.Fatalf("the unit's own PM should replace the fleet PM, got %+v", got)
+	}
+	got = ApplicablePMPrograms(programs, otherUnit)
+	if len(got) != 2 || got[0].ID != fleetPM.ID || got[1].ID != fleetDOT.ID {
+		t.Fatalf("expected the fleet programs, got %+v", got)
+	}
+	if got := ApplicablePMPrograms(programs, models.Vehicle{ID: primitive.NewObjectID()}); len(got) != 0 {
+		t.Fatalf("expected no programs, got %+v", got)
+	}
+}
+
+func TestPMDueState(t *testing.T) {
+	now := time.Date(2026, 6, 1, 12, 0, 0, 0, time.UTC)
+	window := PMWindow{Within: 30 * 24 * time.Hour, Miles: 1000}
+	p := models.PMProgram{IntervalDays: 90}
+
+	never := PMDueState(p, nil, 0, 0, now, window)
+	if !never.Due || never.Overdue || never.DueAt == nil || !never.DueAt.Equal(now) {
+		t.Fatalf("a unit never serviced is due now, got %+v", never)
+	}
+	last := now.AddDate(0, 0, -70)
+	soon := PMDueState(p, &last, 0, 0, now, window)
+	if !soon.Due || soon.Overdue || soon.DaysLeft != 20 {
+		t.Fatalf("expected due in 20 days, got %+v", soon)
+	}
+	last = now.AddDate(0, 0, -100)
+	late := PMDueState(p, &last, 0, 0, now, window)
+	if !late.Due || !late.Overdue || late.DaysLeft != -10 {
+		t.Fatalf("expected 10 days overdue, got %+v", late)
+	}
+	last = now.AddDate(0, 0, -10)
+	if st := PMDueState(p, &last, 0, 0, now, window); st.Due {
+		t.Fatalf("expected not yet due, got %+v", st)
+	}
+
+	miles := models.PMProgram{IntervalMiles: 25000}
+	if st := PMDueState(miles, nil, 0, 0, now, window); !st.Due || st.DueAt != nil || st.MilesLeft != nil {
+		t.Fatalf("a unit never serviced is due now without a date, got %+v", st)
+	}
+	last = now.AddDate(0, -6, 0)
+	if st := PMDueState(miles, &last, 0, 140000, now, window); !st.Due || st.MilesLeft != nil || st.DueOdometer != nil {
+		t.Fatalf("without the odometer at the last service the unit is due, got %+v", st)
+	}
+	if st := PMDueState(miles, &last, 0, 0, now, window); !st.Due {
+		t.Fatalf("a serviced unit that was never read is due, got %+v", st)
+	}
+	st := PMDueState(miles, &last, 120000, 144500, now, window)
+	if !st.Due || st.Overdue || st.MilesLeft == nil || *st.MilesLeft != 500 || *st.DueOdometer != 145000 {
+		t.Fatalf("expected due in 500 miles, got %+v", st)
+	}
+	if st := PMDueState(miles, &last, 120000, 146000, now, window); !st.Due || !st.Overdue {
+		t.Fatalf("expected overdue by miles, got %+v", st)
+	}
+	if st := PMDueState(miles, &last, 120000, 130000, now, window); st.Due {
+		t.Fatalf("expected not yet due by miles, got %+v", st)
+	}
+
+	// whichever interval comes first makes the unit due
+	both := models.PMProgram{IntervalDays: 365, IntervalMiles: 25000}
+	last = now.AddDate(0, 0, -10)
+	if st := PMDueState(both, &last, 120000, 145200, now, window); !st.Due || !st.Overdue || st.DaysLeft != 355 {
+		t.Fatalf("expected due by miles before the date, got %+v", st)
+	}
+}
+
+func TestPMDueBefore(t *testing.T) {
+	now := time.Date(2026, 6, 1, 12, 0, 0, 0, time.UTC)
+	later := now.AddDate(0, 0, 5)
+	few, many := 100, 900
+	states := []PMState{
+		{MilesLeft: &many},
+		{DueAt: &later},
+		{},
+		{MilesLeft: &few},
+		{DueAt: &now},
+	}
+	sort.SliceStable(states, func(i, j int) bool { return PMDueBefore(states[i], states[j]) })
+	if states[0].DueAt != &now || states[1].DueAt != &later || states[2].MilesLeft != &few || states[3].MilesLeft != &many || states[4].MilesLeft != nil {
+		t.Fatalf("unexpected order %+v", states)
+	}
+}
@@ -0,0 +1,81 @@
+package services
+
+import (
+	"errors"
+	"time"
+
+	"github.com/tss-booking-system/backend/models"
+)
+
+// MaxOccurrences limits how many bookings a single series may generate.
+const MaxOccurrences = 104
+
+var (
+	ErrInvalidRecurrence = errors.New("invalid recurrence rule")
+	ErrRecurrenceTooLong = errors.New("recurrence rule generates too many occurrences")
+)
+
+// ExpandRecurrence returns the start times of every occurrence of rule,
+// beginning at start. Dates listed in rule.Exceptions (compared by calendar
+// day in loc) are skipped but still count towards rule.Count, like EXDATE.
+// Monthly rules skip months that do not have the start day (e.g. the 31st).
+func ExpandRecurrence(rule models.RecurrenceRule, start time.Time, loc *time.Location) ([]time.Time, error) {
+	switch rule.Frequency {
+	case models.RecurDaily, models.RecurWeekly, models.RecurMonthly:
+	default:
+		return nil, ErrInvalidRecurrence
+	}
+	if rule.Interval < 0 || rule.Count < 0 {
+		return nil, ErrInvalidRecurrence
+	}
+	if rule.Count == 0 && rule.Until == nil {
+		return nil, ErrInvalidRecurrence
+	}
+	if rule.Count > MaxOccurrences {
+		return nil, ErrRecurrenceTooLong
+	}
+	interval := rule.Interval
+	if interval == 0 {
+		interval = 1
+	}
+	start = start.In(loc)
+
+	skip := map[string]bool{}
+	for _, ex := range rule.Exceptions {
+		skip[ex.In(loc).Format("2006-01-02")] = true
+	}
+
+	var out []time.Time
+	generated := 0
+	for step := 0; ; step++ {
+		var t time.Time
+		switch rule.Frequency {
+		case models.RecurDaily:
+			t = start.AddDate(0, 0, step*interval)
+		case models.RecurWeekly:
+			t = start.AddDate(0, 0, 7*step*interval)
+		case models.RecurMonthly:
+			t = start.AddDate(0, step*interval, 0)
+			if t.Day() != start.Day() {
+				if step > MaxOccurrences*2 {
+					return nil, ErrRecurrenceTooLong
+				}
+				continue
+			}
+		}
+		if rule.Until != nil && t.After(*rule.Until) {
+			break
+		}
+		generated++
+		if !skip[t.Format("2006-01-02")] {
+			out = append(out, t)
+		}
+		if rule.Count > 0 && generated >= rule.Count {
+			break
+		}
+		if generated > MaxOccurrences {
+			return nil, ErrRecurrenceTooLong
+		}
+	}
+	return out, nil
+}
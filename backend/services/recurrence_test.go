@@ -0,0 +1,64 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"github.com/tss-booking-system/backend/models"
+)
+
+func TestExpandRecurrenceWeeklyCount(t *testing.T) {
+	loc := time.UTC
+	start := time.Date(2026, 1, 6, 8, 0, 0, 0, loc) // Tuesday
+	rule := models.RecurrenceRule{
+		Frequency:  models.RecurWeekly,
+		Interval:   2,
+		Count:      4,
+		Exceptions: []time.Time{time.Date(2026, 2, 3, 0, 0, 0, 0, loc)},
+	}
+	got, err := ExpandRecurrence(rule, start, loc)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := []time.Time{
+		start,
+		start.AddDate(0, 0, 14),
+		start.AddDate(0, 0, 42),
+	}
+	if len(got) != len(want) {
+		t.Fatalf("expected %d occurrences, got %d: %v", len(want), len(got), got)
+	}
+	for i := range want {
+		if !got[i].Equal(want[i]) {
+			t.Fatalf("occurrence %d: expected %v, got %v", i, want[i], got[i])
+		}
+	}
+}
+
+func TestExpandRecurrenceMonthlyUntil(t *testing.T) {
+	loc := time.UTC
+	start := time.Date(2026, 1, 31, 9, 0, 0, 0, loc)
+	until := time.Date(2026, 6, 1, 0, 0, 0, 0, loc)
+	got, err := ExpandRecurrence(models.RecurrenceRule{Frequency: models.RecurMonthly, Until: &until}, start, loc)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	// February and April have no 31st
+	if len(got) != 3 || got[1].Month() != time.March || got[2].Month() != time.May {
+		t.Fatalf("unexpected occurrences: %v", got)
+	}
+}
+
+func TestExpandRecurrenceInvalid(t *testing.T) {
+	start := time.Now()
+	if _, err := ExpandRecurrence(models.RecurrenceRule{Frequency: models.RecurDaily}, start, time.UTC); err != ErrInvalidRecurrence {
+		t.Fatalf("expected unbounded rule to be rejected, got %v", err)
+	}
+	if _, err := ExpandRecurrence(models.RecurrenceRule{Frequency: "hourly", Count: 2}, start, time.UTC); err != ErrInvalidRecurrence {
+		t.Fatalf("expected unknown frequency to be rejected, got %v", err)
+	}
+	until := start.AddDate(10, 0, 0)
+	if _, err := ExpandRecurrence(models.RecurrenceRule{Frequency: models.RecurDaily, Until: &until}, start, time.UTC); err != ErrRecurrenceTooLong {
+		t.Fatalf("expected long rule to be rejected, got %v", err)
+	}
+}
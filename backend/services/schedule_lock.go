@@ -0,0 +1,163 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/tss-booking-system/backend/models"
+)
+
+var ErrLockTimeout = errors.New("schedule is being changed by another request, try again")
+
+// LeaseStore keeps named leases. TryAcquire takes name for owner until
+// expires unless another owner holds an unexpired lease; Release only drops a
+// lease still held by owner.
+type LeaseStore interface {
+	TryAcquire(ctx context.Context, name, owner string, expires time.Time) (bool, error)
+	Release(ctx context.Context, name, owner string) error
+}
+
+// Locker serialises schedule changes on the same bays and technicians, so
+// the conflict check and the write of one request cannot interleave with
+// another's. Leases expire after TTL in case a holder dies; Wait bounds how
+// long Lock blocks before giving up with ErrLockTimeout.
+type Locker struct {
+	Store LeaseStore
+	TTL   time.Duration
+	Wait  time.Duration
+	Retry time.Duration
+}
+
+// NewLocker returns a Locker with defaults suited to HTTP handlers.
+func NewLocker(store LeaseStore) *Locker {
+	return &Locker{Store: store, TTL: 30 * time.Second, Wait: 5 * time.Second, Retry: 20 * time.Millisecond}
+}
+
+// Lock acquires every named lease and returns a function releasing them;
+// calling it more than once is harmless. Names are taken in sorted order so
+// concurrent callers cannot deadlock.
+func (l *Locker) Lock(ctx context.Context, names ...string) (func(), error) {
+	sorted := append([]string(nil), names...)
+	sort.Strings(sorted)
+	owner := newLeaseOwner()
+	var held []string
+	var once sync.Once
+	release := func() {
+		once.Do(func() {
+			for i := len(held) - 1; i >= 0; i-- {
+				_ = l.Store.Release(context.Background(), held[i], owner)
+			}
+		})
+	}
+	deadline := time.Now().Add(l.Wait)
+	for i, name := range sorted {
+		if i > 0 && name == sorted[i-1] {
+			continue
+		}
+		for {
+			ok, err := l.Store.TryAcquire(ctx, name, owner, time.Now().Add(l.TTL))
+			if err != nil {
+				release()
+				return nil, err
+			}
+			if ok {
+				held = append(held, name)
+				break
+			}
+			if time.Now().After(deadline) {
+				release()
+				return nil, ErrLockTimeout
+			}
+			select {
+			case <-ctx.Done():
+				release()
+				return nil, ctx.Err()
+			case <-time.After(l.Retry):
+			}
+		}
+	}
+	return release, nil
+}
+
+// LockError is returned by Schedule when the leases could not be taken.
+type LockError struct {
+	Err error
+}
+
+func (e *LockError) Error() string { return e.Err.Error() }
+
+func (e *LockError) Unwrap() error { return e.Err }
+
+// Schedule runs one schedule change: it takes the leases of the bays and
+// technicians of bookings, runs check against the current schedule and, when
+// that passes, write. The leases are held from before check until write
+// returns, so two requests for the same slot cannot both pass check before
+// either has written. Errors from check and write are returned as they are.
+func (l *Locker) Schedule(ctx context.Context, bookings []models.Booking, check, write func() error) error {
+	unlock, err := l.Lock(ctx, ScheduleLockNames(bookings...)...)
+	if err != nil {
+		return &LockError{Err: err}
+	}
+	defer unlock()
+	if err := check(); err != nil {
+		return err
+	}
+	return write()
+}
+
+// ScheduleLockNames returns the lease names guarding the bays and technicians
+// of bookings.
+func ScheduleLockNames(bookings ...models.Booking) []string {
+	var names []string
+	for _, b := range bookings {
+		names = append(names, "bay:"+b.BayID.Hex())
+		for _, t := range b.TechnicianIDs {
+			names = append(names, "technician:"+t.Hex())
+		}
+	}
+	return names
+}
+
+func newLeaseOwner() string {
+	b := make([]byte, 12)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// MemoryLeaseStore is an in-process LeaseStore for a single instance and tests.
+type MemoryLeaseStore struct {
+	mu     sync.Mutex
+	leases map[string]memoryLease
+}
+
+type memoryLease struct {
+	owner   string
+	expires time.Time
+}
+
+func (s *MemoryLeaseStore) TryAcquire(_ context.Context, name, owner string, expires time.Time) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.leases == nil {
+		s.leases = map[string]memoryLease{}
+	}
+	if cur, ok := s.leases[name]; ok && cur.owner != owner && cur.expires.After(time.Now()) {
+		return false, nil
+	}
+	s.leases[name] = memoryLease{owner: owner, expires: expires}
+	return true, nil
+}
+
+func (s *MemoryLeaseStore) Release(_ context.Context, name, owner string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if cur, ok := s.leases[name]; ok && cur.owner == owner {
+		delete(s.leases, name)
+	}
+	return nil
+}
@@ -0,0 +1,184 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/tss-booking-system/backend/models"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// scheduleStore stands in for the bookings collection: the state the handler
+// loads for the conflict check and the insert that follows it.
+type scheduleStore struct {
+	mu       sync.Mutex
+	bookings []models.Booking
+}
+
+func (s *scheduleStore) state(b models.Booking) ScheduleState {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	state := ScheduleState{Capacity: 1}
+	for _, e := range s.bookings {
+		if e.BayID == b.BayID {
+			state.BayBookings = append(state.BayBookings, e)
+		}
+		for _, t := range e.TechnicianIDs {
+			if containsID(b.TechnicianIDs, t) {
+				state.TechnicianBookings = append(state.TechnicianBookings, e)
+				break
+			}
+		}
+	}
+	return state
+}
+
+func (s *scheduleStore) insert(b models.Booking) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.bookings = append(s.bookings, b)
+}
+
+// TestConcurrentBookingsForSameBay sends many bookings through
+// Locker.Schedule, the sequence CreateBooking runs, with the conflict check
+// it uses. Requests compete for one bay, and for one technician across two
+// bays; the stored schedule must stay free of double bookings.
+func TestConcurrentBookingsForSameBay(t *testing.T) {
+	locker := NewLocker(&MemoryLeaseStore{})
+	locker.Retry = time.Millisecond
+	bayA, bayB := primitive.NewObjectID(), primitive.NewObjectID()
+	tech := primitive.NewObjectID()
+	start := time.Date(2026, 3, 2, 9, 0, 0, 0, time.UTC)
+	now := start.Add(-time.Hour)
+	store := &scheduleStore{}
+
+	const workers = 30
+	var wg sync.WaitGroup
+	ready := make(chan struct{})
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			<-ready
+			b := models.Booking{ID: primitive.NewObjectID(), BayID: bayA, Start: start.Add(time.Duration(i%4) * 15 * time.Minute), Status: models.BookingOpen}
+			if i%3 == 0 {
+				// a different bay, but the same technician
+				b.BayID = bayB
+				b.TechnicianIDs = []primitive.ObjectID{tech}
+			} else if i%3 == 1 {
+				b.TechnicianIDs = []primitive.ObjectID{tech}
+			}
+			end := b.Start.Add(2 * time.Hour)
+			b.End = &end
+			err := locker.Schedule(context.Background(), []models.Booking{b}, func() error {
+				state := store.state(b)
+				// widen the race window between check and insert
+				time.Sleep(time.Millisecond)
+				return ValidateSchedule(b, state, nil, now)
+			}, func() error {
+				store.insert(b)
+				return nil
+			})
+			var lockErr *LockError
+			if errors.As(err, &lockErr) {
+				t.Errorf("lock: %v", err)
+			}
+		}(i)
+	}
+	close(ready)
+	wg.Wait()
+
+	perBay := map[primitive.ObjectID]int{}
+	withTech := 0
+	for _, b := range store.bookings {
+		perBay[b.BayID]++
+		if containsID(b.TechnicianIDs, tech) {
+			withTech++
+		}
+	}
+	if perBay[bayA] != 1 {
+		t.Fatalf("expected exactly one booking in the contested bay, got %d", perBay[bayA])
+	}
+	if withTech != 1 {
+		t.Fatalf("expected the technician on exactly one booking, got %d", withTech)
+	}
+	for i, a := range store.bookings {
+		for _, b := range store.bookings[i+1:] {
+			if ValidateSchedule(a, ScheduleState{BayBookings: []models.Booking{b}, TechnicianBookings: []models.Booking{b}, Capacity: 1}, nil, now) != nil {
+				t.Fatalf("stored bookings %s and %s conflict", a.ID.Hex(), b.ID.Hex())
+			}
+		}
+	}
+}
+
+func TestScheduleSkipsWriteAfterFailedCheck(t *testing.T) {
+	store := &MemoryLeaseStore{}
+	locker := &Locker{Store: store, TTL: time.Minute, Wait: 20 * time.Millisecond, Retry: time.Millisecond}
+	b := models.Booking{BayID: primitive.NewObjectID()}
+	refused := errors.New("slot taken")
+	wrote := false
+	err := locker.Schedule(context.Background(), []models.Booking{b}, func() error { return refused }, func() error {
+		wrote = true
+		return nil
+	})
+	if !errors.Is(err, refused) || wrote {
+		t.Fatalf("expected the check error without a write, got %v (wrote %v)", err, wrote)
+	}
+
+	unlock, err := locker.Lock(context.Background(), ScheduleLockNames(b)...)
+	if err != nil {
+		t.Fatalf("expected the leases released after Schedule, got %v", err)
+	}
+	defer unlock()
+	err = locker.Schedule(context.Background(), []models.Booking{b}, func() error { return nil }, func() error { return nil })
+	var lockErr *LockError
+	if !errors.As(err, &lockErr) || !errors.Is(err, ErrLockTimeout) {
+		t.Fatalf("expected a LockError timeout while held, got %v", err)
+	}
+}
+
+func TestScheduleLockNames(t *testing.T) {
+	bay, tech := primitive.NewObjectID(), primitive.NewObjectID()
+	names := ScheduleLockNames(models.Booking{BayID: bay, TechnicianIDs: []primitive.ObjectID{tech}})
+	want := []string{"bay:" + bay.Hex(), "technician:" + tech.Hex()}
+	if len(names) != len(want) || names[0] != want[0] || names[1] != want[1] {
+		t.Fatalf("expected %v, got %v", want, names)
+	}
+}
+
+func TestLockerTimesOutAndReleases(t *testing.T) {
+	store := &MemoryLeaseStore{}
+	locker := &Locker{Store: store, TTL: time.Minute, Wait: 20 * time.Millisecond, Retry: time.Millisecond}
+
+	unlock, err := locker.Lock(context.Background(), "bay:1", "technician:2")
+	if err != nil {
+		t.Fatalf("lock: %v", err)
+	}
+	if _, err := locker.Lock(context.Background(), "technician:2"); !errors.Is(err, ErrLockTimeout) {
+		t.Fatalf("expected timeout while held, got %v", err)
+	}
+	unlock()
+	unlock2, err := locker.Lock(context.Background(), "technician:2", "bay:1", "bay:1")
+	if err != nil {
+		t.Fatalf("expected lock after release, got %v", err)
+	}
+	unlock2()
+}
+
+func TestLockerTakesOverExpiredLease(t *testing.T) {
+	store := &MemoryLeaseStore{}
+	crashed := &Locker{Store: store, TTL: 5 * time.Millisecond, Wait: time.Second, Retry: time.Millisecond}
+	if _, err := crashed.Lock(context.Background(), "bay:1"); err != nil {
+		t.Fatalf("lock: %v", err)
+	}
+	// the first holder never unlocks
+	other := &Locker{Store: store, TTL: time.Minute, Wait: time.Second, Retry: time.Millisecond}
+	unlock, err := other.Lock(context.Background(), "bay:1")
+	if err != nil {
+		t.Fatalf("expected the expired lease to be taken over, got %v", err)
+	}
+	unlock()
+}
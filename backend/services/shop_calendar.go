@@ -0,0 +1,176 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/tss-booking-system/backend/models"
+)
+
+var (
+	ErrInvalidCalendar     = errors.New("invalid shop calendar")
+	ErrOutsideWorkingHours = errors.New("booking is outside shop working hours")
+	ErrShopHoliday         = errors.New("shop is closed on this day")
+	ErrBayBlackout         = errors.New("bay is blocked in this timeframe")
+)
+
+const dateLayout = "2006-01-02"
+
+// parseClock parses "HH:MM" into minutes after midnight.
+func parseClock(s string) (int, error) {
+	t, err := time.Parse("15:04", s)
+	if err != nil {
+		return 0, err
+	}
+	return t.Hour()*60 + t.Minute(), nil
+}
+
+// atClock returns the wall-clock time minutes after midnight on day, in day's
+// location. Unlike day.Add it keeps the clock time on DST change days.
+func atClock(day time.Time, minutes int) time.Time {
+	return time.Date(day.Year(), day.Month(), day.Day(), minutes/60, minutes%60, 0, 0, day.Location())
+}
+
+// ValidateShopCalendar checks clock formats, weekday uniqueness and holiday dates.
+func ValidateShopCalendar(cal models.ShopCalendar) error {
+	switch cal.Mode {
+	case "", models.CalendarOff, models.CalendarWarn, models.CalendarReject:
+	default:
+		return fmt.Errorf("%w: unknown mode %q", ErrInvalidCalendar, cal.Mode)
+	}
+	seen := map[time.Weekday]bool{}
+	for _, d := range cal.WeeklyHours {
+		if d.Weekday < time.Sunday || d.Weekday > time.Saturday || seen[d.Weekday] {
+			return fmt.Errorf("%w: weekday %d is invalid or repeated", ErrInvalidCalendar, d.Weekday)
+		}
+		seen[d.Weekday] = true
+		if d.Closed {
+			continue
+		}
+		open, err1 := parseClock(d.Open)
+		closeAt, err2 := parseClock(d.Close)
+		if err1 != nil || err2 != nil || closeAt <= open {
+			return fmt.Errorf("%w: hours for %s must be HH:MM with close after open", ErrInvalidCalendar, d.Weekday)
+		}
+	}
+	for _, hd := range cal.Holidays {
+		if _, err := time.Parse(dateLayout, hd.Date); err != nil {
+			return fmt.Errorf("%w: holiday date %q must be YYYY-MM-DD", ErrInvalidCalendar, hd.Date)
+		}
+	}
+	return nil
+}
+
+// holidayName returns the holiday name for the calendar day of t, if any.
+func holidayName(cal models.ShopCalendar, t time.Time) (string, bool) {
+	day := t.Format(dateLayout)
+	for _, hd := range cal.Holidays {
+		if hd.Date == day {
+			return hd.Name, true
+		}
+	}
+	return "", false
+}
+
+// WorkingWindows returns the opening hours inside [from, to) in loc, skipping
+// holidays and closed weekdays. A calendar without weekly hours is treated as
+// always open (holidays still apply).
+func WorkingWindows(cal models.ShopCalendar, from, to time.Time, loc *time.Location) []Window {
+	hours := map[time.Weekday]models.OpeningHours{}
+	for _, d := range cal.WeeklyHours {
+		hours[d.Weekday] = d
+	}
+	from, to = from.In(loc), to.In(loc)
+	var out []Window
+	day := time.Date(from.Year(), from.Month(), from.Day(), 0, 0, 0, 0, loc)
+	for ; day.Before(to); day = day.AddDate(0, 0, 1) {
+		if _, ok := holidayName(cal, day); ok {
+			continue
+		}
+		var w Window
+		if len(cal.WeeklyHours) == 0 {
+			w = Window{Start: day, End: day.AddDate(0, 0, 1)}
+		} else {
+			d, ok := hours[day.Weekday()]
+			if !ok || d.Closed {
+				continue
+			}
+			open, err1 := parseClock(d.Open)
+			closeAt, err2 := parseClock(d.Close)
+			if err1 != nil || err2 != nil {
+				continue
+			}
+			w = Window{Start: atClock(day, open), End: atClock(day, closeAt)}
+		}
+		if w.Start.Before(from) {
+			w.Start = from
+		}
+		if w.End.After(to) {
+			w.End = to
+		}
+		if w.Start.Before(w.End) {
+			out = append(out, w)
+		}
+	}
+	return MergeWindows(out)
+}
+
+// CheckWorkingTime verifies that a booking starts during opening hours and,
+// when it has an end, finishes during opening hours (closing time included).
+// Multi-day jobs are allowed; the unit may sit in the bay overnight.
+func CheckWorkingTime(cal models.ShopCalendar, b models.Booking, loc *time.Location) error {
+	if cal.Mode == "" || cal.Mode == models.CalendarOff {
+		return nil
+	}
+	points := []time.Time{b.Start}
+	if b.End != nil {
+		points = append(points, b.End.Add(-time.Minute))
+	}
+	for _, p := range points {
+		p = p.In(loc)
+		if name, ok := holidayName(cal, p); ok {
+			if name != "" {
+				return fmt.Errorf("%w (%s %s)", ErrShopHoliday, p.Format(dateLayout), name)
+			}
+			return fmt.Errorf("%w (%s)", ErrShopHoliday, p.Format(dateLayout))
+		}
+		inside := false
+		for _, w := range WorkingWindows(cal, p, p.Add(time.Minute), loc) {
+			if !p.Before(w.Start) && p.Before(w.End) {
+				inside = true
+				break
+			}
+		}
+		if !inside {
+			return fmt.Errorf("%w (%s)", ErrOutsideWorkingHours, p.Format("Mon 01/02/2006 03:04 PM"))
+		}
+	}
+	return nil
+}
+
+// BlackoutWindows converts blackouts into busy windows.
+func BlackoutWindows(blackouts []models.BayBlackout) []Window {
+	out := make([]Window, 0, len(blackouts))
+	for _, bl := range blackouts {
+		out = append(out, Window{Start: bl.Start, End: bl.End})
+	}
+	return out
+}
+
+// ValidateBlackouts returns ErrBayBlackout when b overlaps a blackout of its bay.
+func ValidateBlackouts(b models.Booking, blackouts []models.BayBlackout) error {
+	end := EffectiveEnd(b)
+	for _, bl := range blackouts {
+		if bl.BayID != b.BayID {
+			continue
+		}
+		if b.Start.Before(bl.End) && bl.Start.Before(end) {
+			if bl.Reason != "" {
+				return fmt.Errorf("%w: %s", ErrBayBlackout, bl.Reason)
+			}
+			return ErrBayBlackout
+		}
+	}
+	return nil
+}
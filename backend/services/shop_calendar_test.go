@@ -0,0 +1,109 @@
+package services
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/tss-booking-system/backend/models"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func testCalendar(mode models.CalendarMode) models.ShopCalendar {
+	return models.ShopCalendar{
+		Mode: mode,
+		WeeklyHours: []models.OpeningHours{
+			{Weekday: time.Monday, Open: "08:00", Close: "18:00"},
+			{Weekday: time.Tuesday, Open: "08:00", Close: "18:00"},
+			{Weekday: time.Sunday, Closed: true},
+		},
+		Holidays: []models.Holiday{{Date: "2024-12-24", Name: "Christmas Eve"}},
+	}
+}
+
+func TestCheckWorkingTime(t *testing.T) {
+	loc := time.UTC
+	cal := testCalendar(models.CalendarReject)
+	mon := time.Date(2024, 12, 16, 0, 0, 0, 0, loc) // Monday
+
+	end := mon.Add(18 * time.Hour)
+	ok := models.Booking{Start: mon.Add(9 * time.Hour), End: &end}
+	if err := CheckWorkingTime(cal, ok, loc); err != nil {
+		t.Fatalf("expected booking ending at close to pass, got %v", err)
+	}
+
+	night := models.Booking{Start: mon.Add(3 * time.Hour)}
+	if err := CheckWorkingTime(cal, night, loc); !errors.Is(err, ErrOutsideWorkingHours) {
+		t.Fatalf("expected ErrOutsideWorkingHours, got %v", err)
+	}
+
+	sunday := models.Booking{Start: mon.AddDate(0, 0, 6).Add(10 * time.Hour)}
+	if err := CheckWorkingTime(cal, sunday, loc); !errors.Is(err, ErrOutsideWorkingHours) {
+		t.Fatalf("expected closed weekday to fail, got %v", err)
+	}
+
+	holiday := models.Booking{Start: time.Date(2024, 12, 24, 10, 0, 0, 0, loc)}
+	if err := CheckWorkingTime(cal, holiday, loc); !errors.Is(err, ErrShopHoliday) {
+		t.Fatalf("expected ErrShopHoliday, got %v", err)
+	}
+
+	if err := CheckWorkingTime(testCalendar(models.CalendarOff), night, loc); err != nil {
+		t.Fatalf("expected calendar off to allow anything, got %v", err)
+	}
+}
+
+func TestWorkingWindows(t *testing.T) {
+	loc := time.UTC
+	cal := testCalendar(models.CalendarWarn)
+	from := time.Date(2024, 12, 22, 12, 0, 0, 0, loc) // Sunday noon
+	to := time.Date(2024, 12, 25, 0, 0, 0, 0, loc)
+	got := WorkingWindows(cal, from, to, loc)
+	// Sunday closed, Tuesday 24th is a holiday: only Monday remains
+	if len(got) != 1 {
+		t.Fatalf("expected 1 window, got %v", got)
+	}
+	if got[0].Start.Hour() != 8 || got[0].End.Hour() != 18 || got[0].Start.Day() != 23 {
+		t.Fatalf("unexpected window %v", got[0])
+	}
+}
+
+func TestWorkingWindowsDST(t *testing.T) {
+	loc, err := time.LoadLocation("America/Chicago")
+	if err != nil {
+		t.Skip("time zone data not available")
+	}
+	cal := models.ShopCalendar{
+		Mode:        models.CalendarWarn,
+		WeeklyHours: []models.OpeningHours{{Weekday: time.Sunday, Open: "08:00", Close: "18:00"}},
+	}
+	// clocks go forward on 2026-03-08 and back on 2026-11-01, both Sundays
+	for _, date := range []time.Time{
+		time.Date(2026, 3, 8, 0, 0, 0, 0, loc),
+		time.Date(2026, 11, 1, 0, 0, 0, 0, loc),
+	} {
+		got := WorkingWindows(cal, date, date.AddDate(0, 0, 1), loc)
+		if len(got) != 1 || got[0].Start.Hour() != 8 || got[0].End.Hour() != 18 {
+			t.Fatalf("%s: expected 08:00-18:00, got %v", date.Format("2006-01-02"), got)
+		}
+	}
+}
+
+func TestValidateBlackouts(t *testing.T) {
+	bay := primitive.NewObjectID()
+	base := time.Date(2024, 12, 16, 8, 0, 0, 0, time.UTC)
+	blackouts := []models.BayBlackout{{BayID: bay, Start: base, End: base.Add(4 * time.Hour), Reason: "calibration"}}
+
+	end := base.Add(5 * time.Hour)
+	after := models.Booking{BayID: bay, Start: base.Add(4 * time.Hour), End: &end}
+	if err := ValidateBlackouts(after, blackouts); err != nil {
+		t.Fatalf("expected booking after blackout to pass, got %v", err)
+	}
+	inside := models.Booking{BayID: bay, Start: base.Add(time.Hour)}
+	if err := ValidateBlackouts(inside, blackouts); !errors.Is(err, ErrBayBlackout) {
+		t.Fatalf("expected ErrBayBlackout, got %v", err)
+	}
+	other := models.Booking{BayID: primitive.NewObjectID(), Start: base.Add(time.Hour)}
+	if err := ValidateBlackouts(other, blackouts); err != nil {
+		t.Fatalf("expected other bay to pass, got %v", err)
+	}
+}
@@ -0,0 +1,172 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/tss-booking-system/backend/models"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// SuggestionRequest describes the work a technician is suggested for.
+// RequiredSkills usually come from the job type; Text (complaint,
+// description, job lines) is scanned for technician skill keywords.
+type SuggestionRequest struct {
+	RequiredSkills []string
+	Text           string
+	Start          time.Time
+	End            time.Time
+	// IgnoreBookingID excludes the booking being staffed from workload and conflicts.
+	IgnoreBookingID primitive.ObjectID
+	// Now is when the ranking is made; see HeldUntil.
+	Now time.Time
+}
+
+// TechnicianCandidate is a technician with the data needed for ranking.
+type TechnicianCandidate struct {
+	Technician models.Technician
+	Bookings   []models.Booking
+	TimeOff    []models.TimeOff
+}
+
+// TechnicianSuggestion is one ranked technician with the reasons for its score.
+type TechnicianSuggestion struct {
+	TechnicianID  string   `json:"technician_id"`
+	Name          string   `json:"name"`
+	Skills        []string `json:"skills"`
+	Score         int      `json:"score"`
+	MatchedSkills []string `json:"matched_skills"`
+	MissingSkills []string `json:"missing_skills"`
+	Available     bool     `json:"available"`
+	BookedMinutes int      `json:"booked_minutes"`
+	Reasons       []string `json:"reasons"`
+}
+
+const (
+	scoreRequiredSkills = 50 // share of required skills matched
+	scoreKeyword        = 10 // per skill mentioned in the text, up to scoreKeywordMax
+	scoreKeywordMax     = 20
+	scoreAvailable      = 20
+	penaltyBusy         = 40
+	penaltyOnLeave      = 60
+	penaltyOffShift     = 20
+	penaltyPerHour      = 2 // per booked hour on the same day, up to penaltyWorkloadMax
+	penaltyWorkloadMax  = 20
+)
+
+// RankTechnicians scores candidates by skill match, availability in the
+// requested window and workload on that day, best first.
+func RankTechnicians(req SuggestionRequest, candidates []TechnicianCandidate, loc *time.Location) []TechnicianSuggestion {
+	text := strings.ToLower(req.Text)
+	window := models.Booking{Start: req.Start, End: &req.End}
+	dayStart := time.Date(req.Start.In(loc).Year(), req.Start.In(loc).Month(), req.Start.In(loc).Day(), 0, 0, 0, 0, loc)
+	day := Window{Start: dayStart, End: dayStart.AddDate(0, 0, 1)}
+
+	out := make([]TechnicianSuggestion, 0, len(candidates))
+	for _, cand := range candidates {
+		t := cand.Technician
+		s := TechnicianSuggestion{
+			TechnicianID:  t.ID.Hex(),
+			Name:          t.Name,
+			Skills:        t.Skills,
+			MatchedSkills: []string{},
+			MissingSkills: []string{},
+			Available:     true,
+		}
+		has := map[string]bool{}
+		for _, sk := range t.Skills {
+			has[strings.ToLower(sk)] = true
+		}
+
+		// Skills required by the job type
+		if len(req.RequiredSkills) > 0 {
+			for _, sk := range req.RequiredSkills {
+				if has[strings.ToLower(sk)] {
+					s.MatchedSkills = append(s.MatchedSkills, sk)
+				} else {
+					s.MissingSkills = append(s.MissingSkills, sk)
+				}
+			}
+			s.Score += scoreRequiredSkills * len(s.MatchedSkills) / len(req.RequiredSkills)
+			if len(s.MissingSkills) == 0 {
+				s.Reasons = append(s.Reasons, "has all required skills")
+			} else {
+				s.Reasons = append(s.Reasons, fmt.Sprintf("missing skills: %s", strings.Join(s.MissingSkills, ", ")))
+			}
+		}
+
+		// Skills mentioned in the complaint / job text
+		if text != "" {
+			keyword := 0
+			for _, sk := range t.Skills {
+				if sk != "" && strings.Contains(text, strings.ToLower(sk)) {
+					keyword += scoreKeyword
+					s.Reasons = append(s.Reasons, fmt.Sprintf("skill %q mentioned in the complaint", sk))
+				}
+			}
+			if keyword > scoreKeywordMax {
+				keyword = scoreKeywordMax
+			}
+			s.Score += keyword
+		}
+
+		// Availability in the window
+		for _, b := range cand.Bookings {
+			if !isActive(b) || b.ID == req.IgnoreBookingID {
+				continue
+			}
+			if overlaps(window, b, req.Now) {
+				s.Available = false
+				s.Score -= penaltyBusy
+				s.Reasons = append(s.Reasons, fmt.Sprintf("already booked on #%s at that time", b.Number))
+				break
+			}
+		}
+		if err := CheckTechnicianSchedule(t, cand.TimeOff, window, loc); err != nil {
+			s.Available = false
+			if errors.Is(err, ErrTechnicianOnLeave) {
+				s.Score -= penaltyOnLeave
+				s.Reasons = append(s.Reasons, "on leave during the window")
+			} else {
+				s.Score -= penaltyOffShift
+				s.Reasons = append(s.Reasons, "off shift during the window")
+			}
+		}
+		if s.Available {
+			s.Score += scoreAvailable
+			s.Reasons = append(s.Reasons, "free for the whole window")
+		}
+
+		// Workload on the same day
+		var busy []Window
+		for _, b := range cand.Bookings {
+			if isActive(b) && b.ID != req.IgnoreBookingID {
+				busy = append(busy, Window{Start: b.Start, End: EffectiveEnd(b)})
+			}
+		}
+		for _, w := range IntersectWindows(MergeWindows(busy), []Window{day}) {
+			s.BookedMinutes += int(w.Duration() / time.Minute)
+		}
+		if s.BookedMinutes > 0 {
+			penalty := penaltyPerHour * s.BookedMinutes / 60
+			if penalty > penaltyWorkloadMax {
+				penalty = penaltyWorkloadMax
+			}
+			s.Score -= penalty
+			s.Reasons = append(s.Reasons, fmt.Sprintf("%.1fh already booked that day", float64(s.BookedMinutes)/60))
+		} else {
+			s.Reasons = append(s.Reasons, "no other work that day")
+		}
+		out = append(out, s)
+	}
+	sort.SliceStable(out, func(i, j int) bool {
+		if out[i].Score != out[j].Score {
+			return out[i].Score > out[j].Score
+		}
+		return out[i].Name < out[j].Name
+	})
+	return out
+}
@@ -0,0 +1,50 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"github.com/tss-booking-system/backend/models"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestRankTechnicians(t *testing.T) {
+	loc := time.UTC
+	start := time.Date(2026, 3, 2, 9, 0, 0, 0, loc)
+	end := start.Add(2 * time.Hour)
+	brakes := models.Technician{ID: primitive.NewObjectID(), Name: "Anna", Skills: []string{"brakes", "dot"}}
+	busy := models.Technician{ID: primitive.NewObjectID(), Name: "Bob", Skills: []string{"brakes", "dot"}}
+	electric := models.Technician{ID: primitive.NewObjectID(), Name: "Carl", Skills: []string{"electrical"}}
+	onLeave := models.Technician{ID: primitive.NewObjectID(), Name: "Dana", Skills: []string{"brakes", "dot"}}
+
+	bobEnd := start.Add(3 * time.Hour)
+	candidates := []TechnicianCandidate{
+		{Technician: electric},
+		{Technician: busy, Bookings: []models.Booking{{ID: primitive.NewObjectID(), Number: "000007", Start: start.Add(time.Hour), End: &bobEnd, Status: models.BookingOpen}}},
+		{Technician: onLeave, TimeOff: []models.TimeOff{{TechnicianID: onLeave.ID, Start: start.Add(-time.Hour), End: end, Kind: "vacation"}}},
+		{Technician: brakes},
+	}
+	got := RankTechnicians(SuggestionRequest{
+		RequiredSkills: []string{"brakes"},
+		Text:           "DOT inspection, brakes squeal",
+		Start:          start,
+		End:            end,
+	}, candidates, loc)
+
+	order := []string{got[0].Name, got[1].Name, got[2].Name, got[3].Name}
+	want := []string{"Anna", "Bob", "Carl", "Dana"}
+	for i := range want {
+		if order[i] != want[i] {
+			t.Fatalf("expected order %v, got %v", want, order)
+		}
+	}
+	if !got[0].Available || len(got[0].Reasons) == 0 {
+		t.Fatalf("expected top suggestion to be available with reasons, got %+v", got[0])
+	}
+	if got[1].Available || got[1].BookedMinutes != 120 {
+		t.Fatalf("expected Bob to be busy with 120 booked minutes, got %+v", got[1])
+	}
+	if len(got[2].MissingSkills) != 1 {
+		t.Fatalf("expected Carl to miss brakes, got %+v", got[2])
+	}
+}
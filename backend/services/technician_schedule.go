@@ -0,0 +1,111 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/tss-booking-system/backend/models"
+)
+
+var (
+	ErrInvalidShift       = errors.New("invalid shift")
+	ErrTechnicianOffShift = errors.New("technician is off shift")
+	ErrTechnicianOnLeave  = errors.New("technician is on leave")
+)
+
+// ValidateShifts checks weekdays and "HH:MM" times. Start and end must differ;
+// an end before the start means the shift runs past midnight.
+func ValidateShifts(shifts []models.Shift) error {
+	for _, s := range shifts {
+		if s.Weekday < time.Sunday || s.Weekday > time.Saturday {
+			return fmt.Errorf("%w: weekday %d", ErrInvalidShift, s.Weekday)
+		}
+		start, err1 := parseClock(s.Start)
+		end, err2 := parseClock(s.End)
+		if err1 != nil || err2 != nil || start == end {
+			return fmt.Errorf("%w: %s shift must be HH:MM-HH:MM", ErrInvalidShift, s.Weekday)
+		}
+	}
+	return nil
+}
+
+// ShiftWindows returns on-shift time inside [from, to) in loc. Without shifts
+// the whole range is returned.
+func ShiftWindows(shifts []models.Shift, from, to time.Time, loc *time.Location) []Window {
+	if len(shifts) == 0 {
+		return []Window{{Start: from, End: to}}
+	}
+	from, to = from.In(loc), to.In(loc)
+	var out []Window
+	// start a day early so overnight shifts reaching into the range count
+	day := time.Date(from.Year(), from.Month(), from.Day(), 0, 0, 0, 0, loc).AddDate(0, 0, -1)
+	for ; day.Before(to); day = day.AddDate(0, 0, 1) {
+		for _, s := range shifts {
+			if s.Weekday != day.Weekday() {
+				continue
+			}
+			start, err1 := parseClock(s.Start)
+			end, err2 := parseClock(s.End)
+			if err1 != nil || err2 != nil || start == end {
+				continue
+			}
+			w := Window{Start: atClock(day, start), End: atClock(day, end)}
+			if end < start {
+				w.End = atClock(day.AddDate(0, 0, 1), end)
+			}
+			if w.Start.Before(from) {
+				w.Start = from
+			}
+			if w.End.After(to) {
+				w.End = to
+			}
+			if w.Start.Before(w.End) {
+				out = append(out, w)
+			}
+		}
+	}
+	return MergeWindows(out)
+}
+
+// TimeOffWindows converts time off entries into busy windows.
+func TimeOffWindows(items []models.TimeOff) []Window {
+	out := make([]Window, 0, len(items))
+	for _, t := range items {
+		out = append(out, Window{Start: t.Start, End: t.End})
+	}
+	return out
+}
+
+// CheckTechnicianSchedule reports whether tech can work booking b: it must
+// not overlap the technician's time off, and when shifts are set the booking
+// must start and end on shift (like the shop calendar check).
+func CheckTechnicianSchedule(tech models.Technician, timeOff []models.TimeOff, b models.Booking, loc *time.Location) error {
+	end := EffectiveEnd(b)
+	for _, t := range timeOff {
+		if t.TechnicianID != tech.ID || !b.Start.Before(t.End) || !t.Start.Before(end) {
+			continue
+		}
+		label := t.Kind
+		if label == "" {
+			label = t.Reason
+		}
+		if label != "" {
+			return fmt.Errorf("%w: %s (%s)", ErrTechnicianOnLeave, tech.Name, label)
+		}
+		return fmt.Errorf("%w: %s", ErrTechnicianOnLeave, tech.Name)
+	}
+	if len(tech.Shifts) == 0 {
+		return nil
+	}
+	points := []time.Time{b.Start}
+	if b.End != nil {
+		points = append(points, b.End.Add(-time.Minute))
+	}
+	for _, p := range points {
+		if len(ShiftWindows(tech.Shifts, p, p.Add(time.Minute), loc)) == 0 {
+			return fmt.Errorf("%w: %s (%s)", ErrTechnicianOffShift, tech.Name, p.In(loc).Format("Mon 01/02/2006 03:04 PM"))
+		}
+	}
+	return nil
+}
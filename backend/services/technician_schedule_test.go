@@ -0,0 +1,70 @@
+package services
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/tss-booking-system/backend/models"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestShiftWindowsOvernight(t *testing.T) {
+	loc := time.UTC
+	shifts := []models.Shift{{Weekday: time.Monday, Start: "22:00", End: "06:00"}}
+	from := time.Date(2026, 3, 3, 0, 0, 0, 0, loc) // Tuesday
+	to := from.Add(24 * time.Hour)
+	got := ShiftWindows(shifts, from, to, loc)
+	if len(got) != 1 || !got[0].Start.Equal(from) || !got[0].End.Equal(from.Add(6*time.Hour)) {
+		t.Fatalf("expected Monday night shift to reach into Tuesday, got %v", got)
+	}
+}
+
+func TestShiftWindowsDST(t *testing.T) {
+	loc, err := time.LoadLocation("America/Chicago")
+	if err != nil {
+		t.Skip("time zone data not available")
+	}
+	// clocks go forward at 2:00 on Sunday 2026-03-08
+	shifts := []models.Shift{
+		{Weekday: time.Sunday, Start: "07:00", End: "15:00"},
+		{Weekday: time.Saturday, Start: "22:00", End: "06:00"},
+	}
+	from := time.Date(2026, 3, 8, 0, 0, 0, 0, loc)
+	got := ShiftWindows(shifts, from, from.AddDate(0, 0, 1), loc)
+	if len(got) != 2 {
+		t.Fatalf("expected two windows, got %v", got)
+	}
+	if got[0].End.Hour() != 6 || got[1].Start.Hour() != 7 || got[1].End.Hour() != 15 {
+		t.Fatalf("expected shifts to keep their clock times, got %v", got)
+	}
+}
+
+func TestCheckTechnicianSchedule(t *testing.T) {
+	loc := time.UTC
+	tech := models.Technician{
+		ID:     primitive.NewObjectID(),
+		Name:   "Mike",
+		Shifts: []models.Shift{{Weekday: time.Monday, Start: "07:00", End: "15:30"}},
+	}
+	mon := time.Date(2026, 3, 2, 0, 0, 0, 0, loc)
+	end := mon.Add(15*time.Hour + 30*time.Minute)
+	onShift := models.Booking{Start: mon.Add(8 * time.Hour), End: &end}
+	if err := CheckTechnicianSchedule(tech, nil, onShift, loc); err != nil {
+		t.Fatalf("expected on-shift booking to pass, got %v", err)
+	}
+
+	late := models.Booking{Start: mon.Add(16 * time.Hour)}
+	if err := CheckTechnicianSchedule(tech, nil, late, loc); !errors.Is(err, ErrTechnicianOffShift) {
+		t.Fatalf("expected ErrTechnicianOffShift, got %v", err)
+	}
+
+	vacation := []models.TimeOff{{TechnicianID: tech.ID, Start: mon, End: mon.AddDate(0, 0, 5), Kind: "vacation"}}
+	if err := CheckTechnicianSchedule(tech, vacation, onShift, loc); !errors.Is(err, ErrTechnicianOnLeave) {
+		t.Fatalf("expected ErrTechnicianOnLeave, got %v", err)
+	}
+
+	if err := ValidateShifts([]models.Shift{{Weekday: time.Friday, Start: "08:00", End: "08:00"}}); !errors.Is(err, ErrInvalidShift) {
+		t.Fatalf("expected ErrInvalidShift, got %v", err)
+	}
+}
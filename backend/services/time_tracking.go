@@ -0,0 +1,52 @@
+package services
+
+import (
+	"time"
+
+	"github.com/tss-booking-system/backend/models"
+)
+
+// LaborTotals summarizes clocked labor in minutes. Running clocks count up to
+// the time the totals are computed.
+type LaborTotals struct {
+	TotalMinutes int            `json:"total_minutes"`
+	ActiveClocks int            `json:"active_clocks"`
+	ByTechnician map[string]int `json:"by_technician"`
+	ByBooking    map[string]int `json:"by_booking"`
+	ByJob        map[string]int `json:"by_job"`
+}
+
+// EntryMinutes returns the length of a time entry in whole minutes; entries
+// without an end are measured up to now.
+func EntryMinutes(e models.TimeEntry, now time.Time) int {
+	end := now
+	if e.End != nil {
+		end = *e.End
+	}
+	if !end.After(e.Start) {
+		return 0
+	}
+	return int(end.Sub(e.Start) / time.Minute)
+}
+
+// SumTimeEntries aggregates entries per technician, booking and job line.
+func SumTimeEntries(entries []models.TimeEntry, now time.Time) LaborTotals {
+	t := LaborTotals{
+		ByTechnician: map[string]int{},
+		ByBooking:    map[string]int{},
+		ByJob:        map[string]int{},
+	}
+	for _, e := range entries {
+		m := EntryMinutes(e, now)
+		if e.End == nil {
+			t.ActiveClocks++
+		}
+		t.TotalMinutes += m
+		t.ByTechnician[e.TechnicianID.Hex()] += m
+		t.ByBooking[e.BookingID.Hex()] += m
+		if e.JobID != nil {
+			t.ByJob[e.JobID.Hex()] += m
+		}
+	}
+	return t
+}
@@ -0,0 +1,31 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"github.com/tss-booking-system/backend/models"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestSumTimeEntries(t *testing.T) {
+	base := time.Date(2026, 3, 2, 8, 0, 0, 0, time.UTC)
+	techA, techB := primitive.NewObjectID(), primitive.NewObjectID()
+	booking := primitive.NewObjectID()
+	job := primitive.NewObjectID()
+	end := base.Add(90 * time.Minute)
+	entries := []models.TimeEntry{
+		{TechnicianID: techA, BookingID: booking, JobID: &job, Start: base, End: &end},
+		{TechnicianID: techB, BookingID: booking, Start: base.Add(time.Hour)},
+	}
+	got := SumTimeEntries(entries, base.Add(2*time.Hour))
+	if got.TotalMinutes != 150 || got.ActiveClocks != 1 {
+		t.Fatalf("expected 150 minutes and 1 active clock, got %+v", got)
+	}
+	if got.ByTechnician[techA.Hex()] != 90 || got.ByTechnician[techB.Hex()] != 60 {
+		t.Fatalf("unexpected per technician totals: %v", got.ByTechnician)
+	}
+	if got.ByJob[job.Hex()] != 90 || got.ByBooking[booking.Hex()] != 150 {
+		t.Fatalf("unexpected job/booking totals: %v %v", got.ByJob, got.ByBooking)
+	}
+}
@@ -0,0 +1,208 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/tss-booking-system/backend/models"
+)
+
+var ErrVINConflict = errors.New("units have different VINs; choose the VIN to keep")
+
+// VINConflictError lists the distinct VINs found among units being merged.
+type VINConflictError struct {
+	VINs []string
+}
+
+func (e *VINConflictError) Error() string {
+	return fmt.Sprintf("%s: %s", ErrVINConflict.Error(), strings.Join(e.VINs, ", "))
+}
+
+func (e *VINConflictError) Unwrap() error {
+	return ErrVINConflict
+}
+
+// NormalizeVIN upper-cases a VIN and drops spaces and dashes.
+func NormalizeVIN(vin string) string {
+	return strings.Map(func(r rune) rune {
+		if unicode.IsSpace(r) || r == '-' {
+			return -1
+		}
+		return unicode.ToUpper(r)
+	}, vin)
+}
+
+// unitNumberKey normalises a plate or unit number for comparison.
+func unitNumberKey(v string) string {
+	return strings.Map(func(r rune) rune {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			return unicode.ToUpper(r)
+		}
+		return -1
+	}, v)
+}
+
+// DuplicateGroup is a set of units that look like the same vehicle. Reasons
+// holds "vin" and/or "unit" (same plate or unit number within a company).
+type DuplicateGroup struct {
+	Reasons  []string         `json:"reasons"`
+	Vehicles []models.Vehicle `json:"vehicles"`
+}
+
+// FindDuplicateVehicles groups units sharing a VIN, or a plate or unit
+// number within the same company. Units linked through different keys end
+// up in one group. Groups and their units keep the input order.
+func FindDuplicateVehicles(vehicles []models.Vehicle) []DuplicateGroup {
+	parent := make([]int, len(vehicles))
+	for i := range parent {
+		parent[i] = i
+	}
+	var find func(int) int
+	find = func(i int) int {
+		if parent[i] != i {
+			parent[i] = find(parent[i])
+		}
+		return parent[i]
+	}
+	reasons := map[int]map[string]bool{}
+	firstByKey := map[string]int{}
+	link := func(i int, key, reason string) {
+		j, ok := firstByKey[key]
+		if !ok {
+			firstByKey[key] = i
+			return
+		}
+		ri, rj := find(i), find(j)
+		if ri != rj {
+			if rj > ri {
+				ri, rj = rj, ri
+			}
+			parent[ri] = rj
+		}
+		if reasons[j] == nil {
+			reasons[j] = map[string]bool{}
+		}
+		reasons[j][reason] = true
+	}
+	for i, v := range vehicles {
+		if vin := NormalizeVIN(v.VIN); vin != "" {
+			link(i, "vin:"+vin, "vin")
+		}
+		seen := map[string]bool{}
+		for _, n := range []string{v.Plate, v.Nickname} {
+			if k := unitNumberKey(n); k != "" && !seen[k] {
+				seen[k] = true
+				link(i, "unit:"+v.CompanyID.Hex()+":"+k, "unit")
+			}
+		}
+	}
+	members := map[int][]int{}
+	var roots []int
+	for i := range vehicles {
+		r := find(i)
+		if _, ok := members[r]; !ok {
+			roots = append(roots, r)
+		}
+		members[r] = append(members[r], i)
+	}
+	var groups []DuplicateGroup
+	for _, r := range roots {
+		idx := members[r]
+		if len(idx) < 2 {
+			continue
+		}
+		why := map[string]bool{}
+		g := DuplicateGroup{}
+		for _, i := range idx {
+			for reason := range reasons[i] {
+				why[reason] = true
+			}
+			g.Vehicles = append(g.Vehicles, vehicles[i])
+		}
+		for reason := range why {
+			g.Reasons = append(g.Reasons, reason)
+		}
+		sort.Strings(g.Reasons)
+		groups = append(groups, g)
+	}
+	return groups
+}
+
+// MergeVehicleFields fills the blank fields of target from sources, in
+// order. When the units carry different VINs, vin picks the one to keep;
+// without a valid choice a *VINConflictError is returned. The VINs and unit
+// numbers the merged unit does not keep become its aliases, so imports still
+// resolve them after the sources are purged.
+func MergeVehicleFields(target models.Vehicle, sources []models.Vehicle, vin string) (models.Vehicle, error) {
+	var vins []string
+	seen := map[string]bool{}
+	for _, v := range append([]models.Vehicle{target}, sources...) {
+		if n := NormalizeVIN(v.VIN); n != "" && !seen[n] {
+			seen[n] = true
+			vins = append(vins, n)
+		}
+	}
+	switch {
+	case len(vins) == 0:
+		target.VIN = ""
+	case len(vins) == 1:
+		target.VIN = vins[0]
+	case seen[NormalizeVIN(vin)]:
+		target.VIN = NormalizeVIN(vin)
+	default:
+		return target, &VINConflictError{VINs: vins}
+	}
+	for _, s := range sources {
+		if target.Type == "" {
+			target.Type = s.Type
+		}
+		if target.Plate == "" {
+			target.Plate = s.Plate
+		}
+		if target.Nickname == "" {
+			target.Nickname = s.Nickname
+		}
+		if target.Make == "" {
+			target.Make = s.Make
+		}
+		if target.Model == "" {
+			target.Model = s.Model
+		}
+		if target.Year == 0 {
+			target.Year = s.Year
+		}
+		if target.LengthFt == 0 {
+			target.LengthFt = s.LengthFt
+		}
+	}
+	vinAliases := append([]string(nil), vins...)
+	units := append([]string(nil), target.UnitAliases...)
+	for _, s := range sources {
+		vinAliases = append(vinAliases, s.VINAliases...)
+		units = append(units, s.Plate, s.Nickname)
+		units = append(units, s.UnitAliases...)
+	}
+	target.VINAliases = mergeAliases(target.VINAliases, vinAliases, NormalizeVIN, target.VIN)
+	target.UnitAliases = mergeAliases(nil, units, strings.TrimSpace, target.Plate, target.Nickname)
+	return target, nil
+}
+
+// mergeAliases appends the normalised values to aliases, skipping blanks,
+// duplicates and the values kept on the record itself.
+func mergeAliases(aliases, values []string, norm func(string) string, kept ...string) []string {
+	seen := map[string]bool{"": true}
+	for _, k := range kept {
+		seen[norm(k)] = true
+	}
+	var out []string
+	for _, v := range append(append([]string(nil), aliases...), values...) {
+		if n := norm(v); !seen[n] {
+			seen[n] = true
+			out = append(out, n)
+		}
+	}
+	return out
+}
@@ -0,0 +1,117 @@
+package services
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/tss-booking-system/backend/models"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestNormalizeVIN(t *testing.T) {
+	if got := NormalizeVIN(" 1ftfw1e5-0nfa 00001 "); got != "1FTFW1E50NFA00001" {
+		t.Fatalf("unexpected VIN %q", got)
+	}
+}
+
+func TestFindDuplicateVehicles(t *testing.T) {
+	acme, other := primitive.NewObjectID(), primitive.NewObjectID()
+	vehicles := []models.Vehicle{
+		{ID: primitive.NewObjectID(), CompanyID: acme, VIN: "1FTFW1E50NFA00001", Nickname: "101"},
+		{ID: primitive.NewObjectID(), CompanyID: other, VIN: "1ftfw1e50nfa00001"},
+		{ID: primitive.NewObjectID(), CompanyID: acme, Plate: "Unit-101"},
+		{ID: primitive.NewObjectID(), CompanyID: other, Nickname: "101"},
+		{ID: primitive.NewObjectID(), CompanyID: acme, Nickname: "202"},
+		{ID: primitive.NewObjectID(), CompanyID: acme, Plate: "202 "},
+		{ID: primitive.NewObjectID(), CompanyID: acme, Nickname: "303"},
+	}
+	groups := FindDuplicateVehicles(vehicles)
+	if len(groups) != 2 {
+		t.Fatalf("expected 2 groups, got %d: %+v", len(groups), groups)
+	}
+	// unit 101 at acme shares the VIN with the second unit; the plate
+	// "Unit-101" does not match "101", and unit 101 at other is another
+	// company's unit number.
+	first := groups[0]
+	if len(first.Vehicles) != 2 || first.Vehicles[0].ID != vehicles[0].ID || first.Vehicles[1].ID != vehicles[1].ID {
+		t.Fatalf("unexpected first group %+v", first)
+	}
+	if len(first.Reasons) != 1 || first.Reasons[0] != "vin" {
+		t.Fatalf("expected vin reason, got %v", first.Reasons)
+	}
+	second := groups[1]
+	if len(second.Vehicles) != 2 || second.Vehicles[0].ID != vehicles[4].ID || second.Vehicles[1].ID != vehicles[5].ID {
+		t.Fatalf("unexpected second group %+v", second)
+	}
+	if len(second.Reasons) != 1 || second.Reasons[0] != "unit" {
+		t.Fatalf("expected unit reason, got %v", second.Reasons)
+	}
+}
+
+func TestFindDuplicateVehiclesChains(t *testing.T) {
+	acme := primitive.NewObjectID()
+	vehicles := []models.Vehicle{
+		{ID: primitive.NewObjectID(), CompanyID: acme, VIN: "VIN1", Nickname: "7"},
+		{ID: primitive.NewObjectID(), CompanyID: acme, Nickname: "7", Plate: "ABC123"},
+		{ID: primitive.NewObjectID(), CompanyID: acme, Plate: "abc-123", VIN: "VIN1"},
+	}
+	groups := FindDuplicateVehicles(vehicles)
+	if len(groups) != 1 || len(groups[0].Vehicles) != 3 {
+		t.Fatalf("expected one group of 3, got %+v", groups)
+	}
+	if len(groups[0].Reasons) != 2 {
+		t.Fatalf("expected vin and unit reasons, got %v", groups[0].Reasons)
+	}
+}
+
+func TestMergeVehicleFields(t *testing.T) {
+	target := models.Vehicle{Nickname: "101", Make: "Freightliner"}
+	sources := []models.Vehicle{
+		{VIN: "vin-a", Make: "Volvo", Model: "VNL", Year: 2019},
+		{Plate: "ABC123", Year: 2020, LengthFt: 53},
+	}
+	got, err := MergeVehicleFields(target, sources, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.VIN != "VINA" || got.Make != "Freightliner" || got.Model != "VNL" || got.Year != 2019 || got.Plate != "ABC123" || got.LengthFt != 53 {
+		t.Fatalf("unexpected merge %+v", got)
+	}
+
+	target.VIN = "VINB"
+	_, err = MergeVehicleFields(target, sources, "")
+	var conflict *VINConflictError
+	if !errors.As(err, &conflict) || !errors.Is(err, ErrVINConflict) || len(conflict.VINs) != 2 {
+		t.Fatalf("expected VIN conflict, got %v", err)
+	}
+	if _, err := MergeVehicleFields(target, sources, "VINC"); !errors.Is(err, ErrVINConflict) {
+		t.Fatalf("a VIN not on any unit must not resolve the conflict, got %v", err)
+	}
+	got, err = MergeVehicleFields(target, sources, "vin a")
+	if err != nil || got.VIN != "VINA" {
+		t.Fatalf("expected chosen VIN, got %q, %v", got.VIN, err)
+	}
+}
+
+func TestMergeVehicleFieldsKeepsAliases(t *testing.T) {
+	target := models.Vehicle{VIN: "VINA", Nickname: "101", UnitAliases: []string{"99"}}
+	sources := []models.Vehicle{
+		{VIN: "vin-b", Nickname: "101", Plate: "ABC123"},
+		{Nickname: "T-7", VINAliases: []string{"VINC"}, UnitAliases: []string{"99", "100"}},
+	}
+	got, err := MergeVehicleFields(target, sources, "VINA")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(got.VINAliases, ",") != "VINB,VINC" {
+		t.Fatalf("unexpected VIN aliases %v", got.VINAliases)
+	}
+	// ABC123 became the plate of the merged unit, so it is not an alias
+	if got.Plate != "ABC123" || strings.Join(got.UnitAliases, ",") != "99,T-7,100" {
+		t.Fatalf("unexpected unit aliases %v (plate %q)", got.UnitAliases, got.Plate)
+	}
+	if len(target.UnitAliases) != 1 {
+		t.Fatalf("target aliases must not be modified, got %v", target.UnitAliases)
+	}
+}
@@ -0,0 +1,33 @@
+package services
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+)
+
+var (
+	ErrStaleVersion   = errors.New("document was changed by someone else; reload and merge your changes")
+	ErrInvalidIfMatch = errors.New("If-Match must be a single version ETag")
+)
+
+// FormatETag renders a document version as a strong ETag.
+func FormatETag(version int) string {
+	return `"` + strconv.Itoa(version) + `"`
+}
+
+// ParseIfMatch reads the version from an If-Match header. Quoted, weak and
+// bare values are accepted; an empty header or "*" means no version check.
+func ParseIfMatch(header string) (int, bool, error) {
+	v := strings.TrimSpace(header)
+	if v == "" || v == "*" {
+		return 0, false, nil
+	}
+	v = strings.TrimPrefix(v, "W/")
+	v = strings.Trim(v, `"`)
+	n, err := strconv.Atoi(v)
+	if err != nil || n < 0 {
+		return 0, false, ErrInvalidIfMatch
+	}
+	return n, true, nil
+}
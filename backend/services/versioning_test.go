@@ -0,0 +1,36 @@
+package services
+
+import "testing"
+
+func TestParseIfMatch(t *testing.T) {
+	cases := []struct {
+		header  string
+		version int
+		checked bool
+		wantErr bool
+	}{
+		{header: "", checked: false},
+		{header: "*", checked: false},
+		{header: `"3"`, version: 3, checked: true},
+		{header: `W/"7"`, version: 7, checked: true},
+		{header: "0", version: 0, checked: true},
+		{header: `"abc"`, wantErr: true},
+		{header: `"1", "2"`, wantErr: true},
+		{header: "-1", wantErr: true},
+	}
+	for _, tc := range cases {
+		v, ok, err := ParseIfMatch(tc.header)
+		if (err != nil) != tc.wantErr {
+			t.Fatalf("%q: unexpected error %v", tc.header, err)
+		}
+		if tc.wantErr {
+			continue
+		}
+		if v != tc.version || ok != tc.checked {
+			t.Fatalf("%q: expected (%d, %v), got (%d, %v)", tc.header, tc.version, tc.checked, v, ok)
+		}
+	}
+	if got, _, _ := ParseIfMatch(FormatETag(12)); got != 12 {
+		t.Fatalf("expected ETag round trip, got %d", got)
+	}
+}
@@ -0,0 +1,134 @@
+package services
+
+import (
+	"bytes"
+	_ "embed"
+	"encoding/csv"
+	"errors"
+	"strings"
+	"time"
+)
+
+var (
+	ErrVINLength     = errors.New("a VIN must be 17 characters")
+	ErrVINCharacters = errors.New("a VIN may only contain digits and letters other than I, O and Q")
+	ErrVINCheckDigit = errors.New("VIN check digit does not match")
+)
+
+// wmiCSV maps world manufacturer identifiers (the first three VIN
+// characters) to manufacturers common in North American truck fleets.
+//
+//go:embed wmi.csv
+var wmiCSV []byte
+
+// WMIEntry is one manufacturer from the embedded WMI table.
+type WMIEntry struct {
+	Manufacturer string
+	Make         string
+}
+
+var wmiTable = loadWMITable()
+
+func loadWMITable() map[string]WMIEntry {
+	rows, err := csv.NewReader(bytes.NewReader(wmiCSV)).ReadAll()
+	if err != nil {
+		panic("services: invalid wmi.csv: " + err.Error())
+	}
+	table := make(map[string]WMIEntry, len(rows))
+	for _, row := range rows[1:] {
+		table[row[0]] = WMIEntry{Manufacturer: row[1], Make: row[2]}
+	}
+	return table
+}
+
+// vinWeights are the per-position multipliers of the North American check
+// digit; position 9 holds the check digit itself.
+var vinWeights = [17]int{8, 7, 6, 5, 4, 3, 2, 10, 0, 9, 8, 7, 6, 5, 4, 3, 2}
+
+// vinValue transliterates a VIN character for the check digit. Letters I, O
+// and Q are not allowed and report false.
+func vinValue(r byte) (int, bool) {
+	switch {
+	case r >= '0' && r <= '9':
+		return int(r - '0'), true
+	case r >= 'A' && r <= 'H':
+		return int(r-'A') + 1, true
+	case r >= 'J' && r <= 'N':
+		return int(r-'J') + 1, true
+	case r == 'P':
+		return 7, true
+	case r == 'R':
+		return 9, true
+	case r >= 'S' && r <= 'Z':
+		return int(r-'S') + 2, true
+	}
+	return 0, false
+}
+
+// VINCheckDigit computes the check digit ('0'-'9' or 'X') of a 17 character
+// VIN. The character at position 9 is ignored.
+func VINCheckDigit(vin string) (byte, error) {
+	if len(vin) != 17 {
+		return 0, ErrVINLength
+	}
+	sum := 0
+	for i := 0; i < 17; i++ {
+		v, ok := vinValue(vin[i])
+		if !ok {
+			return 0, ErrVINCharacters
+		}
+		sum += v * vinWeights[i]
+	}
+	if sum%11 == 10 {
+		return 'X', nil
+	}
+	return byte('0' + sum%11), nil
+}
+
+// ValidateVIN checks the length, characters and check digit of a normalised
+// VIN (see NormalizeVIN).
+func ValidateVIN(vin string) error {
+	check, err := VINCheckDigit(vin)
+	if err != nil {
+		return err
+	}
+	if vin[8] != check {
+		return ErrVINCheckDigit
+	}
+	return nil
+}
+
+// vinYearCodes lists the model year characters (position 10) from 1980 on;
+// the sequence repeats every 30 years.
+const vinYearCodes = "ABCDEFGHJKLMNPRSTVWXY123456789"
+
+// VINInfo is what can be decoded from a VIN without an online lookup.
+// Manufacturer and Make are empty for manufacturers missing from the table.
+type VINInfo struct {
+	WMI          string `json:"wmi"`
+	Manufacturer string `json:"manufacturer,omitempty"`
+	Make         string `json:"make,omitempty"`
+	ModelYear    int    `json:"model_year,omitempty"`
+}
+
+// DecodeVIN validates a normalised VIN and decodes its manufacturer and
+// model year. The year code repeats every 30 years; the latest year not
+// after next year (relative to now) is taken.
+func DecodeVIN(vin string, now time.Time) (VINInfo, error) {
+	if err := ValidateVIN(vin); err != nil {
+		return VINInfo{}, err
+	}
+	info := VINInfo{WMI: vin[:3]}
+	if e, ok := wmiTable[info.WMI]; ok {
+		info.Manufacturer = e.Manufacturer
+		info.Make = e.Make
+	}
+	if i := strings.IndexByte(vinYearCodes, vin[9]); i >= 0 {
+		year := 1980 + i
+		for year+30 <= now.Year()+1 {
+			year += 30
+		}
+		info.ModelYear = year
+	}
+	return info, nil
+}
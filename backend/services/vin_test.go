@@ -0,0 +1,56 @@
+package services
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestValidateVIN(t *testing.T) {
+	for _, vin := range []string{"1M8GDM9AXKP042788", "3AKJHHDR0RSUA1234", "4V4NC9EH4LN123456"} {
+		if err := ValidateVIN(vin); err != nil {
+			t.Fatalf("ValidateVIN(%q) = %v", vin, err)
+		}
+	}
+	cases := []struct {
+		vin  string
+		want error
+	}{
+		{"1M8GDM9AXKP04278", ErrVINLength},
+		{"1M8GDM9AXKP042788A", ErrVINLength},
+		{"1M8GDM9AXKO042788", ErrVINCharacters},
+		{"1m8GDM9AXKP042788", ErrVINCharacters},
+		{"1M8GDM9A1KP042788", ErrVINCheckDigit},
+		{"4V4NC9EH4LN123457", ErrVINCheckDigit},
+	}
+	for _, tc := range cases {
+		if err := ValidateVIN(tc.vin); !errors.Is(err, tc.want) {
+			t.Fatalf("ValidateVIN(%q) = %v, want %v", tc.vin, err, tc.want)
+		}
+	}
+}
+
+func TestDecodeVIN(t *testing.T) {
+	now := time.Date(2026, 1, 6, 0, 0, 0, 0, time.UTC)
+	info, err := DecodeVIN("3AKJHHDR0RSUA1234", now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.WMI != "3AK" || info.Make != "Freightliner" || info.ModelYear != 2024 {
+		t.Fatalf("unexpected info %+v", info)
+	}
+	info, err = DecodeVIN("4V4NC9EH4LN123456", now)
+	if err != nil || info.Make != "Volvo" || info.ModelYear != 2020 {
+		t.Fatalf("unexpected info %+v, %v", info, err)
+	}
+	// 'K' is 1989 or 2019; a 2019 unit is not yet possible in 2000.
+	if info, _ := DecodeVIN("1M8GDM9AXKP042788", time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC)); info.ModelYear != 1989 {
+		t.Fatalf("expected 1989, got %d", info.ModelYear)
+	}
+	if info, _ := DecodeVIN("1M8GDM9AXKP042788", now); info.ModelYear != 2019 || info.Make != "" {
+		t.Fatalf("expected unknown make from 2019, got %+v", info)
+	}
+	if _, err := DecodeVIN("1M8GDM9A1KP042788", now); !errors.Is(err, ErrVINCheckDigit) {
+		t.Fatalf("expected check digit error, got %v", err)
+	}
+}
@@ -0,0 +1,62 @@
+package services
+
+import (
+	"errors"
+	"sort"
+	"time"
+
+	"github.com/tss-booking-system/backend/models"
+)
+
+var ErrInvalidPriority = errors.New("priority must be urgent, normal or low")
+
+// ValidatePriority accepts the known priorities and the empty value (normal).
+func ValidatePriority(p models.BookingPriority) error {
+	switch p {
+	case "", models.PriorityUrgent, models.PriorityNormal, models.PriorityLow:
+		return nil
+	}
+	return ErrInvalidPriority
+}
+
+func priorityRank(p models.BookingPriority) int {
+	switch p {
+	case models.PriorityUrgent:
+		return 0
+	case models.PriorityLow:
+		return 2
+	}
+	return 1
+}
+
+// QueuedSince returns when b entered the waiting list, falling back to its
+// creation time for bookings queued before queued_at was tracked.
+func QueuedSince(b models.Booking) time.Time {
+	if b.QueuedAt != nil {
+		return *b.QueuedAt
+	}
+	return b.CreatedAt
+}
+
+// SortWaitingQueue orders waiting bookings by priority, then by the manual
+// queue order (bookings never reordered go last), then first come first
+// served. QueuePosition (1-based) and TimeInQueue (minutes) are filled in.
+func SortWaitingQueue(items []models.Booking, now time.Time) {
+	sort.SliceStable(items, func(i, j int) bool {
+		a, b := items[i], items[j]
+		if ra, rb := priorityRank(a.Priority), priorityRank(b.Priority); ra != rb {
+			return ra < rb
+		}
+		if a.QueueOrder != b.QueueOrder {
+			if a.QueueOrder == 0 || b.QueueOrder == 0 {
+				return b.QueueOrder == 0
+			}
+			return a.QueueOrder < b.QueueOrder
+		}
+		return QueuedSince(a).Before(QueuedSince(b))
+	})
+	for i := range items {
+		items[i].QueuePosition = i + 1
+		items[i].TimeInQueue = int(now.Sub(QueuedSince(items[i])) / time.Minute)
+	}
+}
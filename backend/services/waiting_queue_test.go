@@ -0,0 +1,37 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"github.com/tss-booking-system/backend/models"
+)
+
+func TestSortWaitingQueue(t *testing.T) {
+	base := time.Date(2026, 3, 2, 8, 0, 0, 0, time.UTC)
+	queued := func(m int) *time.Time {
+		t := base.Add(time.Duration(m) * time.Minute)
+		return &t
+	}
+	items := []models.Booking{
+		{Number: "low", Priority: models.PriorityLow, QueuedAt: queued(0)},
+		{Number: "normal-late", QueuedAt: queued(30)},
+		{Number: "normal-early", Priority: models.PriorityNormal, QueuedAt: queued(10)},
+		{Number: "normal-dragged", QueueOrder: 1, QueuedAt: queued(50)},
+		{Number: "urgent", Priority: models.PriorityUrgent, QueuedAt: queued(60)},
+	}
+	SortWaitingQueue(items, base.Add(90*time.Minute))
+
+	want := []string{"urgent", "normal-dragged", "normal-early", "normal-late", "low"}
+	for i, w := range want {
+		if items[i].Number != w {
+			t.Fatalf("position %d: expected %s, got %s", i+1, w, items[i].Number)
+		}
+		if items[i].QueuePosition != i+1 {
+			t.Fatalf("expected queue position %d, got %d", i+1, items[i].QueuePosition)
+		}
+	}
+	if items[0].TimeInQueue != 30 {
+		t.Fatalf("expected 30 minutes in queue, got %d", items[0].TimeInQueue)
+	}
+}
@@ -25,10 +25,12 @@ import CompanyDetailsPage from './pages/CompanyDetailsPage'
 import DashboardPage from './pages/DashboardPage'
 import LoginPage from './pages/LoginPage'
 import LogsPage from './pages/LogsPage'
+import PmDuePage from './pages/PmDuePage'
 import ProfilePage from './pages/ProfilePage.tsx'
 import SettingsPage from './pages/SettingsPage'
 import TechnicianDetailsPage from './pages/TechnicianDetailsPage'
 import TechniciansPage from './pages/TechniciansPage'
+import TrashPage from './pages/TrashPage'
 import UnitDetailsPage from './pages/UnitDetailsPage'
 import UserDetailsPage from './pages/UserDetailsPage'
 import UsersPage from './pages/UsersPage'
@@ -134,6 +136,8 @@ function App() {
 								<Route path='/users' element={<UsersPage />} />
 								<Route path='/users/:id' element={<UserDetailsPage />} />
 								<Route path='/logs' element={<LogsPage />} />
+								<Route path='/trash' element={<TrashPage />} />
+								<Route path='/pm' element={<PmDuePage />} />
 								<Route path='/profile' element={<ProfilePage />} />
 								<Route path='/settings' element={<SettingsPage />} />
 							</Route>
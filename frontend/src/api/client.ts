@@ -57,3 +57,50 @@ api.interceptors.response.use(
 
 export { api }
 
+
+// Idempotency keys let the backend recognise retried create requests. A key is
+// reused while the same payload is resubmitted for a scope and dropped once
+// the request succeeded.
+const pendingKeys = new Map<string, { key: string; body: string }>()
+
+function newKey(): string {
+  if (typeof crypto !== 'undefined' && 'randomUUID' in crypto) {
+    return crypto.randomUUID()
+  }
+  return `${Date.now().toString(36)}-${Math.random().toString(36).slice(2)}`
+}
+
+export function idempotencyKeyFor(scope: string, payload: unknown): string {
+  const body = JSON.stringify(payload)
+  const pending = pendingKeys.get(scope)
+  if (pending && pending.body === body) {
+    return pending.key
+  }
+  const key = newKey()
+  pendingKeys.set(scope, { key, body })
+  return key
+}
+
+export function clearIdempotencyKey(scope: string) {
+  pendingKeys.delete(scope)
+}
+
+// deleteErrorMessage explains a delete refused because other records still
+// reference the record (409 with dependent counts), else returns fallback.
+export function deleteErrorMessage(err: unknown, fallback: string): string {
+  if (axios.isAxiosError(err) && err.response?.status === 409) {
+    const dependents = err.response.data?.dependents as
+      | Record<string, number>
+      | undefined
+    const parts = Object.entries(dependents ?? {})
+      .filter(([, n]) => n > 0)
+      .map(([kind, n]) => `${n} ${kind}`)
+    if (parts.length > 0) {
+      return `Still in use by ${parts.join(', ')}`
+    }
+    if (typeof err.response.data?.error === 'string') {
+      return err.response.data.error
+    }
+  }
+  return fallback
+}
@@ -0,0 +1,36 @@
+import axios from 'axios'
+
+// Odometer and engine hours read when a booking is created or started. Blank
+// means the meter was not read.
+export type MeterReadingForm = {
+	odometer: string
+	engine_hours: string
+	allow_lower_reading: boolean
+}
+
+export const emptyMeterReading: MeterReadingForm = {
+	odometer: '',
+	engine_hours: '',
+	allow_lower_reading: false,
+}
+
+// meterPayload turns the form into the request fields; blank meters are left out.
+export const meterPayload = (r: MeterReadingForm) => ({
+	odometer: r.odometer ? Number(r.odometer) : undefined,
+	engine_hours: r.engine_hours ? Number(r.engine_hours) : undefined,
+	allow_lower_reading: r.allow_lower_reading || undefined,
+})
+
+// meterErrorMessage explains a reading refused because it is lower than the
+// unit's last one (409 with the last reading), else returns fallback.
+export function meterErrorMessage(err: unknown, fallback: string): string {
+	if (!axios.isAxiosError(err) || err.response?.status !== 409) return fallback
+	const last = err.response.data?.last as
+		| { odometer?: number; engine_hours?: number }
+		| undefined
+	if (!last) return fallback
+	const parts = []
+	if (last.odometer) parts.push(`${last.odometer.toLocaleString()} mi`)
+	if (last.engine_hours) parts.push(`${last.engine_hours.toLocaleString()} h`)
+	return `Reading is lower than the last one (${parts.join(', ') || '—'}). An admin can allow a lower reading.`
+}
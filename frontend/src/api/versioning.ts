@@ -0,0 +1,51 @@
+import axios from 'axios'
+import { useState } from 'react'
+
+// Edits send the version they were loaded at. When someone else saved the
+// record in the meantime the API answers 409 with the current document.
+
+// staleCurrent returns the current document from a 409 stale-version
+// response, else undefined.
+export function staleCurrent<T>(err: unknown): T | undefined {
+	if (axios.isAxiosError(err) && err.response?.status === 409) {
+		return (err.response.data?.current as T | undefined) ?? undefined
+	}
+	return undefined
+}
+
+// mergeStale keeps the fields the user changed since the form was loaded
+// (base) and takes everything else from the newer saved version (theirs).
+export function mergeStale<F extends object>(base: F, mine: F, theirs: F): F {
+	const out = { ...theirs }
+	for (const key of Object.keys(mine) as Array<keyof F>) {
+		if (JSON.stringify(mine[key]) !== JSON.stringify(base[key])) {
+			out[key] = mine[key]
+		}
+	}
+	return out
+}
+
+// useVersionedEdit tracks the version and form an edit started from. After
+// a stale save, resolve merges the user's edits onto the current document and
+// moves the edit to its version, so saving again goes through.
+export function useVersionedEdit<F extends object>() {
+	const [edit, setEdit] = useState<{ version?: number; base?: F }>({})
+	return {
+		version: edit.version,
+		begin: (form: F, version?: number) => setEdit({ version, base: form }),
+		resolve: <T extends { version?: number }>(
+			err: unknown,
+			mine: F,
+			toForm: (current: T) => F
+		): F | undefined => {
+			const current = staleCurrent<T>(err)
+			if (!current) return undefined
+			const theirs = toForm(current)
+			setEdit({ version: current.version, base: theirs })
+			return mergeStale(edit.base ?? theirs, mine, theirs)
+		},
+	}
+}
+
+export const staleMessage = (what: string) =>
+	`Someone else saved this ${what} first. Their changes are now in the form; review and save again.`
@@ -2,9 +2,22 @@ import { CheckIcon, PlusIcon } from '@heroicons/react/24/outline'
 import CustomInput from '../shared/CustomInput'
 import CustomModal from '../shared/CustomModal'
 
-type BayForm = {
+export type BayForm = {
 	key: string
 	name: string
+	default_duration: string
+	capacity: string
+	capabilities: string
+	max_length_ft: string
+}
+
+export const emptyBayForm: BayForm = {
+	key: '',
+	name: '',
+	default_duration: '',
+	capacity: '1',
+	capabilities: '',
+	max_length_ft: '',
 }
 
 type Props = {
@@ -75,6 +88,34 @@ export default function BayQuickModal({
 					onChange={v => onChange({ key: v })}
 					placeholder='Unique key'
 				/>
+				<CustomInput
+					label='Capacity'
+					type='number'
+					value={form.capacity}
+					onChange={v => onChange({ capacity: v })}
+					helperText='Units the bay holds at once'
+				/>
+				<CustomInput
+					label='Default duration (min)'
+					type='number'
+					value={form.default_duration}
+					onChange={v => onChange({ default_duration: v })}
+					placeholder='e.g. 120'
+				/>
+				<CustomInput
+					label='Capabilities'
+					value={form.capabilities}
+					onChange={v => onChange({ capabilities: v })}
+					placeholder='inside, alignment'
+					helperText='Comma separated'
+				/>
+				<CustomInput
+					label='Max unit length (ft)'
+					type='number'
+					value={form.max_length_ft}
+					onChange={v => onChange({ max_length_ft: v })}
+					placeholder='No limit'
+				/>
 			</div>
 		</CustomModal>
 	)
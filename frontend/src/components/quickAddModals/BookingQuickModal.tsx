@@ -1,9 +1,11 @@
 import { PlusIcon } from '@heroicons/react/24/outline'
 import { useEffect, useMemo, useState } from 'react'
 import { api } from '../../api/client'
+import type { MeterReadingForm } from '../../api/meter'
 import CustomAutocomplete from '../shared/CustomAutocomplete'
 import CustomInput from '../shared/CustomInput'
 import CustomModal from '../shared/CustomModal'
+import MeterReadingFields from '../shared/MeterReadingFields'
 import MultiAutocomplete from '../shared/MultiAutocomplete'
 
 type Option = {
@@ -34,6 +36,10 @@ type Props = {
 	bays: Option[]
 	companies: Option[]
 	technicians: Option[]
+	// meter reading taken when the booking is created; not shown on edit
+	reading?: MeterReadingForm
+	canOverrideReading?: boolean
+	onReadingChange?: (patch: Partial<MeterReadingForm>) => void
 	onChange: (patch: Partial<BookingForm>) => void
 	onCancel: () => void
 	onSubmit: () => void
@@ -48,6 +54,9 @@ export default function BookingQuickModal({
 	bays,
 	companies,
 	technicians,
+	reading,
+	canOverrideReading = false,
+	onReadingChange,
 	onChange,
 	onCancel,
 	onSubmit,
@@ -332,6 +341,13 @@ export default function BookingQuickModal({
 						placeholder='Select technicians'
 					/>
 				</div>
+				{!isEdit && reading && onReadingChange ? (
+					<MeterReadingFields
+						value={reading}
+						onChange={onReadingChange}
+						canOverride={canOverrideReading}
+					/>
+				) : null}
 			</div>
 		</CustomModal>
 	)
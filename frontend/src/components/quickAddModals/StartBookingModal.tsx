@@ -0,0 +1,58 @@
+import { PlayIcon } from '@heroicons/react/24/outline'
+import type { MeterReadingForm } from '../../api/meter'
+import CustomModal from '../shared/CustomModal'
+import MeterReadingFields from '../shared/MeterReadingFields'
+
+type Props = {
+	isOpen: boolean
+	isSaving: boolean
+	reading: MeterReadingForm
+	canOverride?: boolean
+	onChange: (patch: Partial<MeterReadingForm>) => void
+	onCancel: () => void
+	onSubmit: () => void
+}
+
+export default function StartBookingModal({
+	isOpen,
+	isSaving,
+	reading,
+	canOverride = false,
+	onChange,
+	onCancel,
+	onSubmit,
+}: Props) {
+	return (
+		<CustomModal
+			isOpen={isOpen}
+			onClose={onCancel}
+			title='Check in'
+			footer={
+				<div className='flex justify-end gap-2'>
+					<button
+						type='button'
+						onClick={onCancel}
+						className='rounded-md border border-slate-200 px-3 py-2 text-sm font-semibold text-slate-700 hover:bg-slate-100'
+					>
+						Cancel
+					</button>
+					<button
+						type='button'
+						onClick={onSubmit}
+						disabled={isSaving}
+						className='inline-flex items-center gap-2 rounded-md bg-slate-900 px-3 py-2 text-sm font-semibold text-white disabled:opacity-60'
+					>
+						<PlayIcon className='h-4 w-4' />
+						{isSaving ? 'Starting...' : 'Start'}
+					</button>
+				</div>
+			}
+		>
+			<MeterReadingFields
+				value={reading}
+				onChange={onChange}
+				canOverride={canOverride}
+			/>
+		</CustomModal>
+	)
+}
@@ -0,0 +1,45 @@
+import type { MeterReadingForm } from '../../api/meter'
+import CustomInput from './CustomInput'
+import CustomSwitch from './CustomSwitch'
+
+type Props = {
+	value: MeterReadingForm
+	onChange: (patch: Partial<MeterReadingForm>) => void
+	// admins may record a reading below the last one (cluster or engine swap)
+	canOverride?: boolean
+}
+
+export default function MeterReadingFields({
+	value,
+	onChange,
+	canOverride = false,
+}: Props) {
+	return (
+		<div className='grid gap-3'>
+			<div className='grid grid-cols-2 gap-3'>
+				<CustomInput
+					label='Odometer (mi)'
+					type='number'
+					value={value.odometer}
+					onChange={v => onChange({ odometer: v })}
+					placeholder='Optional'
+				/>
+				<CustomInput
+					label='Engine hours'
+					type='number'
+					value={value.engine_hours}
+					onChange={v => onChange({ engine_hours: v })}
+					placeholder='Optional'
+				/>
+			</div>
+			{canOverride ? (
+				<CustomSwitch
+					checked={value.allow_lower_reading}
+					onChange={v => onChange({ allow_lower_reading: v })}
+					label='Allow lower reading'
+					description='For a replaced cluster or engine'
+				/>
+			) : null}
+		</div>
+	)
+}
@@ -133,11 +133,19 @@ export default function AppHeader() {
 							</Menu.Items>
 						</Transition>
 					</Menu>
+					<NavLink to='/pm' className={navLinkClass} aria-label='PM due'>
+						PM due
+					</NavLink>
 					{role === 'admin' && (
 						<NavLink to='/logs' className={navLinkClass} aria-label='Logs'>
 							Logs
 						</NavLink>
 					)}
+					{role === 'admin' && (
+						<NavLink to='/trash' className={navLinkClass} aria-label='Trash'>
+							Trash
+						</NavLink>
+					)}
 					{role === 'admin' && (
 						<NavLink
 							to='/settings'
@@ -13,14 +13,16 @@ export default function CustomDetailsPage({
 	renderLogs,
 	renderContacts,
 	renderUnits,
+	renderHistory,
 }: {
 	title: ReactNode
 	subtitle?: ReactNode
 	rows: Row[]
-	tabs?: Array<'general' | 'contacts' | 'units' | 'logs'>
+	tabs?: Array<'general' | 'contacts' | 'units' | 'history' | 'logs'>
 	renderLogs?: () => ReactNode
 	renderContacts?: () => ReactNode
 	renderUnits?: () => ReactNode
+	renderHistory?: () => ReactNode
 }) {
 	const navigate = useNavigate()
 	const [search] = useSearchParams()
@@ -54,6 +56,8 @@ export default function CustomDetailsPage({
 							? 'Contacts'
 							: t === 'units'
 							? 'Units'
+							: t === 'history'
+							? 'History'
 							: 'Logs',
 				}))}
 			/>
@@ -98,6 +102,14 @@ export default function CustomDetailsPage({
 						<p className='text-sm text-slate-600'>No units yet.</p>
 					)}
 				</section>
+			) : active === 'history' ? (
+				<section className='rounded-xl border border-slate-200 bg-white p-4 shadow-sm'>
+					{renderHistory ? (
+						renderHistory()
+					) : (
+						<p className='text-sm text-slate-600'>No history yet.</p>
+					)}
+				</section>
 			) : (
 				<section className='rounded-xl border border-slate-200 bg-white p-4 shadow-sm'>
 					{renderLogs ? (
@@ -2,8 +2,12 @@ import { PencilSquareIcon, TrashIcon } from '@heroicons/react/24/outline'
 import { useMutation, useQuery, useQueryClient } from '@tanstack/react-query'
 import { useState } from 'react'
 import { NavLink } from 'react-router-dom'
-import { api } from '../api/client'
-import BayQuickModal from '../components/quickAddModals/BayQuickModal'
+import { api, deleteErrorMessage } from '../api/client'
+import { staleMessage, useVersionedEdit } from '../api/versioning'
+import BayQuickModal, {
+	emptyBayForm,
+	type BayForm,
+} from '../components/quickAddModals/BayQuickModal'
 import CustomTable, { type Column } from '../components/shared/CustomTable'
 import ConfirmDeleteModal from '../components/shared/ui/ConfirmDeleteModal'
 import CreateButton from '../components/shared/ui/CreateButton'
@@ -12,6 +16,33 @@ import { useToast } from '../components/shared/ui/ToastProvider'
 import { useAuth } from '../context/AuthContext'
 import type { Bay } from '../types'
 
+// bayPayload turns the form into the fields the API stores; empty numbers
+// clear the value.
+function bayPayload(form: BayForm) {
+	return {
+		key: form.key,
+		name: form.name,
+		default_duration: Number(form.default_duration) || 0,
+		capacity: Number(form.capacity) || 0,
+		capabilities: form.capabilities
+			.split(',')
+			.map(s => s.trim())
+			.filter(Boolean),
+		max_length_ft: Number(form.max_length_ft) || 0,
+	}
+}
+
+function bayForm(b: Bay): BayForm {
+	return {
+		key: b.key,
+		name: b.name,
+		default_duration: b.default_duration ? String(b.default_duration) : '',
+		capacity: String(b.capacity || 1),
+		capabilities: (b.capabilities ?? []).join(', '),
+		max_length_ft: b.max_length_ft ? String(b.max_length_ft) : '',
+	}
+}
+
 function BaysPage() {
 	const qc = useQueryClient()
 	const { role } = useAuth()
@@ -19,7 +50,8 @@ function BaysPage() {
 	const [modalOpen, setModalOpen] = useState(false)
 	const [editingId, setEditingId] = useState<string | null>(null)
 	const [pendingDeleteId, setPendingDeleteId] = useState<string | null>(null)
-	const [form, setForm] = useState({ key: '', name: '' })
+	const [form, setForm] = useState<BayForm>(emptyBayForm)
+	const edit = useVersionedEdit<BayForm>()
 
 	const listQuery = useQuery({
 		queryKey: ['bays'],
@@ -28,13 +60,10 @@ function BaysPage() {
 
 	const createMutation = useMutation({
 		mutationFn: async () =>
-			api.post('/api/bays', {
-				key: form.key,
-				name: form.name,
-			}),
+			api.post('/api/bays', bayPayload(form)),
 		onSuccess: () => {
 			qc.invalidateQueries({ queryKey: ['bays'] })
-			setForm({ key: '', name: '' })
+			setForm(emptyBayForm)
 			setModalOpen(false)
 			success('Bay created')
 		},
@@ -42,17 +71,22 @@ function BaysPage() {
 	})
 	const updateMutation = useMutation({
 		mutationFn: async (id: string) =>
-			api.put(`/api/bays/${id}`, {
-				key: form.key,
-				name: form.name,
-			}),
+			api.put(`/api/bays/${id}`, { ...bayPayload(form), version: edit.version }),
 		onSuccess: () => {
 			qc.invalidateQueries({ queryKey: ['bays'] })
 			setEditingId(null)
 			setModalOpen(false)
 			success('Bay updated')
 		},
-		onError: () => error('Failed to update bay'),
+		onError: err => {
+			const merged = edit.resolve(err, form, bayForm)
+			if (merged) {
+				setForm(merged)
+				error(staleMessage('bay'))
+				return
+			}
+			error('Failed to update bay')
+		},
 	})
 	const deleteMutation = useMutation({
 		mutationFn: async (id: string) => api.delete(`/api/bays/${id}`),
@@ -60,17 +94,18 @@ function BaysPage() {
 			qc.invalidateQueries({ queryKey: ['bays'] })
 			success('Bay deleted')
 		},
-		onError: () => error('Failed to delete bay'),
+		onError: err => error(deleteErrorMessage(err, 'Failed to delete bay')),
 	})
 
 	const openCreate = () => {
 		setEditingId(null)
-		setForm({ key: '', name: '' })
+		setForm(emptyBayForm)
 		setModalOpen(true)
 	}
 	const openEdit = (b: Bay) => {
 		setEditingId(b.id)
-		setForm({ key: b.key, name: b.name })
+		setForm(bayForm(b))
+		edit.begin(bayForm(b), b.version)
 		setModalOpen(true)
 	}
 
@@ -85,6 +120,16 @@ function BaysPage() {
 			),
 		},
 		{ key: 'key', header: 'Key' },
+		{
+			key: 'capacity',
+			header: 'Capacity',
+			render: row => String(row.capacity || 1),
+		},
+		{
+			key: 'capabilities',
+			header: 'Capabilities',
+			render: row => (row.capabilities ?? []).join(', ') || '—',
+		},
 		{
 			key: 'actions',
 			header: 'Actions',
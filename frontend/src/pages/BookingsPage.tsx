@@ -3,14 +3,23 @@ import {
 	ArrowPathIcon,
 	CheckCircleIcon,
 	PencilSquareIcon,
+	PlayIcon,
 	TrashIcon,
 	XMarkIcon,
 } from '@heroicons/react/24/outline'
 import { useMutation, useQuery, useQueryClient } from '@tanstack/react-query'
 import { useMemo, useState } from 'react'
 import { NavLink } from 'react-router-dom'
-import { api } from '../api/client'
+import { api, clearIdempotencyKey, idempotencyKeyFor } from '../api/client'
+import {
+	emptyMeterReading,
+	meterErrorMessage,
+	meterPayload,
+	type MeterReadingForm,
+} from '../api/meter'
+import { staleMessage, useVersionedEdit } from '../api/versioning'
 import BookingQuickModal from '../components/quickAddModals/BookingQuickModal'
+import StartBookingModal from '../components/quickAddModals/StartBookingModal'
 import CustomSelect, { type Option } from '../components/shared/CustomSelect'
 import CustomTable, { type Column } from '../components/shared/CustomTable'
 import ConfirmDeleteModal from '../components/shared/ui/ConfirmDeleteModal'
@@ -20,6 +29,30 @@ import { useToast } from '../components/shared/ui/ToastProvider'
 import { useAuth } from '../context/AuthContext'
 import type { Bay, Booking, Company, Technician, Vehicle } from '../types'
 
+type BookingForm = {
+	complaint: string
+	description: string
+	fullbay_service_id: string
+	vehicle_id: string
+	bay_id: string
+	technician_ids: string[]
+	start: string
+	end: string
+	company_id: string
+}
+
+const bookingForm = (b: Booking): BookingForm => ({
+	complaint: b.complaint ?? '',
+	description: b.description,
+	fullbay_service_id: b.fullbay_service_id ?? '',
+	vehicle_id: b.vehicle_id,
+	bay_id: b.bay_id,
+	technician_ids: (b.technician_ids as string[] | undefined) || [],
+	start: b.start.slice(0, 16),
+	end: b.end ? b.end.slice(0, 16) : '',
+	company_id: b.company_id ?? '',
+})
+
 function StatusBadge({ status }: { status: Booking['status'] }) {
 	const colors: Record<Booking['status'], string> = {
 		open: 'bg-amber-100 text-amber-800',
@@ -43,7 +76,7 @@ function BookingsPage() {
 	const [modalOpen, setModalOpen] = useState(false)
 	const [editingId, setEditingId] = useState<string | null>(null)
 	const [pendingDeleteId, setPendingDeleteId] = useState<string | null>(null)
-	const [form, setForm] = useState({
+	const [form, setForm] = useState<BookingForm>({
 		complaint: '',
 		description: '',
 		fullbay_service_id: '',
@@ -54,6 +87,10 @@ function BookingsPage() {
 		end: '',
 		company_id: '',
 	})
+	const edit = useVersionedEdit<BookingForm>()
+	const [reading, setReading] = useState<MeterReadingForm>(emptyMeterReading)
+	const [startingId, setStartingId] = useState<string | null>(null)
+	const [checkIn, setCheckIn] = useState<MeterReadingForm>(emptyMeterReading)
 	const baysQuery = useQuery({
 		queryKey: ['bays'],
 		queryFn: async () => (await api.get<Bay[]>('/api/bays')).data,
@@ -135,10 +172,14 @@ function BookingsPage() {
 				end: form.end ? new Date(form.end).toISOString() : undefined,
 				status: 'open',
 				notes: '',
+				...meterPayload(reading),
 			}
-			await api.post('/api/bookings', payload)
+			await api.post('/api/bookings', payload, {
+				headers: { 'Idempotency-Key': idempotencyKeyFor('booking.create', payload) },
+			})
 		},
 		onSuccess: () => {
+			clearIdempotencyKey('booking.create')
 			queryClient.invalidateQueries({ queryKey: ['bookings'] })
 			setModalOpen(false)
 			setEditingId(null)
@@ -153,9 +194,22 @@ function BookingsPage() {
 				end: '',
 				company_id: '',
 			})
+			setReading(emptyMeterReading)
 			success('Booking created')
 		},
-		onError: () => error('Failed to create booking'),
+		onError: err => error(meterErrorMessage(err, 'Failed to create booking')),
+	})
+
+	// Check-in: start the booking with the meters read on arrival
+	const startMutation = useMutation({
+		mutationFn: async (id: string) =>
+			api.put(`/api/bookings/${id}/start`, meterPayload(checkIn)),
+		onSuccess: () => {
+			queryClient.invalidateQueries({ queryKey: ['bookings'] })
+			setStartingId(null)
+			success('Booking started')
+		},
+		onError: err => error(meterErrorMessage(err, 'Failed to start booking')),
 	})
 
 	const updateMutation = useMutation({
@@ -172,6 +226,7 @@ function BookingsPage() {
 				end: form.end ? new Date(form.end).toISOString() : undefined,
 				status: 'open',
 				notes: '',
+				version: edit.version,
 			}
 			await api.put(`/api/bookings/${id}`, payload)
 		},
@@ -181,7 +236,15 @@ function BookingsPage() {
 			setEditingId(null)
 			success('Booking updated')
 		},
-		onError: () => error('Failed to update booking'),
+		onError: err => {
+			const merged = edit.resolve(err, form, bookingForm)
+			if (merged) {
+				setForm(merged)
+				error(staleMessage('booking'))
+				return
+			}
+			error('Failed to update booking')
+		},
 	})
 
 	const deleteMutation = useMutation({
@@ -262,18 +325,8 @@ function BookingsPage() {
 								type='button'
 								onClick={() => {
 									setEditingId(row.id)
-									setForm({
-										complaint: row.complaint ?? '',
-										description: row.description,
-										fullbay_service_id: row.fullbay_service_id ?? '',
-										vehicle_id: row.vehicle_id,
-										bay_id: row.bay_id,
-										technician_ids:
-											(row.technician_ids as string[] | undefined) || [],
-										start: row.start.slice(0, 16),
-										end: row.end ? row.end.slice(0, 16) : '',
-										company_id: row.company_id ?? '',
-									})
+									setForm(bookingForm(row))
+									edit.begin(bookingForm(row), row.version)
 									setModalOpen(true)
 								}}
 								className='inline-flex items-center gap-1 rounded-md border border-slate-200 px-2 py-1 text-xs font-semibold text-slate-700 hover:bg-slate-50'
@@ -282,6 +335,21 @@ function BookingsPage() {
 								Edit
 							</button>
 						</CustomTooltip>
+						{row.status === 'open' && (
+							<CustomTooltip content='Check in and start'>
+								<button
+									type='button'
+									onClick={() => {
+										setCheckIn(emptyMeterReading)
+										setStartingId(row.id)
+									}}
+									className='inline-flex items-center gap-1 rounded-md bg-blue-100 px-2 py-1 text-xs font-semibold text-blue-800 hover:bg-blue-200'
+								>
+									<PlayIcon className='h-4 w-4' />
+									Start
+								</button>
+							</CustomTooltip>
+						)}
 						<CustomTooltip content='Mark ready'>
 							<button
 								type='button'
@@ -439,6 +507,7 @@ function BookingsPage() {
 								end: '',
 								company_id: '',
 							})
+							setReading(emptyMeterReading)
 							setModalOpen(true)
 						}}
 					>
@@ -543,6 +612,9 @@ function BookingsPage() {
 					id: t.id,
 					label: t.name,
 				}))}
+				reading={reading}
+				canOverrideReading={role === 'admin'}
+				onReadingChange={patch => setReading(prev => ({ ...prev, ...patch }))}
 				onChange={patch => setForm(prev => ({ ...prev, ...patch }))}
 				onCancel={() => {
 					setModalOpen(false)
@@ -554,6 +626,15 @@ function BookingsPage() {
 					editingId ? updateMutation.mutate(editingId) : createMutation.mutate()
 				}
 			/>
+			<StartBookingModal
+				isOpen={Boolean(startingId)}
+				isSaving={startMutation.isPending}
+				reading={checkIn}
+				canOverride={role === 'admin'}
+				onChange={patch => setCheckIn(prev => ({ ...prev, ...patch }))}
+				onCancel={() => setStartingId(null)}
+				onSubmit={() => startingId && startMutation.mutate(startingId)}
+			/>
 			<ConfirmDeleteModal
 				isOpen={Boolean(pendingDeleteId)}
 				onCancel={() => setPendingDeleteId(null)}
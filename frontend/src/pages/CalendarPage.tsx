@@ -26,7 +26,18 @@ import {
 import withDragAndDrop from 'react-big-calendar/lib/addons/dragAndDrop'
 import 'react-big-calendar/lib/addons/dragAndDrop/styles.css'
 import 'react-big-calendar/lib/css/react-big-calendar.css'
-import { api } from '../api/client'
+import { api, clearIdempotencyKey, idempotencyKeyFor } from '../api/client'
+import {
+	emptyMeterReading,
+	meterErrorMessage,
+	meterPayload,
+	type MeterReadingForm,
+} from '../api/meter'
+import {
+	staleCurrent,
+	staleMessage,
+	useVersionedEdit,
+} from '../api/versioning'
 import CalendarMenuDropdown from '../components/calendar/CalendarMenuDropdown'
 import CalendarReady from '../components/calendar/CalendarReady'
 import CalendarWaitingList from '../components/calendar/CalendarWaitingList'
@@ -34,6 +45,8 @@ import FullWidthModal from '../components/calendar/FullWidthModal'
 import BookingQuickModal from '../components/quickAddModals/BookingQuickModal'
 import CustomSelect, { type Option } from '../components/shared/CustomSelect'
 import CreateButton from '../components/shared/ui/CreateButton'
+import { useToast } from '../components/shared/ui/ToastProvider'
+import { useAuth } from '../context/AuthContext'
 import type {
 	Bay,
 	Booking,
@@ -45,6 +58,20 @@ import type {
 
 type ViewMode = 'day' | 'week' | 'month' | 'agenda'
 
+type CalendarForm = {
+	complaint: string
+	description: string
+	fullbay_service_id: string
+	vehicle_id: string
+	bay_id: string
+	technician_ids: string[]
+	company_id: string
+	start: string
+	end: string
+	status: BookingStatus
+	notes: string
+}
+
 const locales = {
 	'en-US': enUS,
 }
@@ -95,19 +122,23 @@ function CalendarPage() {
 	const [view, setView] = useState<ViewMode>('month')
 	const [date, setDate] = useState<Date>(new Date())
 	const [editingId, setEditingId] = useState<string | null>(null)
-	const [form, setForm] = useState({
+	const [form, setForm] = useState<CalendarForm>({
 		complaint: '',
 		description: '',
 		fullbay_service_id: '',
 		vehicle_id: '',
 		bay_id: '',
-		technician_ids: [] as string[],
+		technician_ids: [],
 		company_id: '',
 		start: '',
 		end: '',
-		status: 'open' as BookingStatus,
+		status: 'open',
 		notes: '',
 	})
+	const edit = useVersionedEdit<CalendarForm>()
+	const [reading, setReading] = useState<MeterReadingForm>(emptyMeterReading)
+	const { error } = useToast()
+	const { role } = useAuth()
 	const [modalOpen, setModalOpen] = useState(false)
 	const [fullscreen, setFullscreen] = useState(false)
 	// Month view "+X more" dropdown state
@@ -224,10 +255,14 @@ function CalendarPage() {
 				end: form.end ? new Date(form.end).toISOString() : undefined,
 				status: 'open' as BookingStatus,
 				notes: '',
+				...meterPayload(reading),
 			}
-			await api.post('/api/bookings', payload)
+			await api.post('/api/bookings', payload, {
+				headers: { 'Idempotency-Key': idempotencyKeyFor('booking.create', payload) },
+			})
 		},
 		onSuccess: () => {
+			clearIdempotencyKey('booking.create')
 			queryClient.invalidateQueries({ queryKey: ['agenda'] })
 			queryClient.invalidateQueries({ queryKey: ['bookings'] })
 			setModalOpen(false)
@@ -245,7 +280,9 @@ function CalendarPage() {
 				status: 'open',
 				notes: '',
 			})
+			setReading(emptyMeterReading)
 		},
+		onError: err => error(meterErrorMessage(err, 'Failed to create booking')),
 	})
 	const updateMutation = useMutation({
 		mutationFn: async (id: string) => {
@@ -261,6 +298,7 @@ function CalendarPage() {
 				end: form.end ? new Date(form.end).toISOString() : undefined,
 				// do not set status to avoid unintended resets
 				notes: '',
+				version: edit.version,
 			}
 			await api.put(`/api/bookings/${id}`, payload)
 		},
@@ -270,6 +308,15 @@ function CalendarPage() {
 			setModalOpen(false)
 			setEditingId(null)
 		},
+		onError: err => {
+			const merged = edit.resolve(err, form, calendarForm)
+			if (merged) {
+				setForm(merged)
+				error(staleMessage('booking'))
+				return
+			}
+			error('Failed to update booking')
+		},
 	})
 
 	// Custom header for the time gutter (top-left empty cell in day/week views)
@@ -361,9 +408,55 @@ function CalendarPage() {
 		)}T${pad(d.getHours())}:${pad(d.getMinutes())}`
 	}
 
+	const calendarForm = (b: Booking): CalendarForm => ({
+		complaint: b.complaint || '',
+		description: b.description,
+		fullbay_service_id: b.fullbay_service_id || '',
+		vehicle_id: b.vehicle_id,
+		bay_id: b.bay_id,
+		technician_ids: b.technician_ids || [],
+		company_id: b.company_id,
+		start: formatForInput(new Date(b.start)),
+		end: b.end ? formatForInput(new Date(b.end)) : '',
+		status: b.status,
+		notes: b.notes,
+	})
+
+	const openBooking = (b: Booking) => {
+		setEditingId(b.id)
+		setForm(calendarForm(b))
+		edit.begin(calendarForm(b), b.version)
+		setModalOpen(true)
+	}
+
+	// moveBooking saves a drag or resize. The booking carries the version it
+	// was loaded at, so a move over someone else's save is refused with 409.
+	const moveBooking = async (
+		booking: Booking,
+		start: Date | string,
+		end?: Date | string
+	) => {
+		try {
+			await api.put(`/api/bookings/${booking.id}`, {
+				...booking,
+				start: new Date(start).toISOString(),
+				end: end ? new Date(end).toISOString() : undefined,
+			})
+		} catch (err) {
+			error(
+				staleCurrent(err)
+					? 'Someone else changed this booking first. The calendar now shows their version.'
+					: 'Failed to move booking'
+			)
+		}
+		queryClient.invalidateQueries({ queryKey: ['agenda'] })
+		queryClient.invalidateQueries({ queryKey: ['bookings'] })
+	}
+
 	const handleSlot = (slot: SlotInfo) => {
 		// Enter create mode and fully reset the form for a clean create experience
 		setEditingId(null)
+		setReading(emptyMeterReading)
 		const startDate =
 			slot.start instanceof Date ? slot.start : new Date(slot.start)
 		const endDate =
@@ -411,6 +504,7 @@ function CalendarPage() {
 					<CreateButton
 						onClick={() => {
 							setEditingId(null)
+							setReading(emptyMeterReading)
 							setForm({
 								complaint: '',
 								description: '',
@@ -444,22 +538,7 @@ function CalendarPage() {
 					from={range.from}
 					to={range.to}
 					onSelect={b => {
-						setEditingId(b.id)
-						setForm(prev => ({
-							...prev,
-							complaint: b.complaint || '',
-							description: b.description,
-							vehicle_id: b.vehicle_id,
-							company_id: b.company_id,
-							fullbay_service_id: b.fullbay_service_id || '',
-							technician_ids: b.technician_ids || [],
-							bay_id: b.bay_id,
-							start: formatForInput(new Date(b.start)),
-							end: b.end ? formatForInput(new Date(b.end)) : '',
-							status: b.status,
-							notes: b.notes,
-						}))
-						setModalOpen(true)
+						openBooking(b)
 					}}
 				/>
 			</div>
@@ -474,22 +553,7 @@ function CalendarPage() {
 					from={range.from}
 					to={range.to}
 					onSelect={b => {
-						setEditingId(b.id)
-						setForm(prev => ({
-							...prev,
-							complaint: b.complaint || '',
-							description: b.description,
-							vehicle_id: b.vehicle_id,
-							company_id: b.company_id,
-							fullbay_service_id: b.fullbay_service_id || '',
-							technician_ids: b.technician_ids || [],
-							bay_id: b.bay_id,
-							start: formatForInput(new Date(b.start)),
-							end: b.end ? formatForInput(new Date(b.end)) : '',
-							status: b.status,
-							notes: b.notes,
-						}))
-						setModalOpen(true)
+						openBooking(b)
 					}}
 				/>
 			</div>
@@ -583,57 +647,16 @@ function CalendarPage() {
 						style={{ height: '100%' }}
 						onSelectEvent={(event: RBCEvent) => {
 							const booking = event.resource as Booking
-							setEditingId(booking.id)
-							setForm(prev => ({
-								...prev,
-								complaint: booking.complaint || '',
-								description: booking.description,
-								vehicle_id: booking.vehicle_id,
-								company_id: booking.company_id,
-								fullbay_service_id: booking.fullbay_service_id || '',
-								technician_ids: booking.technician_ids,
-								bay_id: booking.bay_id,
-								start: formatForInput(new Date(booking.start)),
-								end: booking.end ? formatForInput(new Date(booking.end)) : '',
-								status: booking.status,
-								notes: booking.notes,
-							}))
-							setModalOpen(true)
+							openBooking(booking)
 						}}
 						selectable
 						onSelectSlot={handleSlot}
-						onEventDrop={async ({ event, start, end }) => {
-							const booking = event.resource as Booking
-							const startIso = (
-								start instanceof Date ? start : new Date(start)
-							).toISOString()
-							const endIso = end
-								? (end instanceof Date ? end : new Date(end)).toISOString()
-								: undefined
-							await api.put(`/api/bookings/${booking.id}`, {
-								...booking,
-								start: startIso,
-								end: endIso,
-							})
-							queryClient.invalidateQueries({ queryKey: ['agenda'] })
-							queryClient.invalidateQueries({ queryKey: ['bookings'] })
-						}}
-						onEventResize={async ({ event, start, end }) => {
-							const booking = event.resource as Booking
-							const startIso = (
-								start instanceof Date ? start : new Date(start)
-							).toISOString()
-							const endIso = end
-								? (end instanceof Date ? end : new Date(end)).toISOString()
-								: undefined
-							await api.put(`/api/bookings/${booking.id}`, {
-								...booking,
-								start: startIso,
-								end: endIso,
-							})
-							queryClient.invalidateQueries({ queryKey: ['agenda'] })
-							queryClient.invalidateQueries({ queryKey: ['bookings'] })
-						}}
+						onEventDrop={({ event, start, end }) =>
+							moveBooking(event.resource as Booking, start, end)
+						}
+						onEventResize={({ event, start, end }) =>
+							moveBooking(event.resource as Booking, start, end)
+						}
 						// Open custom dropdown instead of drilling down when "+X more" is clicked
 						onShowMore={
 							((evts: RBCEvent[], _date: Date, cell?: HTMLElement) => {
@@ -651,22 +674,7 @@ function CalendarPage() {
 						}
 						onSelect={b => {
 							setMoreState(s => ({ ...s, open: false }))
-							setEditingId(b.id)
-							setForm(prev => ({
-								...prev,
-								complaint: b.complaint || '',
-								description: b.description,
-								vehicle_id: b.vehicle_id,
-								company_id: b.company_id,
-								fullbay_service_id: b.fullbay_service_id || '',
-								technician_ids: b.technician_ids,
-								bay_id: b.bay_id,
-								start: formatForInput(new Date(b.start)),
-								end: b.end ? formatForInput(new Date(b.end)) : '',
-								status: b.status,
-								notes: b.notes,
-							}))
-							setModalOpen(true)
+							openBooking(b)
 						}}
 					/>
 				</div>
@@ -694,6 +702,9 @@ function CalendarPage() {
 					id: t.id,
 					label: t.name,
 				}))}
+				reading={reading}
+				canOverrideReading={role === 'admin'}
+				onReadingChange={patch => setReading(prev => ({ ...prev, ...patch }))}
 				onChange={patch => setForm(prev => ({ ...prev, ...patch }))}
 				onCancel={() => {
 					setModalOpen(false)
@@ -777,56 +788,16 @@ function CalendarPage() {
 						style={{ height: '100%' }}
 						onSelectEvent={(event: RBCEvent) => {
 							const booking = event.resource as Booking
-							setForm(prev => ({
-								...prev,
-								complaint: booking.complaint || '',
-								description: booking.description,
-								vehicle_id: booking.vehicle_id,
-								company_id: booking.company_id,
-								fullbay_service_id: booking.fullbay_service_id || '',
-								technician_ids: booking.technician_ids,
-								bay_id: booking.bay_id,
-								start: formatForInput(new Date(booking.start)),
-								end: booking.end ? formatForInput(new Date(booking.end)) : '',
-								status: booking.status,
-								notes: booking.notes,
-							}))
-							setModalOpen(true)
+							openBooking(booking)
 						}}
 						selectable
 						onSelectSlot={handleSlot}
-						onEventDrop={async ({ event, start, end }) => {
-							const booking = event.resource as Booking
-							const startIso = (
-								start instanceof Date ? start : new Date(start)
-							).toISOString()
-							const endIso = end
-								? (end instanceof Date ? end : new Date(end)).toISOString()
-								: undefined
-							await api.put(`/api/bookings/${booking.id}`, {
-								...booking,
-								start: startIso,
-								end: endIso,
-							})
-							queryClient.invalidateQueries({ queryKey: ['agenda'] })
-							queryClient.invalidateQueries({ queryKey: ['bookings'] })
-						}}
-						onEventResize={async ({ event, start, end }) => {
-							const booking = event.resource as Booking
-							const startIso = (
-								start instanceof Date ? start : new Date(start)
-							).toISOString()
-							const endIso = end
-								? (end instanceof Date ? end : new Date(end)).toISOString()
-								: undefined
-							await api.put(`/api/bookings/${booking.id}`, {
-								...booking,
-								start: startIso,
-								end: endIso,
-							})
-							queryClient.invalidateQueries({ queryKey: ['agenda'] })
-							queryClient.invalidateQueries({ queryKey: ['bookings'] })
-						}}
+						onEventDrop={({ event, start, end }) =>
+							moveBooking(event.resource as Booking, start, end)
+						}
+						onEventResize={({ event, start, end }) =>
+							moveBooking(event.resource as Booking, start, end)
+						}
 					/>
 				</div>
 			</FullWidthModal>
@@ -2,7 +2,8 @@ import { PencilSquareIcon, TrashIcon } from '@heroicons/react/24/outline'
 import { useMutation, useQuery, useQueryClient } from '@tanstack/react-query'
 import { useState } from 'react'
 import { NavLink } from 'react-router-dom'
-import { api } from '../api/client'
+import { api, deleteErrorMessage } from '../api/client'
+import { staleMessage, useVersionedEdit } from '../api/versioning'
 import CompanyQuickModal from '../components/quickAddModals/CompanyQuickModal'
 import CustomTable, { type Column } from '../components/shared/CustomTable'
 import ConfirmDeleteModal from '../components/shared/ui/ConfirmDeleteModal'
@@ -11,13 +12,26 @@ import CustomTooltip from '../components/shared/ui/CustomTooltip'
 import { useToast } from '../components/shared/ui/ToastProvider'
 import type { Company } from '../types'
 
+type CompanyForm = { name: string; contact: string; phone: string }
+
+const companyForm = (c: Company): CompanyForm => ({
+	name: c.name,
+	contact: c.contact,
+	phone: c.phone,
+})
+
 function CompaniesPage() {
 	const qc = useQueryClient()
 	const { success, error } = useToast()
 	const [modalOpen, setModalOpen] = useState(false)
 	const [editingId, setEditingId] = useState<string | null>(null)
 	const [pendingDeleteId, setPendingDeleteId] = useState<string | null>(null)
-	const [form, setForm] = useState({ name: '', contact: '', phone: '' })
+	const [form, setForm] = useState<CompanyForm>({
+		name: '',
+		contact: '',
+		phone: '',
+	})
+	const edit = useVersionedEdit<CompanyForm>()
 
 	const listQuery = useQuery({
 		queryKey: ['companies'],
@@ -35,14 +49,23 @@ function CompaniesPage() {
 		onError: () => error('Failed to create company'),
 	})
 	const updateMutation = useMutation({
-		mutationFn: async (id: string) => api.put(`/api/companies/${id}`, form),
+		mutationFn: async (id: string) =>
+			api.put(`/api/companies/${id}`, { ...form, version: edit.version }),
 		onSuccess: () => {
 			qc.invalidateQueries({ queryKey: ['companies'] })
 			setEditingId(null)
 			setModalOpen(false)
 			success('Company updated')
 		},
-		onError: () => error('Failed to update company'),
+		onError: err => {
+			const merged = edit.resolve(err, form, companyForm)
+			if (merged) {
+				setForm(merged)
+				error(staleMessage('company'))
+				return
+			}
+			error('Failed to update company')
+		},
 	})
 	const deleteMutation = useMutation({
 		mutationFn: async (id: string) => api.delete(`/api/companies/${id}`),
@@ -50,7 +73,7 @@ function CompaniesPage() {
 			qc.invalidateQueries({ queryKey: ['companies'] })
 			success('Company deleted')
 		},
-		onError: () => error('Failed to delete company'),
+		onError: err => error(deleteErrorMessage(err, 'Failed to delete company')),
 	})
 
 	const openCreate = () => {
@@ -60,7 +83,8 @@ function CompaniesPage() {
 	}
 	const openEdit = (c: Company) => {
 		setEditingId(c.id)
-		setForm({ name: c.name, contact: c.contact, phone: c.phone })
+		setForm(companyForm(c))
+		edit.begin(companyForm(c), c.version)
 		setModalOpen(true)
 	}
 
@@ -2,7 +2,8 @@ import { PencilSquareIcon, TrashIcon } from '@heroicons/react/24/outline'
 import { useMutation, useQuery, useQueryClient } from '@tanstack/react-query'
 import { useState } from 'react'
 import { NavLink } from 'react-router-dom'
-import { api } from '../api/client'
+import { api, deleteErrorMessage } from '../api/client'
+import { staleMessage, useVersionedEdit } from '../api/versioning'
 import UnitQuickModal from '../components/quickAddModals/UnitQuickModal'
 import CustomTable, { type Column } from '../components/shared/CustomTable'
 import ConfirmDeleteModal from '../components/shared/ui/ConfirmDeleteModal'
@@ -20,6 +21,15 @@ type VehicleForm = {
 	year: string
 }
 
+const vehicleForm = (v: Vehicle): VehicleForm => ({
+	plate: v.plate,
+	vin: v.vin,
+	type: v.type,
+	make: v.make,
+	model: v.model,
+	year: String(v.year || ''),
+})
+
 export default function CompanyUnitsTab({ companyId }: { companyId: string }) {
 	const qc = useQueryClient()
 	const { success, error } = useToast()
@@ -44,6 +54,7 @@ export default function CompanyUnitsTab({ companyId }: { companyId: string }) {
 		model: '',
 		year: '',
 	})
+	const edit = useVersionedEdit<VehicleForm>()
 
 	const createMutation = useMutation({
 		mutationFn: async () =>
@@ -81,6 +92,7 @@ export default function CompanyUnitsTab({ companyId }: { companyId: string }) {
 				make: form.make,
 				model: form.model,
 				year: form.year ? Number(form.year) : undefined,
+				version: edit.version,
 			}),
 		onSuccess: () => {
 			qc.invalidateQueries({ queryKey: ['company-vehicles', companyId] })
@@ -88,7 +100,15 @@ export default function CompanyUnitsTab({ companyId }: { companyId: string }) {
 			setModalOpen(false)
 			success('Unit updated')
 		},
-		onError: () => error('Failed to update unit'),
+		onError: err => {
+			const merged = edit.resolve(err, form, vehicleForm)
+			if (merged) {
+				setForm(merged)
+				error(staleMessage('unit'))
+				return
+			}
+			error('Failed to update unit')
+		},
 	})
 	const deleteMutation = useMutation({
 		mutationFn: async (id: string) => api.delete(`/api/vehicles/${id}`),
@@ -97,7 +117,7 @@ export default function CompanyUnitsTab({ companyId }: { companyId: string }) {
 			setPendingDeleteId(null)
 			success('Unit deleted')
 		},
-		onError: () => error('Failed to delete unit'),
+		onError: err => error(deleteErrorMessage(err, 'Failed to delete unit')),
 	})
 
 	type Row = Vehicle & { actions?: null }
@@ -128,14 +148,8 @@ export default function CompanyUnitsTab({ companyId }: { companyId: string }) {
 							className='rounded-md border border-slate-200 px-2 py-1 text-xs text-slate-700 hover:bg-slate-50'
 							onClick={() => {
 								setEditingId(r.id)
-								setForm({
-									plate: r.plate,
-									vin: r.vin,
-									type: r.type,
-									make: r.make,
-									model: r.model,
-									year: String(r.year || ''),
-								})
+								setForm(vehicleForm(r))
+								edit.begin(vehicleForm(r), r.version)
 								setModalOpen(true)
 							}}
 						>
@@ -74,10 +74,14 @@ function DashboardPage() {
 	return (
 		<div className='space-y-4'>
 			<h1 className='text-xl font-semibold text-slate-900'>Dashboard</h1>
-			<div className='grid gap-4 sm:grid-cols-2 lg:grid-cols-4'>
+			<div className='grid gap-4 sm:grid-cols-2 lg:grid-cols-5'>
 				<StatCard label='Open bookings' value={data.open_bookings} />
 				<StatCard label='Bookings today' value={data.today_bookings} />
 				<StatCard label='Bays' value={data.bays} />
+				<StatCard
+					label={`Comebacks (${data.comeback_days ?? 30} days)`}
+					value={data.comebacks ?? 0}
+				/>
 				<StatCard
 					label='Snapshot'
 					value={new Date(data.timestamp).toLocaleString()}
@@ -137,6 +141,7 @@ function DashboardPage() {
 					{[
 						{ title: 'Top technicians', rows: data.top.technicians },
 						{ title: 'Top units', rows: data.top.units },
+						{ title: 'Comebacks', rows: data.top.comeback_units ?? [] },
 						{ title: 'Top customers', rows: data.top.companies },
 						{ title: 'Top bays', rows: data.top.bays },
 					].map(block => (
@@ -166,6 +171,13 @@ function DashboardPage() {
 														>
 															{r.name || r.id}
 														</NavLink>
+													) : block.title === 'Comebacks' ? (
+														<NavLink
+															to={`/vehicles/${r.id}?tab=history`}
+															className='text-sky-600 underline'
+														>
+															{r.name || r.id}
+														</NavLink>
 													) : (
 														<span>{r.name || r.id}</span>
 													)}
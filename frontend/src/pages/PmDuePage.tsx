@@ -0,0 +1,179 @@
+import { useMutation, useQuery, useQueryClient } from '@tanstack/react-query'
+import { useState } from 'react'
+import { NavLink } from 'react-router-dom'
+import { api } from '../api/client'
+import CustomSelect, { type Option } from '../components/shared/CustomSelect'
+import CustomTable, { type Column } from '../components/shared/CustomTable'
+import { useToast } from '../components/shared/ui/ToastProvider'
+import { useAuth } from '../context/AuthContext'
+import type { Booking, PMDueItem } from '../types'
+
+const withinOptions: Option<string>[] = [
+	{ label: 'Due within 7 days', value: '7d' },
+	{ label: 'Due within 30 days', value: '30d' },
+	{ label: 'Due within 60 days', value: '60d' },
+	{ label: 'Due within 90 days', value: '90d' },
+]
+
+export default function PmDuePage() {
+	const { role } = useAuth()
+	const qc = useQueryClient()
+	const { success, error } = useToast()
+	const [within, setWithin] = useState('30d')
+
+	const dueQuery = useQuery({
+		queryKey: ['pm-due', within],
+		queryFn: async () =>
+			(await api.get<PMDueItem[]>('/api/pm/due', { params: { within } }))
+				.data,
+	})
+
+	const createMutation = useMutation({
+		mutationFn: async (items?: PMDueItem[]) =>
+			(
+				await api.post<{ created: Booking[]; skipped: number }>(
+					'/api/pm/due/bookings',
+					{
+						items: items?.map(i => ({
+							vehicle_id: i.vehicle.id,
+							program_id: i.program.id,
+						})),
+					},
+					{ params: { within } }
+				)
+			).data,
+		onSuccess: res => {
+			qc.invalidateQueries({ queryKey: ['pm-due'] })
+			qc.invalidateQueries({ queryKey: ['bookings'] })
+			success(
+				`Added ${res.created.length} unit${
+					res.created.length === 1 ? '' : 's'
+				} to the waiting list`
+			)
+		},
+		onError: () => error('Failed to create bookings'),
+	})
+
+	const canBook = role === 'admin' || role === 'office'
+	const items = dueQuery.data ?? []
+	const unbooked = items.filter(i => !i.booking_id)
+
+	const columns: Array<Column<PMDueItem>> = [
+		{
+			key: 'vehicle',
+			header: 'Unit',
+			render: row => (
+				<NavLink
+					to={`/vehicles/${row.vehicle.id}?tab=history`}
+					className='text-sky-600 underline'
+				>
+					{row.vehicle.plate || row.vehicle.vin || row.vehicle.id}
+				</NavLink>
+			),
+		},
+		{
+			key: 'company_name',
+			header: 'Company',
+			render: row => row.company_name || '—',
+		},
+		{ key: 'program', header: 'Program', render: row => row.program.name },
+		{
+			key: 'last_done',
+			header: 'Last done',
+			render: row =>
+				row.last_done ? new Date(row.last_done).toLocaleDateString() : 'Never',
+		},
+		{
+			key: 'due_at',
+			header: 'Due',
+			render: row => (
+				<span className={row.overdue ? 'font-medium text-rose-600' : ''}>
+					{row.due_at ? new Date(row.due_at).toLocaleDateString() : '—'}
+					{row.due_at && row.days_left < 0
+						? ` (${-row.days_left} days overdue)`
+						: ''}
+				</span>
+			),
+		},
+		{
+			key: 'miles_left',
+			header: 'Miles left',
+			render: row =>
+				row.miles_left === undefined ? (
+					'—'
+				) : (
+					<span
+						className={row.miles_left < 0 ? 'font-medium text-rose-600' : ''}
+						title={
+							row.due_odometer
+								? `Due at ${row.due_odometer.toLocaleString()} mi`
+								: undefined
+						}
+					>
+						{row.miles_left < 0
+							? `${(-row.miles_left).toLocaleString()} mi overdue`
+							: `${row.miles_left.toLocaleString()} mi`}
+					</span>
+				),
+		},
+		{
+			key: 'actions',
+			header: '',
+			render: row =>
+				row.booking_id ? (
+					<NavLink
+						to={`/bookings/${row.booking_id}`}
+						className='text-xs text-slate-600 underline'
+					>
+						Booked
+					</NavLink>
+				) : canBook ? (
+					<div className='flex justify-end'>
+						<button
+							className='rounded-md border border-slate-200 px-2 py-1 text-xs text-slate-700 hover:bg-slate-50'
+							disabled={createMutation.isPending}
+							onClick={() => createMutation.mutate([row])}
+						>
+							Add to waiting list
+						</button>
+					</div>
+				) : null,
+		},
+	]
+
+	return (
+		<div className='space-y-4'>
+			<div className='flex items-center justify-between gap-4'>
+				<h1 className='text-xl font-semibold text-slate-900'>PM due</h1>
+				<div className='flex items-center gap-2'>
+					<div className='w-60'>
+						<CustomSelect
+							options={withinOptions}
+							value={
+								withinOptions.find(o => o.value === within) ?? withinOptions[1]
+							}
+							onChange={opt => setWithin(opt.value)}
+						/>
+					</div>
+					{canBook && (
+						<button
+							className='rounded-md bg-slate-900 px-3 py-2 text-sm font-medium text-white hover:bg-slate-800 disabled:opacity-50'
+							disabled={unbooked.length === 0 || createMutation.isPending}
+							onClick={() => createMutation.mutate(unbooked)}
+						>
+							Add all to waiting list ({unbooked.length})
+						</button>
+					)}
+				</div>
+			</div>
+
+			<CustomTable
+				columns={columns}
+				data={items}
+				rowKey={row => `${row.vehicle.id}:${row.program.id}`}
+				emptyText={dueQuery.isLoading ? 'Loading...' : 'No units due'}
+				pageParamKey='pm_due'
+			/>
+		</div>
+	)
+}
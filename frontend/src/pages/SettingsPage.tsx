@@ -86,7 +86,7 @@ function SettingsPage() {
 						placeholder={
 							'{status_icon} <b>{status_name}</b> • <b>#{booking_id}</b>\n\n<b>Complaint:</b> {complaint}\n<b>Description:</b> {description}\n\n<b>Unit:</b> {unit} ({unit_plate} {unit_vin})\n<b>Bay:</b> {bay_name}\n<b>Company:</b> {company_name}\n<b>Fullbay Service ID:</b> {fullbay_service_id}\n\n<b>Technicians:</b> {technician_names}\n\n<b>Start:</b> {start}\n<b>End:</b> {end}'
 						}
-						helperText='Use placeholders like {status_icon}, {status_name}, {booking_id}, {complaint}, {description}, {unit}, {unit_plate}, {unit_vin}, {bay_name}, {company_name}, {technician_names}, {start}, {end}, {fullbay_service_id}'
+						helperText='Use placeholders like {status_icon}, {status_name}, {booking_id}, {complaint}, {description}, {unit}, {unit_plate}, {unit_vin}, {bay_name}, {company_name}, {technician_names}, {jobs}, {start}, {end}, {fullbay_service_id}'
 					/>
 					<div className='mt-2 flex flex-wrap gap-2 text-xs'>
 						{[
@@ -106,6 +106,7 @@ function SettingsPage() {
 							'unit_model',
 							'fullbay_service_id',
 							'technician_names',
+							'jobs',
 						].map(key => (
 							<button
 								key={key}
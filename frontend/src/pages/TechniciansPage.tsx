@@ -2,7 +2,8 @@ import { PencilSquareIcon, TrashIcon } from '@heroicons/react/24/outline'
 import { useMutation, useQuery, useQueryClient } from '@tanstack/react-query'
 import { useState } from 'react'
 import { NavLink } from 'react-router-dom'
-import { api } from '../api/client'
+import { api, deleteErrorMessage } from '../api/client'
+import { staleMessage, useVersionedEdit } from '../api/versioning'
 import TechnicianQuickModal from '../components/quickAddModals/TechnicianQuickModal'
 import CustomTable, { type Column } from '../components/shared/CustomTable'
 import ConfirmDeleteModal from '../components/shared/ui/ConfirmDeleteModal'
@@ -11,18 +12,33 @@ import CustomTooltip from '../components/shared/ui/CustomTooltip'
 import { useToast } from '../components/shared/ui/ToastProvider'
 import type { Technician } from '../types'
 
+type TechnicianForm = {
+	name: string
+	skills: string
+	phone: string
+	email: string
+}
+
+const technicianForm = (t: Technician): TechnicianForm => ({
+	name: t.name,
+	skills: t.skills.join(', '),
+	phone: t.phone,
+	email: t.email,
+})
+
 function TechniciansPage() {
 	const qc = useQueryClient()
 	const { success, error } = useToast()
 	const [modalOpen, setModalOpen] = useState(false)
 	const [editingId, setEditingId] = useState<string | null>(null)
 	const [pendingDeleteId, setPendingDeleteId] = useState<string | null>(null)
-	const [form, setForm] = useState({
+	const [form, setForm] = useState<TechnicianForm>({
 		name: '',
 		skills: '',
 		phone: '',
 		email: '',
 	})
+	const edit = useVersionedEdit<TechnicianForm>()
 
 	const listQuery = useQuery({
 		queryKey: ['technicians'],
@@ -55,6 +71,7 @@ function TechniciansPage() {
 					.split(',')
 					.map(s => s.trim())
 					.filter(Boolean),
+				version: edit.version,
 			}),
 		onSuccess: () => {
 			qc.invalidateQueries({ queryKey: ['technicians'] })
@@ -62,7 +79,15 @@ function TechniciansPage() {
 			setModalOpen(false)
 			success('Technician updated')
 		},
-		onError: () => error('Failed to update technician'),
+		onError: err => {
+			const merged = edit.resolve(err, form, technicianForm)
+			if (merged) {
+				setForm(merged)
+				error(staleMessage('technician'))
+				return
+			}
+			error('Failed to update technician')
+		},
 	})
 
 	const deleteMutation = useMutation({
@@ -71,7 +96,8 @@ function TechniciansPage() {
 			qc.invalidateQueries({ queryKey: ['technicians'] })
 			success('Technician deleted')
 		},
-		onError: () => error('Failed to delete technician'),
+		onError: err =>
+			error(deleteErrorMessage(err, 'Failed to delete technician')),
 	})
 
 	const openCreate = () => {
@@ -82,12 +108,8 @@ function TechniciansPage() {
 
 	const openEdit = (t: Technician) => {
 		setEditingId(t.id)
-		setForm({
-			name: t.name,
-			skills: t.skills.join(', '),
-			phone: t.phone,
-			email: t.email,
-		})
+		setForm(technicianForm(t))
+		edit.begin(technicianForm(t), t.version)
 		setModalOpen(true)
 	}
 
@@ -0,0 +1,163 @@
+import { useMutation, useQuery, useQueryClient } from '@tanstack/react-query'
+import { useMemo, useState } from 'react'
+import { api, deleteErrorMessage } from '../api/client'
+import CustomSelect, { type Option } from '../components/shared/CustomSelect'
+import CustomTable, { type Column } from '../components/shared/CustomTable'
+import { useToast } from '../components/shared/ui/ToastProvider'
+import { useAuth } from '../context/AuthContext'
+import type { TrashItem, TrashType, User } from '../types'
+
+const typeOptions: Option<TrashType | ''>[] = [
+	{ label: 'All types', value: '' },
+	{ label: 'Bookings', value: 'bookings' },
+	{ label: 'Companies', value: 'companies' },
+	{ label: 'Contacts', value: 'contacts' },
+	{ label: 'Units', value: 'vehicles' },
+	{ label: 'Technicians', value: 'technicians' },
+	{ label: 'Bays', value: 'bays' },
+	{ label: 'PM programs', value: 'pm_programs' },
+]
+
+export default function TrashPage() {
+	const { role } = useAuth()
+	const qc = useQueryClient()
+	const { success, error } = useToast()
+	const [selectedType, setSelectedType] = useState<TrashType | ''>('')
+
+	const usersQuery = useQuery({
+		queryKey: ['users'],
+		queryFn: async () => (await api.get<User[]>('/auth/users')).data,
+		enabled: role === 'admin',
+	})
+
+	const trashQuery = useQuery({
+		queryKey: ['trash', selectedType],
+		queryFn: async () =>
+			(
+				await api.get<TrashItem[]>('/api/trash', {
+					params: { type: selectedType || undefined },
+				})
+			).data,
+		enabled: role === 'admin',
+	})
+
+	const restoreMutation = useMutation({
+		mutationFn: async (item: TrashItem) =>
+			api.post(`/api/trash/${item.type}/${item.id}/restore`),
+		onSuccess: () => {
+			qc.invalidateQueries({ queryKey: ['trash'] })
+			success('Restored')
+		},
+		onError: err =>
+			error(deleteErrorMessage(err, 'Failed to restore (the slot may be taken)')),
+	})
+
+	const purgeMutation = useMutation({
+		mutationFn: async (item: TrashItem) =>
+			api.delete(`/api/trash/${item.type}/${item.id}`),
+		onSuccess: () => {
+			qc.invalidateQueries({ queryKey: ['trash'] })
+			success('Deleted permanently')
+		},
+		onError: () => error('Failed to delete permanently'),
+	})
+
+	const userEmails = useMemo(
+		() => new Map((usersQuery.data ?? []).map(u => [u.id, u.email])),
+		[usersQuery.data]
+	)
+
+	// guard in UI; backend also enforces
+	if (role !== 'admin') {
+		return <p className='text-sm text-rose-600'>Access denied</p>
+	}
+
+	const columns: Array<Column<TrashItem>> = [
+		{
+			key: 'deleted_at',
+			header: 'Deleted',
+			render: row => (
+				<span className='whitespace-nowrap text-xs'>
+					{new Date(row.deleted_at).toLocaleString()}
+				</span>
+			),
+		},
+		{
+			key: 'deleted_by',
+			header: 'By',
+			render: row => (
+				<span className='text-xs'>
+					{(row.deleted_by && userEmails.get(row.deleted_by)) || '—'}
+				</span>
+			),
+		},
+		{
+			key: 'type',
+			header: 'Type',
+			render: row =>
+				typeOptions.find(o => o.value === row.type)?.label ?? row.type,
+		},
+		{ key: 'label', header: 'Record', render: row => row.label || row.id },
+		{
+			key: 'purge_at',
+			header: 'Purged on',
+			render: row => (
+				<span className='whitespace-nowrap text-xs'>
+					{row.purge_at ? new Date(row.purge_at).toLocaleDateString() : '—'}
+				</span>
+			),
+		},
+		{
+			key: 'actions',
+			header: '',
+			render: row => (
+				<div className='flex justify-end gap-2'>
+					<button
+						className='rounded-md border border-slate-200 px-2 py-1 text-xs text-slate-700 hover:bg-slate-50'
+						disabled={restoreMutation.isPending}
+						onClick={() => restoreMutation.mutate(row)}
+					>
+						Restore
+					</button>
+					<button
+						className='rounded-md border border-rose-200 px-2 py-1 text-xs text-rose-600 hover:bg-rose-50'
+						disabled={purgeMutation.isPending}
+						onClick={() => {
+							if (window.confirm('Delete permanently? This cannot be undone.')) {
+								purgeMutation.mutate(row)
+							}
+						}}
+					>
+						Delete
+					</button>
+				</div>
+			),
+		},
+	]
+
+	return (
+		<div className='space-y-4'>
+			<div className='flex items-center justify-between'>
+				<h1 className='text-xl font-semibold text-slate-900'>Trash</h1>
+				<div className='w-72'>
+					<CustomSelect
+						placeholder='All types'
+						options={typeOptions}
+						value={
+							typeOptions.find(o => o.value === selectedType) ?? typeOptions[0]
+						}
+						onChange={opt => setSelectedType(opt.value)}
+					/>
+				</div>
+			</div>
+
+			<CustomTable
+				columns={columns}
+				data={trashQuery.data ?? []}
+				rowKey={row => `${row.type}:${row.id}`}
+				emptyText='Trash is empty'
+				pageParamKey='trash'
+			/>
+		</div>
+	)
+}
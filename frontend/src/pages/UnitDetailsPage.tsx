@@ -1,10 +1,22 @@
 import { useQuery } from '@tanstack/react-query'
-import { useParams } from 'react-router-dom'
+import { NavLink, useParams } from 'react-router-dom'
 import { api } from '../api/client'
 import CustomTable, { type Column } from '../components/shared/CustomTable'
 import CustomDetailsPage from '../components/shared/layout/CustomDetailsPage'
 import CustomBadge from '../components/shared/ui/CustomBadge'
-import type { AuditLog, Company, Vehicle } from '../types'
+import type {
+	AuditLog,
+	Company,
+	Vehicle,
+	VehicleHistory,
+	VehicleHistoryItem,
+} from '../types'
+
+function formatMinutes(m: number) {
+	if (!m) return '—'
+	const h = Math.floor(m / 60)
+	return h > 0 ? `${h}h ${m % 60}m` : `${m}m`
+}
 
 export default function UnitDetailsPage() {
 	const { id } = useParams<{ id: string }>()
@@ -16,6 +28,12 @@ export default function UnitDetailsPage() {
 		queryKey: ['companies'],
 		queryFn: async () => (await api.get<Company[]>('/api/companies')).data,
 	})
+	const historyQuery = useQuery({
+		queryKey: ['vehicle-history', id],
+		queryFn: async () =>
+			(await api.get<VehicleHistory>(`/api/vehicles/${id}/history`)).data,
+		enabled: Boolean(id),
+	})
 	const logsQuery = useQuery({
 		queryKey: ['vehicle-logs', id],
 		queryFn: async () =>
@@ -39,6 +57,18 @@ export default function UnitDetailsPage() {
 		{ label: 'Make', value: unit.make || '—' },
 		{ label: 'Model', value: unit.model || '—' },
 		{ label: 'Year', value: String(unit.year) },
+		{
+			label: 'Odometer',
+			value: unit.odometer ? `${unit.odometer.toLocaleString()} mi` : '—',
+		},
+		{
+			label: 'Engine hours',
+			value: unit.engine_hours ? unit.engine_hours.toLocaleString() : '—',
+		},
+		{
+			label: 'Last reading',
+			value: unit.reading_at ? new Date(unit.reading_at).toLocaleString() : '—',
+		},
 		{ label: 'Created', value: new Date(unit.created_at).toLocaleString() },
 		{ label: 'Updated', value: new Date(unit.updated_at).toLocaleString() },
 	]
@@ -48,7 +78,91 @@ export default function UnitDetailsPage() {
 			title={unit.plate || unit.vin || 'Unit'}
 			subtitle='Unit'
 			rows={rows}
-			tabs={['general', 'logs']}
+			tabs={['general', 'history', 'logs']}
+			renderHistory={() => {
+				if (historyQuery.isLoading)
+					return <p className='text-sm text-slate-600'>Loading history...</p>
+				const history = historyQuery.data
+				if (!history || history.items.length === 0)
+					return <p className='text-sm text-slate-600'>No visits yet.</p>
+				const numbers = new Map(
+					history.items.map(b => [b.id, b.number || b.id])
+				)
+				const columns: Array<Column<VehicleHistoryItem>> = [
+					{
+						key: 'start',
+						header: 'Date',
+						className: 'w-44',
+						render: b => (
+							<NavLink
+								to={`/bookings/${b.id}`}
+								className='text-sky-600 underline'
+							>
+								{new Date(b.start).toLocaleDateString()}
+							</NavLink>
+						),
+					},
+					{
+						key: 'complaint',
+						header: 'Complaint',
+						render: b => (
+							<div className='space-y-1'>
+								<span>{b.complaint || b.title || '—'}</span>
+								{b.comeback_of ? (
+									<div>
+										<CustomBadge
+											label={`Comeback of ${numbers.get(b.comeback_of) ?? ''} (${b.comeback_days ?? 0} d)`}
+											variant='update'
+										/>
+									</div>
+								) : null}
+							</div>
+						),
+					},
+					{ key: 'bay_name', header: 'Bay', render: b => b.bay_name || '—' },
+					{
+						key: 'technicians',
+						header: 'Technicians',
+						render: b =>
+							b.technicians.map(t => t.name || t.id).join(', ') || '—',
+					},
+					{
+						key: 'duration_minutes',
+						header: 'Duration',
+						render: b => formatMinutes(b.duration_minutes),
+					},
+					{
+						key: 'labor_minutes',
+						header: 'Labor',
+						render: b => formatMinutes(b.labor_minutes),
+					},
+					{
+						key: 'outcome',
+						header: 'Outcome',
+						render: b => (
+							<span title={(b.corrections ?? []).join('\n')}>
+								{b.outcome.replace('_', ' ')}
+							</span>
+						),
+					},
+				]
+				return (
+					<div className='space-y-2'>
+						{history.comebacks > 0 ? (
+							<p className='text-sm text-amber-700'>
+								{history.comebacks} comeback
+								{history.comebacks === 1 ? '' : 's'} within{' '}
+								{history.comeback_days} days of a closed visit.
+							</p>
+						) : null}
+						<CustomTable
+							columns={columns}
+							data={history.items}
+							pageParamKey='unit_history'
+						/>
+					</div>
+				)
+			}}
 			renderLogs={() => {
 				if (logsQuery.isLoading)
 					return <p className='text-sm text-slate-600'>Loading logs...</p>
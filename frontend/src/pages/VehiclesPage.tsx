@@ -2,7 +2,8 @@ import { PencilSquareIcon, TrashIcon } from '@heroicons/react/24/outline'
 import { useMutation, useQuery, useQueryClient } from '@tanstack/react-query'
 import { useState } from 'react'
 import { NavLink } from 'react-router-dom'
-import { api } from '../api/client'
+import { api, deleteErrorMessage } from '../api/client'
+import { staleMessage, useVersionedEdit } from '../api/versioning'
 import UnitQuickModal from '../components/quickAddModals/UnitQuickModal'
 import CustomTable, { type Column } from '../components/shared/CustomTable'
 import ConfirmDeleteModal from '../components/shared/ui/ConfirmDeleteModal'
@@ -11,22 +12,24 @@ import CustomTooltip from '../components/shared/ui/CustomTooltip'
 import { useToast } from '../components/shared/ui/ToastProvider'
 import type { Company, Vehicle } from '../types'
 
+type VehicleForm = {
+	company_id: string
+	company_name?: string
+	type: Vehicle['type']
+	vin: string
+	plate: string
+	make: string
+	model: string
+	year: number
+}
+
 function VehiclesPage() {
 	const qc = useQueryClient()
 	const { success, error } = useToast()
 	const [modalOpen, setModalOpen] = useState(false)
 	const [editingId, setEditingId] = useState<string | null>(null)
 	const [pendingDeleteId, setPendingDeleteId] = useState<string | null>(null)
-	const [form, setForm] = useState<{
-		company_id: string
-		company_name?: string
-		type: Vehicle['type']
-		vin: string
-		plate: string
-		make: string
-		model: string
-		year: number
-	}>({
+	const [form, setForm] = useState<VehicleForm>({
 		company_id: '',
 		type: 'truck',
 		vin: '',
@@ -45,6 +48,20 @@ function VehiclesPage() {
 		queryKey: ['vehicles'],
 		queryFn: async () => (await api.get<Vehicle[]>('/api/vehicles')).data,
 	})
+	const edit = useVersionedEdit<VehicleForm>()
+	const vehicleForm = (v: Vehicle): VehicleForm => ({
+		company_id: v.company_id,
+		company_name:
+			v.company_name ||
+			(companiesQuery.data ?? []).find(c => c.id === v.company_id)?.name ||
+			'',
+		type: v.type,
+		vin: v.vin,
+		plate: v.plate,
+		make: v.make,
+		model: v.model,
+		year: v.year,
+	})
 
 	const createMutation = useMutation({
 		mutationFn: async () => {
@@ -85,6 +102,7 @@ function VehiclesPage() {
 				make: form.make,
 				model: form.model,
 				year: Number(form.year),
+				version: edit.version,
 			}
 			return api.put(`/api/vehicles/${id}`, payload)
 		},
@@ -94,7 +112,15 @@ function VehiclesPage() {
 			setModalOpen(false)
 			success('Unit updated')
 		},
-		onError: () => error('Failed to update unit'),
+		onError: err => {
+			const merged = edit.resolve(err, form, vehicleForm)
+			if (merged) {
+				setForm(merged)
+				error(staleMessage('unit'))
+				return
+			}
+			error('Failed to update unit')
+		},
 	})
 	const deleteMutation = useMutation({
 		mutationFn: async (id: string) => api.delete(`/api/vehicles/${id}`),
@@ -102,7 +128,7 @@ function VehiclesPage() {
 			qc.invalidateQueries({ queryKey: ['vehicles'] })
 			success('Unit deleted')
 		},
-		onError: () => error('Failed to delete unit'),
+		onError: err => error(deleteErrorMessage(err, 'Failed to delete unit')),
 	})
 
 	const openCreate = () => {
@@ -121,16 +147,8 @@ function VehiclesPage() {
 	}
 	const openEdit = (v: Vehicle) => {
 		setEditingId(v.id)
-		setForm({
-			company_id: v.company_id,
-			company_name: v.company_name || '',
-			type: v.type,
-			vin: v.vin,
-			plate: v.plate,
-			make: v.make,
-			model: v.model,
-			year: v.year,
-		})
+		setForm(vehicleForm(v))
+		edit.begin(vehicleForm(v), v.version)
 		setModalOpen(true)
 	}
 
@@ -6,6 +6,7 @@ export interface Technician {
 	skills: string[]
 	phone: string
 	email: string
+	version?: number
 	created_at: string
 	updated_at: string
 }
@@ -14,6 +15,11 @@ export interface Bay {
 	id: string
 	key: string
 	name: string
+	default_duration?: number
+	capacity?: number
+	capabilities?: string[]
+	max_length_ft?: number
+	version?: number
 	created_at: string
 	updated_at: string
 }
@@ -23,6 +29,7 @@ export interface Company {
 	name: string
 	contact: string
 	phone: string
+	version?: number
 	created_at: string
 	updated_at: string
 }
@@ -47,10 +54,26 @@ export interface Vehicle {
 	make: string
 	model: string
 	year: number
+	odometer?: number
+	engine_hours?: number
+	reading_at?: string
+	version?: number
 	created_at: string
 	updated_at: string
 }
 
+export interface MeterReading {
+	id: string
+	vehicle_id: string
+	booking_id?: string
+	odometer?: number
+	engine_hours?: number
+	source: string
+	override?: boolean
+	recorded_by: string
+	recorded_at: string
+}
+
 export type BookingStatus = 'open' | 'in_progress' | 'closed' | 'canceled'
 
 export interface Booking {
@@ -68,7 +91,10 @@ export interface Booking {
 	end?: string
 	status: BookingStatus
 	notes: string
+	odometer?: number
+	engine_hours?: number
 	created_by: string
+	version?: number
 	created_at: string
 	updated_at: string
 }
@@ -77,15 +103,62 @@ export interface DashboardSummary {
 	open_bookings: number
 	today_bookings: number
 	bays: number
+	comebacks?: number
+	comeback_days?: number
 	timestamp: string
 	top?: {
 		technicians: Array<{ id: string; name: string; count: number }>
 		units: Array<{ id: string; name: string; count: number }>
+		comeback_units?: Array<{ id: string; name: string; count: number }>
 		companies: Array<{ id: string; name: string; count: number }>
 		bays: Array<{ id: string; name: string; count: number }>
 	}
 }
 
+export interface VehicleHistoryItem extends Booking {
+	bay_name?: string
+	technicians: Array<{ id: string; name: string }>
+	duration_minutes: number
+	labor_minutes: number
+	outcome: 'completed' | 'canceled' | 'in_progress' | 'scheduled'
+	corrections?: string[]
+	comeback_of?: string
+	comeback_days?: number
+}
+
+export interface VehicleHistory {
+	vehicle: Vehicle
+	comeback_days: number
+	comebacks: number
+	items: VehicleHistoryItem[]
+}
+
+export interface PMProgram {
+	id: string
+	name: string
+	job_type: string
+	interval_days?: number
+	interval_miles?: number
+	vehicle_ids: string[]
+	company_ids: string[]
+	version: number
+}
+
+export interface PMDueItem {
+	vehicle: Vehicle
+	company_name?: string
+	program: PMProgram
+	last_done?: string
+	last_odometer?: number
+	due_at?: string
+	days_left: number
+	due_odometer?: number
+	miles_left?: number
+	overdue: boolean
+	due: boolean
+	booking_id?: string
+}
+
 export interface AuditLog {
 	id: string
 	action: string
@@ -110,3 +183,22 @@ export interface User {
 	created_at: string
 	updated_at: string
 }
+
+export type TrashType =
+	| 'bookings'
+	| 'companies'
+	| 'contacts'
+	| 'vehicles'
+	| 'technicians'
+	| 'bays'
+	| 'pm_programs'
+
+export interface TrashItem {
+	type: TrashType
+	id: string
+	label: string
+	deleted_at: string
+	deleted_by?: string
+	purge_at?: string
+	record: Record<string, unknown>
+}